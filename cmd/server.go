@@ -1,38 +1,106 @@
 package main
 
 import (
+	"context"
+	"github.com/challenge/pkg/jobs"
+	"github.com/challenge/pkg/lockout"
 	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/password"
+	"github.com/challenge/pkg/ratelimit"
 	"github.com/challenge/pkg/repository"
 	"github.com/challenge/pkg/service"
+	"github.com/challenge/pkg/storage"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"log"
+	"math"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/challenge/pkg/auth"
 	"github.com/challenge/pkg/controller"
 )
 
 const (
-	ServerPort       = "8080"
-	CheckEndpoint    = "/check"
-	UsersEndpoint    = "/users"
-	LoginEndpoint    = "/login"
-	MessagesEndpoint = "/messages"
-	DefaultDSN       = "file::memory:?cache=shared"
+	ServerPort                   = "8080"
+	CheckEndpoint                = "/check"
+	UsersEndpoint                = "/users"
+	UserEndpoint                 = "/users/{id}"
+	ProfileEndpoint              = "/users/me"
+	PasswordEndpoint             = "/users/me/password"
+	TwoFactorEndpoint            = "/users/me/2fa"
+	TwoFactorConfirmEndpoint     = "/users/me/2fa/confirm"
+	ExportsEndpoint              = "/users/me/exports"
+	ExportEndpoint               = "/users/me/exports/{id}"
+	ExportDownloadEndpoint       = "/users/me/exports/{id}/download"
+	BlocksEndpoint               = "/users/me/blocks"
+	BlockEndpoint                = "/users/me/blocks/{id}"
+	PrivacyEndpoint              = "/users/me/privacy"
+	LoginEndpoint                = "/login"
+	LoginTwoFactorEndpoint       = "/login/2fa"
+	RefreshEndpoint              = "/refresh"
+	LogoutEndpoint               = "/logout"
+	JWKSEndpoint                 = "/.well-known/jwks.json"
+	MessagesEndpoint             = "/messages"
+	WebSocketEndpoint            = "/messages/ws"
+	StreamEndpoint               = "/messages/stream"
+	HistoryEndpoint              = "/messages/history"
+	ReadEndpoint                 = "/messages/read"
+	UnreadEndpoint               = "/messages/unread"
+	InboxEndpoint                = "/messages/inbox"
+	MessageStatusEndpoint        = "/messages/{id}/status"
+	MessageEndpoint              = "/messages/{id}"
+	MessageRevisionsEndpoint     = "/messages/{id}/revisions"
+	ConversationsEndpoint        = "/conversations"
+	ConversationEndpoint         = "/conversations/{id}"
+	ConversationMembersEndpoint  = "/conversations/{id}/members"
+	ConversationMemberEndpoint   = "/conversations/{id}/members/{user}"
+	ConversationLeaveEndpoint    = "/conversations/{id}/leave"
+	ConversationMessagesEndpoint = "/conversations/{id}/messages"
+	AttachmentsEndpoint          = "/attachments"
+	AttachmentEndpoint           = "/attachments/{id}"
+	AdminUsersEndpoint           = "/admin/users"
+	AdminUserEndpoint            = "/admin/users/{id}"
+	AdminUserDeletionEndpoint    = "/admin/users/{id}/deletion"
+	AdminUserDisableEndpoint     = "/admin/users/{id}/disable"
+	AdminUserEnableEndpoint      = "/admin/users/{id}/enable"
+	AdminUserRoleEndpoint        = "/admin/users/{id}/role"
+	DefaultDSN                   = "file::memory:?cache=shared"
+	DefaultAttachmentsDir        = "attachments"
+	DefaultRateLimits            = "create_user=10/1h,login=30/1m,refresh=30/1m,send_message=60/1m," +
+		"send_conversation_message=60/1m,upload_attachment=20/1m,change_password=10/1h," +
+		"login_2fa=10/1m,two_factor=10/1h,delete_account=5/1h,data_export=3/1h"
+	DefaultDeletionJobInterval = time.Minute
+	DefaultExportJobInterval   = time.Minute
 )
 
 func main() {
-	if os.Getenv("JWT_SECRET_KEY") == "" {
-		log.Fatal("JWT_SECRET_KEY environment variable is required")
-	}
+	initKeys()
 
 	db := initDatabase()
 	appRepository := repository.NewRepository(db)
-	appService := service.NewService(appRepository)
+	initAdmins(appRepository)
+	deletions := jobs.NewWorker("account_deletion", DefaultDeletionJobInterval)
+	exports := jobs.NewWorker("data_export", DefaultExportJobInterval)
+	appService := service.NewService(appRepository,
+		service.WithStorage(initStorage()),
+		service.WithLoginLockout(initLoginLockout(appRepository)),
+		service.WithPasswordPolicy(initPasswordPolicy()),
+		service.WithPasswordHasher(initPasswordHasher()),
+		service.WithMessagingPolicy(initMessagingPolicy()),
+		service.WithAccountDeletion(initDeletionPolicy(), deletions),
+		service.WithDataExports(exports))
 
 	h := controller.NewHandler(appService)
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryBackend(), initRateLimits())
+
+	// Background jobs
+	go deletions.Run(context.Background(), appService.ProcessAccountDeletions)
+	go exports.Run(context.Background(), appService.ProcessDataExports)
 
 	// Configure endpoints
 	// Health
@@ -46,38 +114,412 @@ func main() {
 	})
 
 	// Users
+	createUser := limiter.Limit("create_user")(h.CreateUser)
+	findUser := auth.ValidateUser(db)(h.FindUser)
 	http.HandleFunc(UsersEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			createUser(w, r)
+		case http.MethodGet:
+			findUser(w, r)
+		default:
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+	})
+
+	http.HandleFunc(UserEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.GetUser(w, r)
+	}))
+
+	http.HandleFunc(ProfileEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetProfile(w, r)
+		case http.MethodPatch:
+			h.UpdateProfile(w, r)
+		case http.MethodDelete:
+			limiter.Limit("delete_account")(h.DeleteAccount)(w, r)
+		default:
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+	}))
+
+	http.HandleFunc(PasswordEndpoint, auth.ValidateUser(db)(limiter.Limit("change_password")(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
 			return
 		}
 
-		h.CreateUser(w, r)
-	})
+		h.ChangePassword(w, r)
+	})))
+
+	http.HandleFunc(TwoFactorEndpoint, auth.ValidateUser(db)(limiter.Limit("two_factor")(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.EnrollTOTP(w, r)
+	})))
+
+	http.HandleFunc(TwoFactorConfirmEndpoint, auth.ValidateUser(db)(limiter.Limit("two_factor")(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.ConfirmTOTP(w, r)
+	})))
+
+	http.HandleFunc(ExportsEndpoint, auth.ValidateUser(db)(limiter.Limit("data_export")(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.RequestDataExport(w, r)
+	})))
+
+	http.HandleFunc(ExportEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.GetDataExport(w, r)
+	}))
+
+	http.HandleFunc(ExportDownloadEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.DownloadDataExport(w, r)
+	}))
+
+	http.HandleFunc(BlocksEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.GetBlockedUsers(w, r)
+	}))
+
+	http.HandleFunc(BlockEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			h.BlockUser(w, r)
+		case http.MethodDelete:
+			h.UnblockUser(w, r)
+		default:
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+	}))
+
+	http.HandleFunc(PrivacyEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetPrivacy(w, r)
+		case http.MethodPut:
+			h.UpdatePrivacy(w, r)
+		default:
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+	}))
 
 	// Auth
-	http.HandleFunc(LoginEndpoint, func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc(LoginEndpoint, limiter.Limit("login")(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
 			return
 		}
 
 		h.Login(w, r)
+	}))
+
+	http.HandleFunc(LoginTwoFactorEndpoint, limiter.Limit("login_2fa")(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.VerifyLogin(w, r)
+	}))
+
+	http.HandleFunc(RefreshEndpoint, limiter.Limit("refresh")(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.Refresh(w, r)
+	}))
+
+	http.HandleFunc(LogoutEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.Logout(w, r)
+	}))
+
+	http.HandleFunc(JWKSEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.GetJWKS(w, r)
 	})
 
 	// Messages
+	sendMessage := limiter.Limit("send_message")(h.SendMessage)
 	http.HandleFunc(MessagesEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
 			h.GetMessages(w, r)
 		case http.MethodPost:
-			h.SendMessage(w, r)
+			sendMessage(w, r)
 		default:
 			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
 			return
 		}
 	}))
 
+	http.HandleFunc(HistoryEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.GetMessageHistory(w, r)
+	}))
+
+	http.HandleFunc(ReadEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.MarkMessagesRead(w, r)
+	}))
+
+	http.HandleFunc(UnreadEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.GetUnreadCounts(w, r)
+	}))
+
+	http.HandleFunc(InboxEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.GetInbox(w, r)
+	}))
+
+	http.HandleFunc(MessageStatusEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.GetMessageStatus(w, r)
+	}))
+
+	http.HandleFunc(MessageEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPatch:
+			h.EditMessage(w, r)
+		case http.MethodDelete:
+			h.DeleteMessage(w, r)
+		default:
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+	}))
+
+	http.HandleFunc(MessageRevisionsEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.GetMessageRevisions(w, r)
+	}))
+
+	// Real-time messages
+	http.HandleFunc(WebSocketEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.StreamMessagesWS(w, r)
+	}))
+
+	http.HandleFunc(StreamEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.StreamMessagesSSE(w, r)
+	}))
+
+	// Conversations
+	http.HandleFunc(ConversationsEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.CreateConversation(w, r)
+	}))
+
+	http.HandleFunc(ConversationEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.GetConversation(w, r)
+	}))
+
+	http.HandleFunc(ConversationMembersEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.AddConversationMember(w, r)
+	}))
+
+	http.HandleFunc(ConversationMemberEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.RemoveConversationMember(w, r)
+	}))
+
+	http.HandleFunc(ConversationLeaveEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.LeaveConversation(w, r)
+	}))
+
+	sendConversationMessage := limiter.Limit("send_conversation_message")(h.SendConversationMessage)
+	http.HandleFunc(ConversationMessagesEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetConversationMessages(w, r)
+		case http.MethodPost:
+			sendConversationMessage(w, r)
+		default:
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+	}))
+
+	// Attachments
+	http.HandleFunc(AttachmentsEndpoint, auth.ValidateUser(db)(limiter.Limit("upload_attachment")(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.UploadAttachment(w, r)
+	})))
+
+	http.HandleFunc(AttachmentEndpoint, auth.ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.DownloadAttachment(w, r)
+	}))
+
+	// Admin
+	admin := func(next http.HandlerFunc) http.HandlerFunc {
+		return auth.ValidateUser(db)(auth.RequireRole(models.RoleAdmin)(next))
+	}
+
+	http.HandleFunc(AdminUsersEndpoint, admin(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.ListUsers(w, r)
+	}))
+
+	http.HandleFunc(AdminUserEndpoint, admin(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.DeleteUser(w, r)
+	}))
+
+	http.HandleFunc(AdminUserDeletionEndpoint, admin(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.GetAccountDeletion(w, r)
+	}))
+
+	http.HandleFunc(AdminUserDisableEndpoint, admin(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.DisableUser(w, r)
+	}))
+
+	http.HandleFunc(AdminUserEnableEndpoint, admin(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.EnableUser(w, r)
+	}))
+
+	http.HandleFunc(AdminUserRoleEndpoint, admin(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, http.StatusText(405), http.StatusMethodNotAllowed)
+			return
+		}
+
+		h.AssignRole(w, r)
+	}))
+
 	// Start server
 	log.Println("Server started at port " + ServerPort)
 	log.Fatal(http.ListenAndServe(":"+ServerPort, nil))
@@ -94,10 +536,187 @@ func initDatabase() *gorm.DB {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
-	err = db.AutoMigrate(&models.User{}, &models.Message{})
+	err = db.AutoMigrate(&models.User{}, &models.Message{}, &models.MessageRevision{}, &models.Conversation{}, &models.ConversationMember{}, &models.Attachment{}, &models.RefreshToken{}, &models.RevokedToken{}, &models.LoginAttempt{}, &models.LoginLockout{}, &models.TOTPCredential{}, &models.RecoveryCode{}, &models.LoginChallenge{}, &models.AccountDeletion{}, &models.DataExport{}, &models.Block{})
 	if err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 
 	return db
 }
+
+// initKeys loads the signing keys from JWT_KEYS_DIR. Without it tokens are
+// signed with the JWT_SECRET_KEY shared secret.
+func initKeys() {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		if os.Getenv("JWT_SECRET_KEY") == "" {
+			log.Fatal("JWT_SECRET_KEY or JWT_KEYS_DIR environment variable is required")
+		}
+		return
+	}
+
+	activeID := os.Getenv("JWT_ACTIVE_KEY")
+	if activeID == "" {
+		log.Fatal("JWT_ACTIVE_KEY environment variable is required with JWT_KEYS_DIR")
+	}
+
+	keys, err := auth.LoadKeySet(dir, activeID, strings.Split(os.Getenv("JWT_RETIRED_KEYS"), ","))
+	if err != nil {
+		log.Fatalf("Failed to load signing keys: %v", err)
+	}
+	auth.Keys = keys
+}
+
+// initAdmins gives the admin role to the existing users listed in
+// ADMIN_USERNAMES, so the first admin can be set up without database access
+func initAdmins(repo repository.Repository) {
+	for _, username := range strings.Split(os.Getenv("ADMIN_USERNAMES"), ",") {
+		username = strings.TrimSpace(username)
+		if username == "" {
+			continue
+		}
+
+		user, err := repo.GetUserByUsername(username)
+		if err != nil {
+			log.Printf("Cannot make %s an admin: %v", username, err)
+			continue
+		}
+
+		if err := repo.UpdateUserRole(user.ID, models.RoleAdmin); err != nil {
+			log.Fatalf("Cannot make %s an admin: %v", username, err)
+		}
+	}
+}
+
+// initMessagingPolicy returns the messaging policy set in MESSAGING_POLICY,
+// open by default
+func initMessagingPolicy() string {
+	switch policy := os.Getenv("MESSAGING_POLICY"); policy {
+	case "", service.MessagingPolicyOpen:
+		return service.MessagingPolicyOpen
+	case service.MessagingPolicySupport:
+		return service.MessagingPolicySupport
+	default:
+		log.Fatalf("Invalid MESSAGING_POLICY: %s", policy)
+		return ""
+	}
+}
+
+// initDeletionPolicy returns what happens to the messages of deleted users, set
+// in ACCOUNT_DELETION_MESSAGES and anonymize by default
+func initDeletionPolicy() string {
+	switch policy := os.Getenv("ACCOUNT_DELETION_MESSAGES"); policy {
+	case "", models.DeletionPolicyAnonymize:
+		return models.DeletionPolicyAnonymize
+	case models.DeletionPolicyDelete, models.DeletionPolicyRetain:
+		return policy
+	default:
+		log.Fatalf("Invalid ACCOUNT_DELETION_MESSAGES: %s", policy)
+		return ""
+	}
+}
+
+// initLoginLockout keeps the failed login attempts in memory, or in the
+// database when LOGIN_ATTEMPTS_PERSIST is set so restarts do not reset them
+func initLoginLockout(repo repository.Repository) *lockout.Guard {
+	var store lockout.Store = lockout.NewMemoryStore()
+	if persist, _ := strconv.ParseBool(os.Getenv("LOGIN_ATTEMPTS_PERSIST")); persist {
+		store = repo
+	}
+
+	return lockout.NewGuard(lockout.DefaultConfig(), store)
+}
+
+// initRateLimits returns the default limits of each route overridden by the
+// ones set in RATE_LIMITS
+func initRateLimits() map[string]ratelimit.Limit {
+	limits, err := ratelimit.ParseLimits(DefaultRateLimits)
+	if err != nil {
+		log.Fatalf("Invalid default rate limits: %v", err)
+	}
+
+	overrides, err := ratelimit.ParseLimits(os.Getenv("RATE_LIMITS"))
+	if err != nil {
+		log.Fatalf("Invalid RATE_LIMITS: %v", err)
+	}
+	for route, limit := range overrides {
+		limits[route] = limit
+	}
+
+	return limits
+}
+
+// initPasswordPolicy returns the default password policy with the minimum
+// length and character classes set in PASSWORD_MIN_LENGTH and PASSWORD_MIN_CLASSES
+func initPasswordPolicy() password.Policy {
+	policy := password.DefaultPolicy()
+
+	if value := os.Getenv("PASSWORD_MIN_LENGTH"); value != "" {
+		minLength, err := strconv.Atoi(value)
+		if err != nil || minLength < 1 || minLength > policy.MaxLength {
+			log.Fatalf("Invalid PASSWORD_MIN_LENGTH: %s", value)
+		}
+		policy.MinLength = minLength
+	}
+
+	if value := os.Getenv("PASSWORD_MIN_CLASSES"); value != "" {
+		minClasses, err := strconv.Atoi(value)
+		if err != nil || minClasses < 0 || minClasses > 4 {
+			log.Fatalf("Invalid PASSWORD_MIN_CLASSES: %s", value)
+		}
+		policy.MinClasses = minClasses
+	}
+
+	return policy
+}
+
+// initPasswordHasher returns the hasher of new passwords set in PASSWORD_HASH,
+// argon2id by default, with the costs set in ARGON2_MEMORY, ARGON2_ITERATIONS,
+// ARGON2_PARALLELISM and BCRYPT_COST
+func initPasswordHasher() *password.Hasher {
+	switch algorithm := os.Getenv("PASSWORD_HASH"); algorithm {
+	case "", "argon2id":
+		params := password.DefaultArgon2Params()
+		params.Memory = uint32(envUint("ARGON2_MEMORY", uint64(params.Memory), 8, math.MaxUint32))
+		params.Iterations = uint32(envUint("ARGON2_ITERATIONS", uint64(params.Iterations), 1, math.MaxUint32))
+		params.Parallelism = uint8(envUint("ARGON2_PARALLELISM", uint64(params.Parallelism), 1, math.MaxUint8))
+
+		return password.NewHasher(password.Argon2id{Params: params})
+	case "bcrypt":
+		cost := envUint("BCRYPT_COST", uint64(bcrypt.DefaultCost), uint64(bcrypt.MinCost), uint64(bcrypt.MaxCost))
+
+		return password.NewHasher(password.Bcrypt{Cost: int(cost)})
+	default:
+		log.Fatalf("Invalid PASSWORD_HASH: %s", algorithm)
+		return nil
+	}
+}
+
+// envUint reads an unsigned integer between min and max from the environment
+func envUint(name string, fallback, min, max uint64) uint64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	number, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || number < min || number > max {
+		log.Fatalf("Invalid %s: %s", name, value)
+	}
+
+	return number
+}
+
+func initStorage() storage.Storage {
+	dir := os.Getenv("ATTACHMENTS_DIR")
+	if dir == "" {
+		dir = DefaultAttachmentsDir
+	}
+
+	store, err := storage.NewLocalStorage(dir)
+	if err != nil {
+		log.Fatalf("Failed to initialize attachment storage: %v", err)
+	}
+
+	return store
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// Issuer is the iss claim of issued tokens. Tokens from another issuer are rejected.
+	Issuer = envOrDefault("JWT_ISSUER", "challenge")
+	// Audience is the aud claim of issued tokens. Tokens for another audience are rejected.
+	Audience = envOrDefault("JWT_AUDIENCE", "challenge-api")
+	// Leeway is the clock skew tolerated when checking exp, nbf and iat
+	Leeway = 30 * time.Second
+)
+
+// Claims are the claims of an access token. The subject is the user id.
+type Claims struct {
+	SessionID string `json:"sid"`
+	Role      string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// NewClaims returns the claims of an access token issued now for the user
+func NewClaims(userID uint64, role, sessionID, tokenID string, now time.Time) Claims {
+	return Claims{
+		SessionID: sessionID,
+		Role:      role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(userID, 10),
+			Issuer:    Issuer,
+			Audience:  jwt.ClaimStrings{Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL)),
+			ID:        tokenID,
+		},
+	}
+}
+
+// UserID returns the user id stored in the subject
+func (c Claims) UserID() (uint64, error) {
+	return strconv.ParseUint(c.Subject, 10, 64)
+}
+
+// Validate checks the claims the jwt parser does not require
+func (c Claims) Validate() error {
+	if _, err := c.UserID(); err != nil {
+		return errors.New("token subject is not a user id")
+	}
+	if c.ID == "" {
+		return errors.New("token has no jti")
+	}
+	if c.SessionID == "" {
+		return errors.New("token has no sid")
+	}
+	if c.Role == "" {
+		return errors.New("token has no role")
+	}
+	if c.IssuedAt == nil || c.NotBefore == nil {
+		return errors.New("token has no iat or nbf")
+	}
+
+	return nil
+}
+
+// ParseToken verifies the signature of a token and validates its claims
+// against the configured issuer and audience
+func ParseToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, keyfunc,
+		jwt.WithIssuer(Issuer),
+		jwt.WithAudience(Audience),
+		jwt.WithLeeway(Leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func envOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return defaultValue
+}
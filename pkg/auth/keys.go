@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Keys holds the asymmetric keys used to sign and verify tokens. When it is
+// nil tokens are signed with JwtSecret using HS256.
+var Keys *KeySet
+
+const minRSAKeyBits = 2048
+
+// SigningKey is a key identified by its kid. Keys loaded from a public key
+// file can only verify tokens.
+type SigningKey struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+	Retired    bool
+}
+
+// KeySet signs tokens with its active key and verifies them with any key
+// that is not retired
+type KeySet struct {
+	keys     map[string]*SigningKey
+	activeID string
+}
+
+// JWK is the public part of a key as published in the JWKS endpoint
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewKeySet builds a key set from already parsed keys
+func NewKeySet(activeID string, keys ...*SigningKey) (*KeySet, error) {
+	set := &KeySet{keys: map[string]*SigningKey{}, activeID: activeID}
+	for _, key := range keys {
+		if _, ok := set.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicated key %q", key.ID)
+		}
+		set.keys[key.ID] = key
+	}
+
+	active, ok := set.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("active key %q not found", activeID)
+	}
+	if active.PrivateKey == nil {
+		return nil, fmt.Errorf("active key %q has no private key", activeID)
+	}
+	if active.Retired {
+		return nil, fmt.Errorf("active key %q is retired", activeID)
+	}
+
+	return set, nil
+}
+
+// LoadKeySet loads every .pem file of dir as a key named after the file. It
+// accepts RSA and Ed25519 keys, private (PKCS#8 or PKCS#1) or public (PKIX).
+func LoadKeySet(dir, activeID string, retired []string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	retiredIDs := map[string]bool{}
+	for _, id := range retired {
+		if id = strings.TrimSpace(id); id != "" {
+			retiredIDs[id] = true
+		}
+	}
+
+	var keys []*SigningKey
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		id := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := ParseSigningKey(id, data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %s: %w", path, err)
+		}
+		key.Retired = retiredIDs[id]
+		keys = append(keys, key)
+	}
+
+	return NewKeySet(activeID, keys...)
+}
+
+// ParseSigningKey parses a PEM encoded RSA or Ed25519 key
+func ParseSigningKey(id string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{ID: id}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.PrivateKey, key.PublicKey = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.PublicKey = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.PrivateKey, key.PublicKey = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.PublicKey = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+
+	if rsaKey, ok := key.PublicKey.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("RSA keys must have at least %d bits", minRSAKeyBits)
+	}
+
+	return key, nil
+}
+
+// Sign signs the claims with the active key, setting its kid in the header
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	active := k.keys[k.activeID]
+	token := jwt.NewWithClaims(active.Method, claims)
+	token.Header["kid"] = active.ID
+
+	return token.SignedString(active.PrivateKey)
+}
+
+// Keyfunc returns the key to verify a token with, based on its kid
+func (k *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	id, ok := token.Header["kid"].(string)
+	if !ok {
+		return nil, errors.New("token has no kid")
+	}
+
+	key, ok := k.keys[id]
+	if !ok || key.Retired {
+		return nil, fmt.Errorf("unknown key %q", id)
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), id)
+	}
+
+	return key.PublicKey, nil
+}
+
+// JWKS returns the public keys that are still accepted
+func (k *KeySet) JWKS() JWKS {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	jwks := JWKS{Keys: []JWK{}}
+	for _, id := range ids {
+		key := k.keys[id]
+		if key.Retired {
+			continue
+		}
+
+		jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Method.Alg()}
+		switch pub := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
+
+// SignToken signs the claims with the configured keys, or with JwtSecret
+// when no key set was loaded
+func SignToken(claims jwt.Claims) (string, error) {
+	if Keys != nil {
+		return Keys.Sign(claims)
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(JwtSecret)
+}
+
+// PublicKeys returns the JWKS of the configured keys. It is empty when tokens
+// are signed with the shared secret.
+func PublicKeys() JWKS {
+	if Keys != nil {
+		return Keys.JWKS()
+	}
+
+	return JWKS{Keys: []JWK{}}
+}
+
+// keyfunc resolves the key to verify a token with. Only the configured kind of
+// keys is accepted, so an HS256 token cannot be used once keys are loaded.
+func keyfunc(token *jwt.Token) (interface{}, error) {
+	if Keys != nil {
+		return Keys.Keyfunc(token)
+	}
+
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("unexpected signing method")
+	}
+	return JwtSecret, nil
+}
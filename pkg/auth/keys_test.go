@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/repository"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, name+".pem"), data, 0o600))
+}
+
+// setupTestKeys writes an RSA key, an Ed25519 key and the public half of
+// another RSA key to a temporary directory
+func setupTestKeys(t *testing.T) (string, *rsa.PrivateKey) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	assert.NoError(t, err)
+	writePEM(t, dir, "rsa-1", "PRIVATE KEY", der)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	der, err = x509.MarshalPKCS8PrivateKey(edKey)
+	assert.NoError(t, err)
+	writePEM(t, dir, "ed-1", "PRIVATE KEY", der)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	der, err = x509.MarshalPKIXPublicKey(&otherKey.PublicKey)
+	assert.NoError(t, err)
+	writePEM(t, dir, "rsa-old", "PUBLIC KEY", der)
+
+	return dir, otherKey
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir, _ := setupTestKeys(t)
+
+	tests := []struct {
+		name        string
+		activeID    string
+		retired     []string
+		expectError bool
+	}{
+		{name: "rsa active key", activeID: "rsa-1"},
+		{name: "ed25519 active key", activeID: "ed-1", retired: []string{"rsa-old"}},
+		{name: "unknown active key", activeID: "missing", expectError: true},
+		{name: "public key cannot be active", activeID: "rsa-old", expectError: true},
+		{name: "retired key cannot be active", activeID: "rsa-1", retired: []string{"rsa-1"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadKeySet(dir, tt.activeID, tt.retired)
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestParseSigningKey_SmallRSAKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	_, err = ParseSigningKey("small", data)
+	assert.Error(t, err)
+}
+
+func TestKeySet_JWKS(t *testing.T) {
+	dir, _ := setupTestKeys(t)
+	keys, err := LoadKeySet(dir, "ed-1", []string{"rsa-old"})
+	assert.NoError(t, err)
+
+	jwks := keys.JWKS()
+	assert.Len(t, jwks.Keys, 2)
+	assert.Equal(t, "ed-1", jwks.Keys[0].KeyID)
+	assert.Equal(t, "OKP", jwks.Keys[0].KeyType)
+	assert.Equal(t, "EdDSA", jwks.Keys[0].Algorithm)
+	assert.NotEmpty(t, jwks.Keys[0].X)
+	assert.Equal(t, "rsa-1", jwks.Keys[1].KeyID)
+	assert.Equal(t, "RSA", jwks.Keys[1].KeyType)
+	assert.Equal(t, "RS256", jwks.Keys[1].Algorithm)
+	assert.Equal(t, "AQAB", jwks.Keys[1].E)
+}
+
+func TestValidateUser_KeySet(t *testing.T) {
+	db := repository.SetupTestDB(t)
+	assert.NoError(t, db.Create(&models.User{ID: 1, Username: "testuser", Password: "password"}).Error)
+
+	dir, oldKey := setupTestKeys(t)
+	keys, err := LoadKeySet(dir, "rsa-1", []string{"rsa-old"})
+	assert.NoError(t, err)
+	Keys = keys
+	t.Cleanup(func() { Keys = nil })
+
+	claims := testClaims(nil)
+
+	signed, err := SignToken(claims)
+	assert.NoError(t, err)
+
+	retiredToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	retiredToken.Header["kid"] = "rsa-old"
+	retired, err := retiredToken.SignedString(oldKey)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+	}{
+		{name: "active key", token: signed, expectedStatus: http.StatusOK},
+		{name: "retired key", token: retired, expectedStatus: http.StatusUnauthorized},
+		{name: "shared secret", token: createTestToken(claims), expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rr := httptest.NewRecorder()
+
+			handler := ValidateUser(db)(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			handler.ServeHTTP(rr, req)
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+		})
+	}
+}
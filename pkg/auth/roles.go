@@ -0,0 +1,25 @@
+package auth
+
+import (
+	"log"
+	"net/http"
+)
+
+// RequireRole only lets through requests made with a token that has one of
+// the roles. It must be wrapped by ValidateUser, which puts the role in the context.
+func RequireRole(roles ...string) func(_ http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			role, _ := r.Context().Value("role").(string)
+			for _, allowed := range roles {
+				if role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			log.Printf("Forbidden: role %q is not one of %v", role, roles)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireRole(t *testing.T) {
+	tests := []struct {
+		name           string
+		role           interface{}
+		expectedStatus int
+	}{
+		{name: "allowed role", role: "admin", expectedStatus: http.StatusOK},
+		{name: "other allowed role", role: "agent", expectedStatus: http.StatusOK},
+		{name: "forbidden role", role: "user", expectedStatus: http.StatusForbidden},
+		{name: "no role", role: nil, expectedStatus: http.StatusForbidden},
+	}
+
+	handler := RequireRole("admin", "agent")(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.role != nil {
+				ctx = context.WithValue(ctx, "role", tt.role)
+			}
+			req := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+		})
+	}
+}
@@ -2,18 +2,24 @@ package auth
 
 import (
 	"context"
-	"fmt"
 	"github.com/challenge/pkg/models"
-	"github.com/golang-jwt/jwt/v5"
 	"gorm.io/gorm"
 	"log"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 )
 
 var JwtSecret = []byte(os.Getenv("JWT_SECRET_KEY"))
 
+const (
+	// AccessTokenTTL is how long an access token can be used
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL is how long a refresh token can be used to get a new access token
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
 // ValidateUser checks for a token and validates it
 // before allowing the method to execute
 func ValidateUser(db *gorm.DB) func(_ http.HandlerFunc) http.HandlerFunc {
@@ -28,48 +34,49 @@ func ValidateUser(db *gorm.DB) func(_ http.HandlerFunc) http.HandlerFunc {
 
 			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-			token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, fmt.Errorf("unexpected signing method")
-				}
-				return JwtSecret, nil
-			})
-
-			if err != nil || !token.Valid {
+			claims, err := ParseToken(tokenString)
+			if err != nil {
 				log.Println(err)
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
 			}
 
-			claims, ok := token.Claims.(jwt.MapClaims)
-			if !ok {
-				log.Println("Invalid token: claims not map claims")
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
-			}
+			tokenID, sessionID := claims.ID, claims.SessionID
+			userID, _ := claims.UserID()
 
-			userIDraw, ok := claims["user_id"].(float64)
-			if !ok {
-				http.Error(w, "Invalid token", http.StatusUnauthorized)
+			var revoked int64
+			if err := db.Model(&models.RevokedToken{}).Where("jti = ?", tokenID).Count(&revoked).Error; err != nil {
+				log.Println(err)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
 			}
-
-			_, ok = claims["exp"].(float64)
-			if !ok {
-				http.Error(w, "Invalid token", http.StatusUnauthorized)
+			if revoked > 0 {
+				log.Println("Invalid token: token was revoked")
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
 			}
 
-			userID := uint64(userIDraw)
-
 			var user models.User
 			if err := db.Where("id = ?", userID).First(&user).Error; err != nil {
 				log.Println("User not found")
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
 			}
+			if user.DisabledAt != nil {
+				log.Println("Invalid token: user is disabled")
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			// A token issued before a role change has to be refreshed to get the new role
+			if user.Role != claims.Role {
+				log.Println("Invalid token: role changed")
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
 
 			ctx := context.WithValue(r.Context(), "user_id", userID)
+			ctx = context.WithValue(ctx, "session_id", sessionID)
+			ctx = context.WithValue(ctx, "role", claims.Role)
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		}
@@ -18,6 +18,32 @@ func createTestToken(claims jwt.MapClaims) string {
 	return tokenString
 }
 
+// testClaims returns valid claims for user 1 with the given claims replaced,
+// or removed when their value is nil
+func testClaims(overrides jwt.MapClaims) jwt.MapClaims {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub":  "1",
+		"iss":  Issuer,
+		"aud":  Audience,
+		"iat":  now.Unix(),
+		"nbf":  now.Unix(),
+		"exp":  now.Add(time.Hour).Unix(),
+		"jti":  "token",
+		"sid":  "session",
+		"role": "user",
+	}
+	for key, value := range overrides {
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+	}
+
+	return claims
+}
+
 func TestValidateUser(t *testing.T) {
 	db := repository.SetupTestDB(t)
 	middleware := ValidateUser(db)
@@ -30,41 +56,91 @@ func TestValidateUser(t *testing.T) {
 	err := db.Create(testUser).Error
 	assert.NoError(t, err)
 
+	disabledAt := time.Now()
+	err = db.Create(&models.User{ID: 3, Username: "disabled", Password: "password", DisabledAt: &disabledAt}).Error
+	assert.NoError(t, err)
+
+	err = db.Create(&models.RevokedToken{JTI: "revoked", ExpiresAt: time.Now().Add(time.Hour)}).Error
+	assert.NoError(t, err)
+
 	tests := []struct {
 		name           string
 		token          string
 		expectedStatus int
 	}{
 		{
-			name: "Valid token",
-			token: "Bearer " + createTestToken(jwt.MapClaims{
-				"user_id": 1,
-				"exp":     time.Now().Add(time.Hour).Unix(),
-			}),
+			name:           "Valid token",
+			token:          "Bearer " + createTestToken(testClaims(nil)),
 			expectedStatus: http.StatusOK,
 		},
 		{
-			name: "No Bearer prefix",
-			token: createTestToken(jwt.MapClaims{
-				"user_id": 1,
-				"exp":     time.Now().Add(time.Hour).Unix(),
-			}),
+			name:           "Revoked token",
+			token:          "Bearer " + createTestToken(testClaims(jwt.MapClaims{"jti": "revoked"})),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "jti not a claim",
+			token:          "Bearer " + createTestToken(testClaims(jwt.MapClaims{"jti": nil})),
 			expectedStatus: http.StatusUnauthorized,
 		},
 		{
-			name: "Expired token",
-			token: "Bearer " + createTestToken(jwt.MapClaims{
-				"user_id": 1,
-				"exp":     time.Now().Add(-time.Hour).Unix(),
-			}),
+			name:           "sid not a claim",
+			token:          "Bearer " + createTestToken(testClaims(jwt.MapClaims{"sid": nil})),
 			expectedStatus: http.StatusUnauthorized,
 		},
 		{
-			name: "Invalid user",
-			token: "Bearer " + createTestToken(jwt.MapClaims{
-				"user_id": 2,
-				"exp":     time.Now().Add(time.Hour).Unix(),
-			}),
+			name:           "No Bearer prefix",
+			token:          createTestToken(testClaims(nil)),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Expired token",
+			token:          "Bearer " + createTestToken(testClaims(jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()})),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Expired within leeway",
+			token:          "Bearer " + createTestToken(testClaims(jwt.MapClaims{"exp": time.Now().Add(-10 * time.Second).Unix()})),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Not valid yet",
+			token:          "Bearer " + createTestToken(testClaims(jwt.MapClaims{"nbf": time.Now().Add(time.Hour).Unix()})),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "iat not a claim",
+			token:          "Bearer " + createTestToken(testClaims(jwt.MapClaims{"iat": nil})),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Other issuer",
+			token:          "Bearer " + createTestToken(testClaims(jwt.MapClaims{"iss": "staging"})),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Other audience",
+			token:          "Bearer " + createTestToken(testClaims(jwt.MapClaims{"aud": "staging-api"})),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Invalid user",
+			token:          "Bearer " + createTestToken(testClaims(jwt.MapClaims{"sub": "2"})),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Disabled user",
+			token:          "Bearer " + createTestToken(testClaims(jwt.MapClaims{"sub": "3"})),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Role changed since the token was issued",
+			token:          "Bearer " + createTestToken(testClaims(jwt.MapClaims{"role": "admin"})),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "role not a claim",
+			token:          "Bearer " + createTestToken(testClaims(jwt.MapClaims{"role": nil})),
 			expectedStatus: http.StatusUnauthorized,
 		},
 		{
@@ -73,17 +149,18 @@ func TestValidateUser(t *testing.T) {
 			expectedStatus: http.StatusUnauthorized,
 		},
 		{
-			name: "user_id not a claim",
-			token: "Bearer " + createTestToken(jwt.MapClaims{
-				"exp": time.Now().Add(time.Hour).Unix(),
-			}),
+			name:           "sub not a claim",
+			token:          "Bearer " + createTestToken(testClaims(jwt.MapClaims{"sub": nil})),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "sub not a user id",
+			token:          "Bearer " + createTestToken(testClaims(jwt.MapClaims{"sub": "admin"})),
 			expectedStatus: http.StatusUnauthorized,
 		},
 		{
-			name: "exp not a claim",
-			token: "Bearer " + createTestToken(jwt.MapClaims{
-				"user_id": 1,
-			}),
+			name:           "exp not a claim",
+			token:          "Bearer " + createTestToken(testClaims(jwt.MapClaims{"exp": nil})),
 			expectedStatus: http.StatusUnauthorized,
 		},
 	}
@@ -99,6 +176,7 @@ func TestValidateUser(t *testing.T) {
 			handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				userID := r.Context().Value("user_id")
 				assert.NotNil(t, userID)
+				assert.Equal(t, "user", r.Context().Value("role"))
 				w.WriteHeader(http.StatusOK)
 			}))
 
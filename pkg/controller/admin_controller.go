@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/helpers"
+	"github.com/challenge/pkg/models"
+)
+
+// AdminUserResponse is a user as seen by admins
+type AdminUserResponse struct {
+	ProfileResponse
+	DisabledAt *time.Time `json:"disabled_at"`
+}
+
+type AssignRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// ListUsers lists the users of the platform ordered by id
+func (h Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	after, err := strconv.ParseUint(r.FormValue("after"), 10, 64)
+	if err != nil && r.FormValue("after") != "" {
+		http.Error(w, "Invalid after value", http.StatusBadRequest)
+		return
+	}
+
+	limitStr := r.FormValue("limit")
+	if limitStr == "" {
+		limitStr = helpers.DefaultUsersLimit
+	}
+
+	limit, err := strconv.ParseUint(limitStr, 10, 32)
+	if err != nil || limit == 0 {
+		http.Error(w, "Invalid limit value", http.StatusBadRequest)
+		return
+	}
+
+	users, err := h.Service.ListUsers(after, limit)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	response := make([]AdminUserResponse, 0, len(users))
+	for i := range users {
+		response = append(response, newAdminUserResponse(&users[i]))
+	}
+
+	helpers.RespondJSON(w, response)
+}
+
+// DisableUser disables the account of a user and logs them out
+func (h Handler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserDisabled(w, r, true)
+}
+
+// EnableUser enables the account of a disabled user
+func (h Handler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserDisabled(w, r, false)
+}
+
+// AssignRole changes the role of a user
+func (h Handler) AssignRole(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	var req AssignRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.Service.AssignRole(requestUserID(r), userID, req.Role)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, newAdminUserResponse(user))
+}
+
+// DeleteUser schedules the deletion of the account of a user
+func (h Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	deletion, err := h.Service.DeleteUser(requestUserID(r), userID)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSONStatus(w, http.StatusAccepted, deletion)
+}
+
+// GetAccountDeletion returns the progress of the deletion of the account of a user
+func (h Handler) GetAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	deletion, err := h.Service.GetAccountDeletion(userID)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, deletion)
+}
+
+func (h Handler) setUserDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	userID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.Service.SetUserDisabled(requestUserID(r), userID, disabled)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, newAdminUserResponse(user))
+}
+
+func newAdminUserResponse(user *models.User) AdminUserResponse {
+	return AdminUserResponse{
+		ProfileResponse: newProfileResponse(user),
+		DisabledAt:      user.DisabledAt,
+	}
+}
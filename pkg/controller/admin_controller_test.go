@@ -0,0 +1,257 @@
+package controller
+
+import (
+	"context"
+	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/service"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListUsers(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		setupMock    func(mock *service.MockService)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:  "success",
+			query: "?after=1&limit=2",
+			setupMock: func(mock *service.MockService) {
+				mock.On("ListUsers", uint64(1), uint64(2)).Return([]models.User{{ID: 2, Username: "peer", Role: models.RoleUser}}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `[{"id":2,"username":"peer","display_name":"","avatar_url":"","status_text":"","role":"user","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z","disabled_at":null}]`,
+		},
+		{
+			name:  "default page",
+			query: "",
+			setupMock: func(mock *service.MockService) {
+				mock.On("ListUsers", uint64(0), uint64(100)).Return([]models.User{}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `[]`,
+		},
+		{
+			name:         "invalid after",
+			query:        "?after=abc",
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid after value\n",
+		},
+		{
+			name:         "invalid limit",
+			query:        "?limit=0",
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid limit value\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/users"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			handler.ListUsers(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDisableUser(t *testing.T) {
+	disabledAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		id           string
+		handler      func(h Handler) http.HandlerFunc
+		setupMock    func(mock *service.MockService)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:    "disable",
+			id:      "2",
+			handler: func(h Handler) http.HandlerFunc { return h.DisableUser },
+			setupMock: func(mock *service.MockService) {
+				mock.On("SetUserDisabled", uint64(1), uint64(2), true).
+					Return(&models.User{ID: 2, Username: "peer", Role: models.RoleUser, DisabledAt: &disabledAt}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"id":2,"username":"peer","display_name":"","avatar_url":"","status_text":"","role":"user","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z","disabled_at":"2025-01-02T03:04:05Z"}`,
+		},
+		{
+			name:    "enable",
+			id:      "2",
+			handler: func(h Handler) http.HandlerFunc { return h.EnableUser },
+			setupMock: func(mock *service.MockService) {
+				mock.On("SetUserDisabled", uint64(1), uint64(2), false).
+					Return(&models.User{ID: 2, Username: "peer", Role: models.RoleUser}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"id":2,"username":"peer","display_name":"","avatar_url":"","status_text":"","role":"user","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z","disabled_at":null}`,
+		},
+		{
+			name:    "own account",
+			id:      "1",
+			handler: func(h Handler) http.HandlerFunc { return h.DisableUser },
+			setupMock: func(mock *service.MockService) {
+				mock.On("SetUserDisabled", uint64(1), uint64(1), true).
+					Return(nil, httperrors.BadRequestError("you cannot disable your own account"))
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "you cannot disable your own account\n",
+		},
+		{
+			name:         "invalid id",
+			id:           "peer",
+			handler:      func(h Handler) http.HandlerFunc { return h.DisableUser },
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid user id\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/users/"+tt.id+"/disable", nil)
+			req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+			req.SetPathValue("id", tt.id)
+			w := httptest.NewRecorder()
+
+			tt.handler(handler)(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAssignRole(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		setupMock    func(mock *service.MockService)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "success",
+			body: `{"role":"agent"}`,
+			setupMock: func(mock *service.MockService) {
+				mock.On("AssignRole", uint64(1), uint64(2), models.RoleAgent).
+					Return(&models.User{ID: 2, Username: "peer", Role: models.RoleAgent}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"id":2,"username":"peer","display_name":"","avatar_url":"","status_text":"","role":"agent","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z","disabled_at":null}`,
+		},
+		{
+			name: "invalid role",
+			body: `{"role":"root"}`,
+			setupMock: func(mock *service.MockService) {
+				mock.On("AssignRole", uint64(1), uint64(2), "root").Return(nil, httperrors.BadRequestError("invalid role"))
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "invalid role\n",
+		},
+		{
+			name:         "invalid request body",
+			body:         `{`,
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid request body\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodPut, "/admin/users/2/role", strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+			req.SetPathValue("id", "2")
+			w := httptest.NewRecorder()
+
+			handler.AssignRole(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	mockService := new(service.MockService)
+	mockService.On("DeleteUser", uint64(1), uint64(2)).
+		Return(&models.AccountDeletion{ID: 1, UserID: 2, RequestedBy: 1, Status: models.AccountDeletionPending}, nil)
+	mockService.On("DeleteUser", uint64(1), uint64(1)).
+		Return(nil, httperrors.BadRequestError("you cannot delete your own account"))
+	handler := NewHandler(mockService)
+
+	for _, tt := range []struct {
+		id           string
+		expectedCode int
+	}{
+		{id: "2", expectedCode: http.StatusAccepted},
+		{id: "1", expectedCode: http.StatusBadRequest},
+		{id: "peer", expectedCode: http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(http.MethodDelete, "/admin/users/"+tt.id, nil)
+		req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+		req.SetPathValue("id", tt.id)
+		w := httptest.NewRecorder()
+
+		handler.DeleteUser(w, req)
+
+		assert.Equal(t, tt.expectedCode, w.Code, tt.id)
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestGetAccountDeletion(t *testing.T) {
+	mockService := new(service.MockService)
+	mockService.On("GetAccountDeletion", uint64(2)).
+		Return(&models.AccountDeletion{ID: 1, UserID: 2, Status: models.AccountDeletionCompleted, MessagesProcessed: 42}, nil)
+	mockService.On("GetAccountDeletion", uint64(3)).
+		Return(nil, httperrors.NotFoundError("account deletion not found"))
+	handler := NewHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/users/2/deletion", nil)
+	req.SetPathValue("id", "2")
+	w := httptest.NewRecorder()
+	handler.GetAccountDeletion(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"completed","messages_processed":42`)
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/users/3/deletion", nil)
+	req.SetPathValue("id", "3")
+	w = httptest.NewRecorder()
+	handler.GetAccountDeletion(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertExpectations(t)
+}
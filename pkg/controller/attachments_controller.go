@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/helpers"
+)
+
+// multipartOverhead leaves room for the multipart headers and boundaries on
+// top of the attachment itself
+const multipartOverhead = 64 << 10
+
+// UploadAttachment stores the "file" part of a multipart request for the logged user
+func (h Handler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, helpers.MaxAttachmentSize+multipartOverhead)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "Invalid multipart request", http.StatusBadRequest)
+		return
+	}
+
+	// Stream the file straight to the storage instead of buffering the whole form
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			http.Error(w, "Invalid multipart request", http.StatusBadRequest)
+			return
+		}
+
+		if part.FormName() != "file" {
+			_ = part.Close()
+			continue
+		}
+
+		attachment, err := h.Service.UploadAttachment(requestUserID(r), part.FileName(), part)
+		_ = part.Close()
+		if err != nil {
+			errors.HandleError(w, err)
+			return
+		}
+
+		helpers.RespondJSON(w, attachment)
+		return
+	}
+
+	http.Error(w, "Missing file", http.StatusBadRequest)
+}
+
+// DownloadAttachment streams an attachment the logged user is allowed to see
+func (h Handler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	attachmentID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+
+	attachment, content, err := h.Service.GetAttachment(requestUserID(r), attachmentID)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", attachment.MimeType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	w.WriteHeader(http.StatusOK)
+
+	_, _ = io.Copy(w, content)
+}
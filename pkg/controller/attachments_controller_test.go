@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func multipartBody(t *testing.T, field, fileName, content string) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile(field, fileName)
+	assert.NoError(t, err)
+	_, err = part.Write([]byte(content))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	return body, writer.FormDataContentType()
+}
+
+func TestUploadAttachment(t *testing.T) {
+	tests := []struct {
+		name           string
+		field          string
+		contentType    string
+		setupMock      func(mock *service.MockService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:  "success",
+			field: "file",
+			setupMock: func(m *service.MockService) {
+				m.On("UploadAttachment", uint64(1), "cat.png", mock.MatchedBy(func(r io.Reader) bool {
+					data, _ := io.ReadAll(r)
+					return string(data) == "png data"
+				})).Return(&models.Attachment{
+					ID: 3, OwnerID: 1, FileName: "cat.png", MimeType: "image/png", Size: 8, SHA256: "abc",
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":3,"owner":1,"file_name":"cat.png","mime_type":"image/png","size":8,"sha256":"abc","created_at":"0001-01-01T00:00:00Z"}`,
+		},
+		{
+			name:  "service error",
+			field: "file",
+			setupMock: func(m *service.MockService) {
+				m.On("UploadAttachment", uint64(1), "cat.png", mock.Anything).
+					Return(nil, httperrors.BadRequestError("unsupported attachment type"))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "unsupported attachment type\n",
+		},
+		{
+			name:           "missing file",
+			field:          "other",
+			setupMock:      func(m *service.MockService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Missing file\n",
+		},
+		{
+			name:           "not multipart",
+			field:          "file",
+			contentType:    "application/json",
+			setupMock:      func(m *service.MockService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid multipart request\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			body, contentType := multipartBody(t, tt.field, "cat.png", "png data")
+			if tt.contentType != "" {
+				contentType = tt.contentType
+			}
+			req := httptest.NewRequest(http.MethodPost, "/attachments", body)
+			req.Header.Set("Content-Type", contentType)
+			req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+			w := httptest.NewRecorder()
+
+			handler.UploadAttachment(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDownloadAttachment(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		setupMock      func(mock *service.MockService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "success",
+			id:   "3",
+			setupMock: func(m *service.MockService) {
+				m.On("GetAttachment", uint64(1), uint64(3)).Return(&models.Attachment{
+					ID: 3, FileName: "cat.png", MimeType: "image/png", Size: 8,
+				}, io.NopCloser(strings.NewReader("png data")), nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "png data",
+		},
+		{
+			name: "forbidden",
+			id:   "3",
+			setupMock: func(m *service.MockService) {
+				m.On("GetAttachment", uint64(1), uint64(3)).
+					Return(nil, nil, httperrors.ForbiddenError("you are not allowed to see this attachment"))
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "you are not allowed to see this attachment\n",
+		},
+		{
+			name:           "invalid id",
+			id:             "abc",
+			setupMock:      func(m *service.MockService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid attachment ID\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodGet, "/attachments/"+tt.id, nil)
+			req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+			req.SetPathValue("id", tt.id)
+			w := httptest.NewRecorder()
+
+			handler.DownloadAttachment(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+				assert.Equal(t, "8", w.Header().Get("Content-Length"))
+				assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+				assert.Equal(t, `attachment; filename=cat.png`, w.Header().Get("Content-Disposition"))
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/helpers"
+)
+
+// PrivacyResponse holds the privacy settings of the logged user
+type PrivacyResponse struct {
+	ContactsOnly bool `json:"contacts_only"`
+}
+
+type UpdatePrivacyRequest struct {
+	ContactsOnly *bool `json:"contacts_only"`
+}
+
+// GetBlockedUsers returns the block list of the logged user
+func (h Handler) GetBlockedUsers(w http.ResponseWriter, r *http.Request) {
+	blocks, err := h.Service.GetBlockedUsers(requestUserID(r))
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, blocks)
+}
+
+// BlockUser adds the user in the path to the block list of the logged user
+func (h Handler) BlockUser(w http.ResponseWriter, r *http.Request) {
+	blockedID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	block, err := h.Service.BlockUser(requestUserID(r), blockedID)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, block)
+}
+
+// UnblockUser removes the user in the path from the block list of the logged user
+func (h Handler) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	blockedID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.UnblockUser(requestUserID(r), blockedID); err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPrivacy returns the privacy settings of the logged user
+func (h Handler) GetPrivacy(w http.ResponseWriter, r *http.Request) {
+	user, err := h.Service.GetUserProfile(requestUserID(r))
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, PrivacyResponse{ContactsOnly: user.ContactsOnly})
+}
+
+// UpdatePrivacy changes the privacy settings of the logged user
+func (h Handler) UpdatePrivacy(w http.ResponseWriter, r *http.Request) {
+	var req UpdatePrivacyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.ContactsOnly == nil {
+		http.Error(w, "Invalid contacts_only value", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.Service.SetContactsOnly(requestUserID(r), *req.ContactsOnly)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, PrivacyResponse{ContactsOnly: user.ContactsOnly})
+}
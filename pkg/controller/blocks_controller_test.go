@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"context"
+	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/service"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetBlockedUsers(t *testing.T) {
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	mockService := new(service.MockService)
+	mockService.On("GetBlockedUsers", uint64(1)).Return([]models.Block{{UserID: 1, BlockedID: 2, CreatedAt: createdAt}}, nil)
+	handler := NewHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/me/blocks", nil)
+	req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+	w := httptest.NewRecorder()
+
+	handler.GetBlockedUsers(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[{"user":2,"created_at":"2025-01-02T03:04:05Z"}]`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestBlockUser(t *testing.T) {
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		id           string
+		handler      func(h Handler) http.HandlerFunc
+		setupMock    func(mock *service.MockService)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:    "block",
+			id:      "2",
+			handler: func(h Handler) http.HandlerFunc { return h.BlockUser },
+			setupMock: func(mock *service.MockService) {
+				mock.On("BlockUser", uint64(1), uint64(2)).Return(&models.Block{UserID: 1, BlockedID: 2, CreatedAt: createdAt}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"user":2,"created_at":"2025-01-02T03:04:05Z"}`,
+		},
+		{
+			name:    "block yourself",
+			id:      "1",
+			handler: func(h Handler) http.HandlerFunc { return h.BlockUser },
+			setupMock: func(mock *service.MockService) {
+				mock.On("BlockUser", uint64(1), uint64(1)).Return(nil, httperrors.BadRequestError("you cannot block yourself"))
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "you cannot block yourself\n",
+		},
+		{
+			name:    "unblock",
+			id:      "2",
+			handler: func(h Handler) http.HandlerFunc { return h.UnblockUser },
+			setupMock: func(mock *service.MockService) {
+				mock.On("UnblockUser", uint64(1), uint64(2)).Return(nil)
+			},
+			expectedCode: http.StatusNoContent,
+			expectedBody: "",
+		},
+		{
+			name:         "invalid id",
+			id:           "peer",
+			handler:      func(h Handler) http.HandlerFunc { return h.UnblockUser },
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid user id\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodPut, "/users/me/blocks/"+tt.id, nil)
+			req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+			req.SetPathValue("id", tt.id)
+			w := httptest.NewRecorder()
+
+			tt.handler(handler)(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUpdatePrivacy(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		setupMock    func(mock *service.MockService)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "contacts only",
+			body: `{"contacts_only":true}`,
+			setupMock: func(mock *service.MockService) {
+				mock.On("SetContactsOnly", uint64(1), true).Return(&models.User{ID: 1, ContactsOnly: true}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"contacts_only":true}`,
+		},
+		{
+			name:         "missing setting",
+			body:         `{}`,
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid contacts_only value\n",
+		},
+		{
+			name:         "invalid body",
+			body:         `{"contacts_only":"yes"}`,
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid request body\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodPut, "/users/me/privacy", strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+			w := httptest.NewRecorder()
+
+			handler.UpdatePrivacy(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetPrivacy(t *testing.T) {
+	mockService := new(service.MockService)
+	mockService.On("GetUserProfile", uint64(1)).Return(&models.User{ID: 1, ContactsOnly: true}, nil)
+	handler := NewHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/me/privacy", nil)
+	req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+	w := httptest.NewRecorder()
+
+	handler.GetPrivacy(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"contacts_only":true}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
@@ -1,6 +1,10 @@
 package controller
 
-import "github.com/challenge/pkg/service"
+import (
+	"net/http"
+
+	"github.com/challenge/pkg/service"
+)
 
 // Handler provides the interface to handle different requests
 type Handler struct {
@@ -10,3 +14,9 @@ type Handler struct {
 func NewHandler(service service.Service) Handler {
 	return Handler{Service: service}
 }
+
+// requestUserID returns the id of the user authenticated by auth.ValidateUser
+func requestUserID(r *http.Request) uint64 {
+	userID, _ := r.Context().Value("user_id").(uint64)
+	return userID
+}
@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"encoding/json"
+	"github.com/challenge/pkg/errors"
+	"net/http"
+	"strconv"
+
+	"github.com/challenge/pkg/helpers"
+	"github.com/challenge/pkg/models"
+)
+
+type CreateConversationRequest struct {
+	Name    string   `json:"name"`
+	Members []uint64 `json:"members"`
+}
+
+type AddConversationMemberRequest struct {
+	UserID uint64 `json:"user"`
+	Role   string `json:"role"`
+}
+
+type SendConversationMessageRequest struct {
+	Content models.Content `json:"content"`
+}
+
+// CreateConversation creates a group conversation owned by the logged user
+func (h Handler) CreateConversation(w http.ResponseWriter, r *http.Request) {
+	requestUser := requestUserID(r)
+	var req CreateConversationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	conversation, err := h.Service.CreateConversation(requestUser, req.Name, req.Members)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, conversation)
+}
+
+// GetConversation returns a conversation and its members
+func (h Handler) GetConversation(w http.ResponseWriter, r *http.Request) {
+	conversationID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid conversation ID", http.StatusBadRequest)
+		return
+	}
+
+	conversation, err := h.Service.GetConversation(requestUserID(r), conversationID)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, conversation)
+}
+
+// AddConversationMember adds a user to a conversation
+func (h Handler) AddConversationMember(w http.ResponseWriter, r *http.Request) {
+	conversationID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid conversation ID", http.StatusBadRequest)
+		return
+	}
+
+	var req AddConversationMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	member, err := h.Service.AddConversationMember(requestUserID(r), conversationID, req.UserID, req.Role)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, member)
+}
+
+// RemoveConversationMember removes a user from a conversation
+func (h Handler) RemoveConversationMember(w http.ResponseWriter, r *http.Request) {
+	conversationID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid conversation ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := strconv.ParseUint(r.PathValue("user"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.RemoveConversationMember(requestUserID(r), conversationID, userID); err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LeaveConversation removes the logged user from a conversation
+func (h Handler) LeaveConversation(w http.ResponseWriter, r *http.Request) {
+	conversationID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid conversation ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.LeaveConversation(requestUserID(r), conversationID); err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SendConversationMessage sends a message from the logged user to a conversation
+func (h Handler) SendConversationMessage(w http.ResponseWriter, r *http.Request) {
+	conversationID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid conversation ID", http.StatusBadRequest)
+		return
+	}
+
+	var req SendConversationMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	message, err := h.Service.SendConversationMessage(requestUserID(r), conversationID, &req.Content)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, MessageResponse{
+		ID:        message.Id,
+		Timestamp: message.Timestamp,
+	})
+}
+
+// GetConversationMessages gets the messages of a conversation the logged user belongs to
+func (h Handler) GetConversationMessages(w http.ResponseWriter, r *http.Request) {
+	conversationID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid conversation ID", http.StatusBadRequest)
+		return
+	}
+
+	startStr := r.FormValue("start")
+	if startStr == "" {
+		startStr = "0"
+	}
+
+	start, err := strconv.ParseUint(startStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid start value", http.StatusBadRequest)
+		return
+	}
+
+	limitStr := r.FormValue("limit")
+	if limitStr == "" {
+		limitStr = helpers.DefaultMessagesLimit
+	}
+
+	limit, err := strconv.ParseUint(limitStr, 10, 32)
+	if err != nil || limit == 0 {
+		http.Error(w, "Invalid limit value", http.StatusBadRequest)
+		return
+	}
+	limit = min(limit, helpers.MaxMessagesLimit)
+
+	messages, err := h.Service.GetConversationMessages(requestUserID(r), conversationID, start, limit)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, messages)
+}
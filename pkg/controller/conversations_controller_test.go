@@ -0,0 +1,258 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/service"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateConversation(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        interface{}
+		setupMock    func(mock *service.MockService)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:  "success",
+			input: map[string]interface{}{"name": "team", "members": []uint64{2, 3}},
+			setupMock: func(mock *service.MockService) {
+				mock.On("CreateConversation", uint64(1), "team", []uint64{2, 3}).Return(&models.Conversation{
+					ID:        1,
+					Name:      "team",
+					CreatedBy: 1,
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"id":1,"name":"team","created_by":1,"created_at":"0001-01-01T00:00:00Z"}`,
+		},
+		{
+			name:         "failure - invalid request body",
+			input:        "invalid",
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid request body\n",
+		},
+		{
+			name:  "failure - service error",
+			input: map[string]interface{}{"name": ""},
+			setupMock: func(mock *service.MockService) {
+				mock.On("CreateConversation", uint64(1), "", []uint64(nil)).Return(nil, httperrors.BadRequestError("invalid conversation name"))
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "invalid conversation name\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			jsonBytes, _ := json.Marshal(tt.input)
+			req := httptest.NewRequest(http.MethodPost, "/conversations", bytes.NewReader(jsonBytes))
+			req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+			w := httptest.NewRecorder()
+
+			handler.CreateConversation(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestAddConversationMember(t *testing.T) {
+	tests := []struct {
+		name           string
+		conversationID string
+		input          interface{}
+		setupMock      func(mock *service.MockService)
+		expectedCode   int
+	}{
+		{
+			name:           "success",
+			conversationID: "1",
+			input:          map[string]interface{}{"user": 3, "role": "admin"},
+			setupMock: func(mock *service.MockService) {
+				mock.On("AddConversationMember", uint64(1), uint64(1), uint64(3), "admin").Return(&models.ConversationMember{
+					ConversationID: 1,
+					UserID:         3,
+					Role:           "admin",
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:           "failure - invalid conversation id",
+			conversationID: "invalid",
+			input:          map[string]interface{}{"user": 3},
+			setupMock:      func(mock *service.MockService) {},
+			expectedCode:   http.StatusBadRequest,
+		},
+		{
+			name:           "failure - forbidden",
+			conversationID: "1",
+			input:          map[string]interface{}{"user": 3},
+			setupMock: func(mock *service.MockService) {
+				mock.On("AddConversationMember", uint64(1), uint64(1), uint64(3), "").Return(nil, httperrors.ForbiddenError("only owners and admins can manage members"))
+			},
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			jsonBytes, _ := json.Marshal(tt.input)
+			req := httptest.NewRequest(http.MethodPost, "/conversations/"+tt.conversationID+"/members", bytes.NewReader(jsonBytes))
+			req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+			req.SetPathValue("id", tt.conversationID)
+			w := httptest.NewRecorder()
+
+			handler.AddConversationMember(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRemoveConversationMember(t *testing.T) {
+	mockService := new(service.MockService)
+	mockService.On("RemoveConversationMember", uint64(1), uint64(1), uint64(3)).Return(nil)
+	handler := NewHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodDelete, "/conversations/1/members/3", nil)
+	req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+	req.SetPathValue("id", "1")
+	req.SetPathValue("user", "3")
+	w := httptest.NewRecorder()
+
+	handler.RemoveConversationMember(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestLeaveConversation(t *testing.T) {
+	mockService := new(service.MockService)
+	mockService.On("LeaveConversation", uint64(1), uint64(1)).Return(httperrors.ForbiddenError("you are not a member of this conversation"))
+	handler := NewHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodPost, "/conversations/1/leave", nil)
+	req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	handler.LeaveConversation(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "you are not a member of this conversation\n", w.Body.String())
+}
+
+func TestSendConversationMessage(t *testing.T) {
+	mockService := new(service.MockService)
+	mockService.On("SendConversationMessage", uint64(1), uint64(1), &models.Content{Type: "text", Text: "Hello"}).Return(&models.Message{
+		Id:        7,
+		Timestamp: "2006-01-02 15:04:05",
+	}, nil)
+	handler := NewHandler(mockService)
+
+	jsonBytes, _ := json.Marshal(map[string]interface{}{
+		"content": map[string]interface{}{"type": "text", "text": "Hello"},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/conversations/1/messages", bytes.NewReader(jsonBytes))
+	req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	handler.SendConversationMessage(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"id":7,"timestamp":"2006-01-02 15:04:05"}`, w.Body.String())
+}
+
+func TestGetConversationMessages(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		setupMock    func(mock *service.MockService)
+		expectedCode int
+	}{
+		{
+			name:  "success",
+			query: "?start=5&limit=10",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetConversationMessages", uint64(1), uint64(1), uint64(5), uint64(10)).Return([]models.Message{{Id: 5}}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:  "success - defaults",
+			query: "",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetConversationMessages", uint64(1), uint64(1), uint64(0), uint64(100)).Return([]models.Message{}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:  "success - limit is lowered to the maximum",
+			query: "?limit=5000",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetConversationMessages", uint64(1), uint64(1), uint64(0), uint64(100)).Return([]models.Message{}, nil)
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "failure - invalid limit",
+			query:        "?limit=invalid",
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "failure - zero limit",
+			query:        "?limit=0",
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:  "failure - not a member",
+			query: "",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetConversationMessages", uint64(1), uint64(1), uint64(0), uint64(100)).Return(nil, httperrors.ForbiddenError("you are not a member of this conversation"))
+			},
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodGet, "/conversations/1/messages"+tt.query, nil)
+			req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+			req.SetPathValue("id", "1")
+			w := httptest.NewRecorder()
+
+			handler.GetConversationMessages(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/helpers"
+	"github.com/challenge/pkg/models"
+)
+
+// DataExportResponse is an export, with the link to download it right after
+// it is requested. The link is not shown again.
+type DataExportResponse struct {
+	*models.DataExport
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// RequestDataExport starts an export of the personal data of the logged user
+func (h Handler) RequestDataExport(w http.ResponseWriter, r *http.Request) {
+	export, token, err := h.Service.RequestDataExport(requestUserID(r))
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSONStatus(w, http.StatusAccepted, DataExportResponse{
+		DataExport:  export,
+		DownloadURL: fmt.Sprintf("/users/me/exports/%d/download?token=%s", export.ID, url.QueryEscape(token)),
+	})
+}
+
+// GetDataExport returns the status of an export of the logged user
+func (h Handler) GetDataExport(w http.ResponseWriter, r *http.Request) {
+	exportID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid export id", http.StatusBadRequest)
+		return
+	}
+
+	export, err := h.Service.GetDataExport(requestUserID(r), exportID)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, DataExportResponse{DataExport: export})
+}
+
+// DownloadDataExport sends the ZIP archive of a ready export. It works once.
+func (h Handler) DownloadDataExport(w http.ResponseWriter, r *http.Request) {
+	exportID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid export id", http.StatusBadRequest)
+		return
+	}
+
+	token := r.FormValue("token")
+	if token == "" {
+		http.Error(w, "Invalid token", http.StatusBadRequest)
+		return
+	}
+
+	export, content, err := h.Service.DownloadDataExport(requestUserID(r), exportID, token)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Length", strconv.FormatInt(export.Size, 10))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fmt.Sprintf("export-%d.zip", export.ID)}))
+	w.WriteHeader(http.StatusOK)
+
+	_, _ = io.Copy(w, content)
+}
@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"context"
+	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/service"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestDataExport(t *testing.T) {
+	mockService := new(service.MockService)
+	mockService.On("RequestDataExport", uint64(1)).
+		Return(&models.DataExport{ID: 3, UserID: 1, Status: models.DataExportPending, TokenHash: "hash"}, "a+b", nil).Once()
+	mockService.On("RequestDataExport", uint64(1)).
+		Return(nil, "", httperrors.BadRequestError("an export is already in progress")).Once()
+	handler := NewHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodPost, "/users/me/exports", nil)
+	req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+	w := httptest.NewRecorder()
+	handler.RequestDataExport(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, `{"id":3,"user":1,"status":"pending","size":0,"created_at":"0001-01-01T00:00:00Z","completed_at":null,"expires_at":null,"download_url":"/users/me/exports/3/download?token=a%2Bb"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	handler.RequestDataExport(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "an export is already in progress\n", w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestGetDataExport(t *testing.T) {
+	mockService := new(service.MockService)
+	mockService.On("GetDataExport", uint64(1), uint64(3)).
+		Return(&models.DataExport{ID: 3, UserID: 1, Status: models.DataExportReady, Size: 10}, nil)
+	handler := NewHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/me/exports/3", nil)
+	req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+	req.SetPathValue("id", "3")
+	w := httptest.NewRecorder()
+	handler.GetDataExport(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"ready","size":10`)
+	assert.NotContains(t, w.Body.String(), "download_url")
+	mockService.AssertExpectations(t)
+}
+
+func TestDownloadDataExport(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		setupMock    func(mock *service.MockService)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:  "success",
+			query: "?token=secret",
+			setupMock: func(mock *service.MockService) {
+				mock.On("DownloadDataExport", uint64(1), uint64(3), "secret").
+					Return(&models.DataExport{ID: 3, Size: 3}, io.NopCloser(strings.NewReader("zip")), nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: "zip",
+		},
+		{
+			name:  "already downloaded",
+			query: "?token=secret",
+			setupMock: func(mock *service.MockService) {
+				mock.On("DownloadDataExport", uint64(1), uint64(3), "secret").
+					Return(nil, nil, httperrors.NotFoundError("export is no longer available"))
+			},
+			expectedCode: http.StatusNotFound,
+			expectedBody: "export is no longer available\n",
+		},
+		{
+			name:         "missing token",
+			query:        "",
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid token\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodGet, "/users/me/exports/3/download"+tt.query, nil)
+			req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+			req.SetPathValue("id", "3")
+			w := httptest.NewRecorder()
+
+			handler.DownloadDataExport(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			if tt.expectedCode == http.StatusOK {
+				assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+				assert.Equal(t, `attachment; filename=export-3.zip`, w.Header().Get("Content-Disposition"))
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/challenge/pkg/auth"
+	"github.com/challenge/pkg/helpers"
+)
+
+// GetJWKS publishes the public keys used to verify access tokens
+func (h Handler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	helpers.RespondJSON(w, auth.PublicKeys())
+}
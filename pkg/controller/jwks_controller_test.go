@@ -0,0 +1,23 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/challenge/pkg/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetJWKS_SharedSecret(t *testing.T) {
+	handler := NewHandler(new(service.MockService))
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	w := httptest.NewRecorder()
+
+	handler.GetJWKS(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"keys":[]}`, w.Body.String())
+	assert.Equal(t, "public, max-age=300", w.Header().Get("Cache-Control"))
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"github.com/challenge/pkg/errors"
 	"github.com/challenge/pkg/helpers"
+	"github.com/challenge/pkg/models"
 	"net/http"
 )
 
@@ -12,12 +13,33 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+type LoginChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 type LoginResponse struct {
-	ID    uint64 `json:"id"`
-	Token string `json:"token"`
+	ID           uint64 `json:"id"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
 }
 
-// Login authenticates a user and returns a token
+// LoginChallengeResponse is returned by login instead of the tokens when the
+// user has two-factor authentication
+type LoginChallengeResponse struct {
+	ID                uint64 `json:"id"`
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresIn         int64  `json:"expires_in"`
+}
+
+// Login authenticates a user and returns a token, or a challenge token to
+// exchange with a two-factor code in VerifyLogin
 func (h Handler) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -30,11 +52,91 @@ func (h Handler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, token, err := h.Service.Login(req.Username, req.Password)
+	tokens, err := h.Service.Login(req.Username, req.Password, helpers.ClientIP(r))
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	if tokens.ChallengeToken != "" {
+		helpers.RespondJSON(w, LoginChallengeResponse{
+			ID:                tokens.UserID,
+			TwoFactorRequired: true,
+			ChallengeToken:    tokens.ChallengeToken,
+			ExpiresIn:         tokens.ExpiresIn,
+		})
+		return
+	}
+
+	helpers.RespondJSON(w, newLoginResponse(tokens))
+}
+
+// VerifyLogin exchanges the challenge token of a login and a TOTP or recovery
+// code for the session tokens
+func (h Handler) VerifyLogin(w http.ResponseWriter, r *http.Request) {
+	var req LoginChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.ChallengeToken == "" || req.Code == "" {
+		http.Error(w, "Invalid challenge token or code", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.Service.VerifyLoginChallenge(req.ChallengeToken, req.Code, helpers.ClientIP(r))
 	if err != nil {
 		errors.HandleError(w, err)
 		return
 	}
 
-	helpers.RespondJSON(w, LoginResponse{ID: id, Token: token})
+	helpers.RespondJSON(w, newLoginResponse(tokens))
+}
+
+// Refresh exchanges a refresh token for a new pair of tokens
+func (h Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.RefreshToken == "" {
+		http.Error(w, "Invalid refresh token", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.Service.RefreshSession(req.RefreshToken)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, newLoginResponse(tokens))
+}
+
+// Logout revokes the session of the token used in the request
+func (h Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	sessionID, _ := r.Context().Value("session_id").(string)
+	if sessionID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.Service.Logout(requestUserID(r), sessionID); err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newLoginResponse(tokens *models.AuthTokens) LoginResponse {
+	return LoginResponse{
+		ID:           tokens.UserID,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}
 }
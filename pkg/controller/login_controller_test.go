@@ -2,14 +2,18 @@ package controller
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/models"
 	"github.com/challenge/pkg/service"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLogin(t *testing.T) {
@@ -27,12 +31,16 @@ func TestLogin(t *testing.T) {
 				"password": "testpass",
 			},
 			setupMock: func(mock *service.MockService) {
-				mock.On("Login", "testuser", "testpass").Return(uint64(1), "token123", nil)
+				mock.On("Login", "testuser", "testpass", "192.0.2.1").Return(&models.AuthTokens{
+					UserID: 1, AccessToken: "token123", RefreshToken: "refresh123", ExpiresIn: 900,
+				}, nil)
 			},
 			expectedCode: http.StatusOK,
 			expectedBody: map[string]interface{}{
-				"id":    1.0,
-				"token": "token123",
+				"id":            1.0,
+				"token":         "token123",
+				"refresh_token": "refresh123",
+				"expires_in":    900.0,
 			},
 		},
 		{
@@ -72,7 +80,7 @@ func TestLogin(t *testing.T) {
 				"password": "wrongpass",
 			},
 			setupMock: func(mock *service.MockService) {
-				mock.On("Login", "testuser", "wrongpass").Return(uint64(0), "", httperrors.BadRequestError("Invalid username or password"))
+				mock.On("Login", "testuser", "wrongpass", "192.0.2.1").Return(nil, httperrors.BadRequestError("Invalid username or password"))
 			},
 			expectedCode: http.StatusBadRequest,
 			expectedBody: "Invalid username or password\n",
@@ -84,7 +92,7 @@ func TestLogin(t *testing.T) {
 				"password": "wrongpass",
 			},
 			setupMock: func(mock *service.MockService) {
-				mock.On("Login", "testuser", "wrongpass").Return(uint64(0), "", httperrors.InternalServerError("an error occurred while trying to login", errors.New("internal server error")))
+				mock.On("Login", "testuser", "wrongpass", "192.0.2.1").Return(nil, httperrors.InternalServerError("an error occurred while trying to login", errors.New("internal server error")))
 			},
 			expectedCode: http.StatusInternalServerError,
 			expectedBody: "an error occurred while trying to login: internal server error\n",
@@ -117,3 +125,174 @@ func TestLogin(t *testing.T) {
 		})
 	}
 }
+
+func TestRefresh(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		setupMock    func(mock *service.MockService)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "success",
+			body: `{"refresh_token":"refresh123"}`,
+			setupMock: func(mock *service.MockService) {
+				mock.On("RefreshSession", "refresh123").Return(&models.AuthTokens{
+					UserID: 1, AccessToken: "token456", RefreshToken: "refresh456", ExpiresIn: 900,
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"id":1,"token":"token456","refresh_token":"refresh456","expires_in":900}`,
+		},
+		{
+			name: "invalid refresh token",
+			body: `{"refresh_token":"refresh123"}`,
+			setupMock: func(mock *service.MockService) {
+				mock.On("RefreshSession", "refresh123").Return(nil, httperrors.UnauthorizedError("invalid refresh token"))
+			},
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: "invalid refresh token\n",
+		},
+		{
+			name:         "missing refresh token",
+			body:         `{}`,
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid refresh token\n",
+		},
+		{
+			name:         "invalid request body",
+			body:         `{"refresh_token":1}`,
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid request body\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodPost, "/refresh", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.Refresh(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLogout(t *testing.T) {
+	mockService := new(service.MockService)
+	mockService.On("Logout", uint64(1), "session").Return(nil)
+	handler := NewHandler(mockService)
+
+	ctx := context.WithValue(context.Background(), "user_id", uint64(1))
+	ctx = context.WithValue(ctx, "session_id", "session")
+	req := httptest.NewRequest(http.MethodPost, "/logout", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	handler.Logout(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestLogin_TooManyAttempts(t *testing.T) {
+	mockService := new(service.MockService)
+	mockService.On("Login", "testuser", "testpass", "192.0.2.1").
+		Return(nil, httperrors.TooManyRequestsError("Too many failed login attempts", 1500*time.Millisecond))
+	handler := NewHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"username":"testuser","password":"testpass"}`))
+	w := httptest.NewRecorder()
+
+	handler.Login(w, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+	assert.Equal(t, "Too many failed login attempts\n", w.Body.String())
+}
+
+func TestLogin_TwoFactorChallenge(t *testing.T) {
+	mockService := new(service.MockService)
+	mockService.On("Login", "testuser", "testpass", "192.0.2.1").
+		Return(&models.AuthTokens{UserID: 1, ChallengeToken: "challenge123", ExpiresIn: 300}, nil)
+	handler := NewHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"username":"testuser","password":"testpass"}`))
+	w := httptest.NewRecorder()
+
+	handler.Login(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"id":1,"two_factor_required":true,"challenge_token":"challenge123","expires_in":300}`, w.Body.String())
+}
+
+func TestVerifyLogin(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		setupMock    func(mock *service.MockService)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "success",
+			body: `{"challenge_token":"challenge123","code":"123456"}`,
+			setupMock: func(mock *service.MockService) {
+				mock.On("VerifyLoginChallenge", "challenge123", "123456", "192.0.2.1").Return(&models.AuthTokens{
+					UserID: 1, AccessToken: "token123", RefreshToken: "refresh123", ExpiresIn: 900,
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"id":1,"token":"token123","refresh_token":"refresh123","expires_in":900}`,
+		},
+		{
+			name: "invalid code",
+			body: `{"challenge_token":"challenge123","code":"000000"}`,
+			setupMock: func(mock *service.MockService) {
+				mock.On("VerifyLoginChallenge", "challenge123", "000000", "192.0.2.1").Return(nil, httperrors.BadRequestError("invalid two-factor code"))
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "invalid two-factor code\n",
+		},
+		{
+			name:         "missing code",
+			body:         `{"challenge_token":"challenge123"}`,
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid challenge token or code\n",
+		},
+		{
+			name:         "invalid request body",
+			body:         `{"challenge_token":1}`,
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid request body\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodPost, "/login/2fa", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.VerifyLogin(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -15,6 +15,26 @@ type MessageResponse struct {
 	Timestamp string `json:"timestamp"`
 }
 
+type EditMessageRequest struct {
+	Text string `json:"text"`
+}
+
+type MarkReadRequest struct {
+	SenderID uint64 `json:"sender"`
+	UpTo     uint64 `json:"up_to"`
+}
+
+type MarkReadResponse struct {
+	Updated int64 `json:"updated"`
+}
+
+// InboxEntryResponse is a direct conversation of the logged user with a peer
+type InboxEntryResponse struct {
+	Peer        ProfileResponse `json:"peer"`
+	LastMessage models.Message  `json:"last_message"`
+	UnreadCount int64           `json:"unread_count"`
+}
+
 // SendMessage send a message from one user to another
 func (h Handler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	requestUser := r.Context().Value("user_id")
@@ -53,7 +73,8 @@ func (h Handler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// GetMessages get the messages from the logged user to a recipient
+// GetMessages get the messages from the logged user to a recipient. Without a start
+// parameter it returns a cursor paginated page instead of a plain list.
 func (h Handler) GetMessages(w http.ResponseWriter, r *http.Request) {
 	requestUser := r.Context().Value("user_id")
 	recipientStr := r.FormValue("recipient")
@@ -71,10 +92,13 @@ func (h Handler) GetMessages(w http.ResponseWriter, r *http.Request) {
 	}
 
 	startStr := r.FormValue("start")
-	start, err := strconv.ParseUint(startStr, 10, 32)
-	if err != nil {
-		http.Error(w, "Invalid start value", http.StatusBadRequest)
-		return
+	var start uint64
+	if startStr != "" {
+		start, err = strconv.ParseUint(startStr, 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid start value", http.StatusBadRequest)
+			return
+		}
 	}
 
 	limitStr := r.FormValue("limit")
@@ -83,16 +107,29 @@ func (h Handler) GetMessages(w http.ResponseWriter, r *http.Request) {
 	}
 
 	limit, err := strconv.ParseUint(limitStr, 10, 32)
-	if err != nil {
+	if err != nil || limit == 0 {
 		http.Error(w, "Invalid limit value", http.StatusBadRequest)
 		return
 	}
+	limit = min(limit, helpers.MaxMessagesLimit)
 
 	if recipientID != requestUser {
 		http.Error(w, "You are not allowed to get messages from this user", http.StatusForbidden)
 		return
 	}
 
+	// Requests without start use cursor pagination, start is kept for existing clients
+	if startStr == "" {
+		page, err := h.Service.GetMessagesPage(recipientID, r.FormValue("cursor"), r.FormValue("direction"), limit)
+		if err != nil {
+			errors.HandleError(w, err)
+			return
+		}
+
+		helpers.RespondJSON(w, page)
+		return
+	}
+
 	messages, err := h.Service.GetMessages(recipientID, start, limit)
 	if err != nil {
 		errors.HandleError(w, err)
@@ -102,6 +139,192 @@ func (h Handler) GetMessages(w http.ResponseWriter, r *http.Request) {
 	helpers.RespondJSON(w, messages)
 }
 
+// GetMessageHistory gets the messages exchanged in both directions between the logged user and a peer
+func (h Handler) GetMessageHistory(w http.ResponseWriter, r *http.Request) {
+	peerStr := r.FormValue("peer")
+
+	peerID, err := strconv.ParseUint(peerStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid peer ID", http.StatusBadRequest)
+		return
+	}
+
+	_, err = h.Service.GetUser(peerID)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	startStr := r.FormValue("start")
+	if startStr == "" {
+		startStr = "0"
+	}
+
+	start, err := strconv.ParseUint(startStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid start value", http.StatusBadRequest)
+		return
+	}
+
+	limitStr := r.FormValue("limit")
+	if limitStr == "" {
+		limitStr = helpers.DefaultMessagesLimit
+	}
+
+	limit, err := strconv.ParseUint(limitStr, 10, 32)
+	if err != nil || limit == 0 {
+		http.Error(w, "Invalid limit value", http.StatusBadRequest)
+		return
+	}
+	limit = min(limit, helpers.MaxMessagesLimit)
+
+	messages, err := h.Service.GetHistory(requestUserID(r), peerID, start, limit)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, messages)
+}
+
+// MarkMessagesRead marks the messages received by the logged user up to an id as read
+func (h Handler) MarkMessagesRead(w http.ResponseWriter, r *http.Request) {
+	var req MarkReadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.Service.MarkMessagesRead(requestUserID(r), req.SenderID, req.UpTo)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, MarkReadResponse{Updated: updated})
+}
+
+// GetMessageStatus returns whether a message was delivered and read by its recipient
+func (h Handler) GetMessageStatus(w http.ResponseWriter, r *http.Request) {
+	messageID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid message ID", http.StatusBadRequest)
+		return
+	}
+
+	status, err := h.Service.GetMessageStatus(requestUserID(r), messageID)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, status)
+}
+
+// GetUnreadCounts returns the number of unread messages of the logged user per sender
+func (h Handler) GetUnreadCounts(w http.ResponseWriter, r *http.Request) {
+	summary, err := h.Service.GetUnreadCounts(requestUserID(r))
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, summary)
+}
+
+// GetInbox returns the direct conversations of the logged user, the most recently active first
+func (h Handler) GetInbox(w http.ResponseWriter, r *http.Request) {
+	before, err := strconv.ParseUint(r.FormValue("before"), 10, 64)
+	if err != nil && r.FormValue("before") != "" {
+		http.Error(w, "Invalid before value", http.StatusBadRequest)
+		return
+	}
+
+	limitStr := r.FormValue("limit")
+	if limitStr == "" {
+		limitStr = helpers.DefaultInboxLimit
+	}
+
+	limit, err := strconv.ParseUint(limitStr, 10, 32)
+	if err != nil || limit == 0 {
+		http.Error(w, "Invalid limit value", http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.Service.GetInbox(requestUserID(r), before, limit)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	response := make([]InboxEntryResponse, 0, len(entries))
+	for i := range entries {
+		response = append(response, InboxEntryResponse{
+			Peer:        newProfileResponse(&entries[i].Peer),
+			LastMessage: entries[i].LastMessage,
+			UnreadCount: entries[i].UnreadCount,
+		})
+	}
+
+	helpers.RespondJSON(w, response)
+}
+
+// EditMessage changes the text of a message sent by the logged user
+func (h Handler) EditMessage(w http.ResponseWriter, r *http.Request) {
+	messageID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid message ID", http.StatusBadRequest)
+		return
+	}
+
+	var req EditMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	message, err := h.Service.EditMessage(requestUserID(r), messageID, req.Text)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, message)
+}
+
+// DeleteMessage deletes a message sent by the logged user
+func (h Handler) DeleteMessage(w http.ResponseWriter, r *http.Request) {
+	messageID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid message ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.DeleteMessage(requestUserID(r), messageID); err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetMessageRevisions returns the previous versions of a message
+func (h Handler) GetMessageRevisions(w http.ResponseWriter, r *http.Request) {
+	messageID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid message ID", http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := h.Service.GetMessageRevisions(requestUserID(r), messageID)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, revisions)
+}
+
 func (h Handler) validateUserFromStr(userIDstr string) (*models.User, error) {
 	userID, err := strconv.ParseUint(userIDstr, 10, 64)
 	if err != nil {
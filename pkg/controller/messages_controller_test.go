@@ -248,6 +248,18 @@ func TestGetMessages(t *testing.T) {
 			expectedCode: http.StatusBadRequest,
 			expectedBody: "Invalid limit value\n",
 		},
+		{
+			name:        "failure - zero limit",
+			requestUser: 2,
+			recipientID: "2",
+			start:       "0",
+			limit:       "0",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetUser", uint64(2)).Return(nil, nil)
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid limit value\n",
+		},
 		{
 			name:        "success - empty limit defaults to 100",
 			requestUser: 2,
@@ -348,3 +360,571 @@ func TestGetMessages(t *testing.T) {
 		})
 	}
 }
+
+func TestGetMessageHistory(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		setupMock    func(mock *service.MockService)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:  "success",
+			query: "?peer=2&start=0&limit=10",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetUser", uint64(2)).Return(nil, nil)
+				mock.On("GetHistory", uint64(1), uint64(2), uint64(0), uint64(10)).Return([]models.Message{
+					{Id: 1, SenderID: 1, RecipientID: 2, Content: models.Content{Type: "text", Text: "Hi"}},
+					{Id: 2, SenderID: 2, RecipientID: 1, Content: models.Content{Type: "text", Text: "Hello"}},
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `[{"id":1,"sender":1,"recipient":2,"timestamp":"","content":{"type":"text","text":"Hi"}},` +
+				`{"id":2,"sender":2,"recipient":1,"timestamp":"","content":{"type":"text","text":"Hello"}}]`,
+		},
+		{
+			name:  "success - default paging",
+			query: "?peer=2",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetUser", uint64(2)).Return(nil, nil)
+				mock.On("GetHistory", uint64(1), uint64(2), uint64(0), uint64(100)).Return([]models.Message{}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `[]`,
+		},
+		{
+			name:  "success - limit is lowered to the maximum",
+			query: "?peer=2&limit=5000",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetUser", uint64(2)).Return(nil, nil)
+				mock.On("GetHistory", uint64(1), uint64(2), uint64(0), uint64(100)).Return([]models.Message{}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `[]`,
+		},
+		{
+			name:  "failure - zero limit",
+			query: "?peer=2&limit=0",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetUser", uint64(2)).Return(nil, nil)
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid limit value\n",
+		},
+		{
+			name:         "failure - invalid peer",
+			query:        "?peer=invalid",
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid peer ID\n",
+		},
+		{
+			name:  "failure - peer not found",
+			query: "?peer=2",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetUser", uint64(2)).Return(nil, httperrors.BadRequestError("user not found"))
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "user not found\n",
+		},
+		{
+			name:  "failure - invalid start",
+			query: "?peer=2&start=invalid",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetUser", uint64(2)).Return(nil, nil)
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid start value\n",
+		},
+		{
+			name:  "failure - service error",
+			query: "?peer=2",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetUser", uint64(2)).Return(nil, nil)
+				mock.On("GetHistory", uint64(1), uint64(2), uint64(0), uint64(100)).Return(nil, errors.New("service error"))
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: "Internal Server Error: service error\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodGet, "/messages/history"+tt.query, nil)
+			req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+			w := httptest.NewRecorder()
+
+			handler.GetMessageHistory(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestGetMessages_Cursor(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		setupMock    func(mock *service.MockService)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:  "success - first page",
+			query: "?recipient=2&limit=1",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetUser", uint64(2)).Return(nil, nil)
+				mock.On("GetMessagesPage", uint64(2), "", "", uint64(1)).Return(&models.MessagePage{
+					Messages:   []models.Message{{Id: 9, SenderID: 1, RecipientID: 2, Content: models.Content{Type: "text", Text: "Hello"}}},
+					NextCursor: "bXNnOjk",
+					HasMore:    true,
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"messages":[{"id":9,"sender":1,"recipient":2,"timestamp":"","content":{"type":"text","text":"Hello"}}],` +
+				`"next_cursor":"bXNnOjk","has_more":true}`,
+		},
+		{
+			name:  "success - after cursor",
+			query: "?recipient=2&cursor=bXNnOjk&direction=after",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetUser", uint64(2)).Return(nil, nil)
+				mock.On("GetMessagesPage", uint64(2), "bXNnOjk", "after", uint64(100)).Return(&models.MessagePage{
+					Messages:   []models.Message{},
+					NextCursor: "bXNnOjk",
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"messages":[],"next_cursor":"bXNnOjk","has_more":false}`,
+		},
+		{
+			name:  "failure - invalid cursor",
+			query: "?recipient=2&cursor=invalid",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetUser", uint64(2)).Return(nil, nil)
+				mock.On("GetMessagesPage", uint64(2), "invalid", "", uint64(100)).Return(nil, httperrors.BadRequestError("invalid cursor"))
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "invalid cursor\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodGet, "/messages"+tt.query, nil)
+			req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(2)))
+			w := httptest.NewRecorder()
+
+			handler.GetMessages(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestMarkMessagesRead(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        interface{}
+		setupMock    func(mock *service.MockService)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:  "success",
+			input: map[string]interface{}{"sender": 1, "up_to": 10},
+			setupMock: func(mock *service.MockService) {
+				mock.On("MarkMessagesRead", uint64(2), uint64(1), uint64(10)).Return(int64(4), nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"updated":4}`,
+		},
+		{
+			name:         "failure - invalid request body",
+			input:        "invalid",
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid request body\n",
+		},
+		{
+			name:  "failure - service error",
+			input: map[string]interface{}{},
+			setupMock: func(mock *service.MockService) {
+				mock.On("MarkMessagesRead", uint64(2), uint64(0), uint64(0)).Return(int64(0), httperrors.BadRequestError("invalid message id"))
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "invalid message id\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			jsonBytes, _ := json.Marshal(tt.input)
+			req := httptest.NewRequest(http.MethodPost, "/messages/read", bytes.NewReader(jsonBytes))
+			req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(2)))
+			w := httptest.NewRecorder()
+
+			handler.MarkMessagesRead(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestGetMessageStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		messageID    string
+		setupMock    func(mock *service.MockService)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:      "success",
+			messageID: "5",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetMessageStatus", uint64(1), uint64(5)).Return(&models.MessageStatus{
+					ID:     5,
+					Status: models.MessageStatusSent,
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"id":5,"status":"sent"}`,
+		},
+		{
+			name:         "failure - invalid message id",
+			messageID:    "invalid",
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid message ID\n",
+		},
+		{
+			name:      "failure - forbidden",
+			messageID: "5",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetMessageStatus", uint64(1), uint64(5)).Return(nil, httperrors.ForbiddenError("you are not allowed to see this message"))
+			},
+			expectedCode: http.StatusForbidden,
+			expectedBody: "you are not allowed to see this message\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodGet, "/messages/"+tt.messageID+"/status", nil)
+			req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+			req.SetPathValue("id", tt.messageID)
+			w := httptest.NewRecorder()
+
+			handler.GetMessageStatus(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestGetInbox(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		setupMock    func(mock *service.MockService)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:  "success",
+			query: "?before=9&limit=1",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetInbox", uint64(2), uint64(9), uint64(1)).Return([]models.InboxEntry{{
+					Peer:        models.User{ID: 1, Username: "peer", Role: models.RoleAgent},
+					LastMessage: models.Message{Id: 5, SenderID: 1, RecipientID: 2, Timestamp: "now", Content: models.Content{Type: "text", Text: "hi"}},
+					UnreadCount: 2,
+				}}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `[{"peer":{"id":1,"username":"peer","display_name":"","avatar_url":"","status_text":"","role":"agent","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"},` +
+				`"last_message":{"id":5,"sender":1,"recipient":2,"timestamp":"now","content":{"type":"text","text":"hi"}},"unread_count":2}]`,
+		},
+		{
+			name:  "default page",
+			query: "",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetInbox", uint64(2), uint64(0), uint64(50)).Return([]models.InboxEntry{}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `[]`,
+		},
+		{
+			name:         "invalid before",
+			query:        "?before=abc",
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid before value\n",
+		},
+		{
+			name:         "invalid limit",
+			query:        "?limit=0",
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid limit value\n",
+		},
+		{
+			name:  "failure - service error",
+			query: "",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetInbox", uint64(2), uint64(0), uint64(50)).Return(nil, errors.New("service error"))
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: "Internal Server Error: service error\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodGet, "/messages/inbox"+tt.query, nil)
+			req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(2)))
+			w := httptest.NewRecorder()
+
+			handler.GetInbox(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetUnreadCounts(t *testing.T) {
+	tests := []struct {
+		name         string
+		setupMock    func(mock *service.MockService)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "success",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetUnreadCounts", uint64(2)).Return(&models.UnreadSummary{
+					Total:   3,
+					Senders: []models.UnreadCount{{SenderID: 1, Count: 3}},
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"total":3,"senders":[{"sender":1,"count":3}]}`,
+		},
+		{
+			name: "failure - service error",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetUnreadCounts", uint64(2)).Return(nil, errors.New("service error"))
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: "Internal Server Error: service error\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodGet, "/messages/unread", nil)
+			req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(2)))
+			w := httptest.NewRecorder()
+
+			handler.GetUnreadCounts(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestEditMessage(t *testing.T) {
+	edited := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		messageID    string
+		input        interface{}
+		setupMock    func(mock *service.MockService)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:      "success",
+			messageID: "5",
+			input:     map[string]interface{}{"text": "edited"},
+			setupMock: func(mock *service.MockService) {
+				mock.On("EditMessage", uint64(1), uint64(5), "edited").Return(&models.Message{
+					Id:          5,
+					SenderID:    1,
+					RecipientID: 2,
+					Content:     models.Content{Type: "text", Text: "edited"},
+					EditedAt:    &edited,
+				}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"id":5,"sender":1,"recipient":2,"timestamp":"","content":{"type":"text","text":"edited"},"edited_at":"2025-01-01T12:00:00Z"}`,
+		},
+		{
+			name:         "failure - invalid message id",
+			messageID:    "invalid",
+			input:        map[string]interface{}{"text": "edited"},
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid message ID\n",
+		},
+		{
+			name:      "failure - not the sender",
+			messageID: "5",
+			input:     map[string]interface{}{"text": "edited"},
+			setupMock: func(mock *service.MockService) {
+				mock.On("EditMessage", uint64(1), uint64(5), "edited").Return(nil, httperrors.ForbiddenError("only the sender can change this message"))
+			},
+			expectedCode: http.StatusForbidden,
+			expectedBody: "only the sender can change this message\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			jsonBytes, _ := json.Marshal(tt.input)
+			req := httptest.NewRequest(http.MethodPatch, "/messages/"+tt.messageID, bytes.NewReader(jsonBytes))
+			req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+			req.SetPathValue("id", tt.messageID)
+			w := httptest.NewRecorder()
+
+			handler.EditMessage(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestDeleteMessage(t *testing.T) {
+	mockService := new(service.MockService)
+	mockService.On("DeleteMessage", uint64(1), uint64(5)).Return(nil)
+	handler := NewHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodDelete, "/messages/5", nil)
+	req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+	req.SetPathValue("id", "5")
+	w := httptest.NewRecorder()
+
+	handler.DeleteMessage(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestGetMessages_DeletedPlaceholder(t *testing.T) {
+	deleted := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	mockService := new(service.MockService)
+	mockService.On("GetUser", uint64(2)).Return(nil, nil)
+	mockService.On("GetMessages", uint64(2), uint64(0), uint64(100)).Return([]models.Message{
+		{
+			Id:          1,
+			SenderID:    1,
+			RecipientID: 2,
+			Content:     models.Content{Type: "text", Text: "secret"},
+			DeletedAt:   &deleted,
+		},
+	}, nil)
+	handler := NewHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/messages?recipient=2&start=0", nil)
+	req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(2)))
+	w := httptest.NewRecorder()
+
+	handler.GetMessages(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[{"id":1,"sender":1,"recipient":2,"timestamp":"","content":{"type":"deleted"},"deleted_at":"2025-01-01T12:00:00Z"}]`, w.Body.String())
+}
+
+func TestGetMessageRevisions(t *testing.T) {
+	mockService := new(service.MockService)
+	mockService.On("GetMessageRevisions", uint64(1), uint64(5)).Return([]models.MessageRevision{
+		{
+			ID:        1,
+			MessageID: 5,
+			Action:    models.RevisionActionEdit,
+			EditedBy:  1,
+			EditedAt:  time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+			Content:   models.Content{Type: "text", Text: "original"},
+		},
+	}, nil)
+	handler := NewHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/messages/5/revisions", nil)
+	req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+	req.SetPathValue("id", "5")
+	w := httptest.NewRecorder()
+
+	handler.GetMessageRevisions(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[{"id":1,"message":5,"action":"edit","edited_by":1,"edited_at":"2025-01-01T12:00:00Z","previous_content":{"type":"text","text":"original"}}]`, w.Body.String())
+}
+
+func TestGetMessages_MediaContent(t *testing.T) {
+	mockService := new(service.MockService)
+	mockService.On("GetUser", uint64(2)).Return(nil, nil)
+	mockService.On("GetMessages", uint64(2), uint64(0), uint64(100)).Return([]models.Message{
+		{
+			Id:          1,
+			SenderID:    1,
+			RecipientID: 2,
+			Content:     models.Content{Type: "image", URL: "https://example.com/a.png", Width: 640, Height: 480, MimeType: "image/png"},
+		},
+		{
+			Id:          2,
+			SenderID:    1,
+			RecipientID: 2,
+			Content:     models.Content{Type: "video", URL: "https://youtu.be/abc", Duration: 90, Source: "youtube"},
+		},
+	}, nil)
+	handler := NewHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/messages?recipient=2&start=0", nil)
+	req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(2)))
+	w := httptest.NewRecorder()
+
+	handler.GetMessages(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[{"id":1,"sender":1,"recipient":2,"timestamp":"",`+
+		`"content":{"type":"image","url":"https://example.com/a.png","width":640,"height":480,"mime_type":"image/png"}},`+
+		`{"id":2,"sender":1,"recipient":2,"timestamp":"",`+
+		`"content":{"type":"video","url":"https://youtu.be/abc","duration":90,"source":"youtube"}}]`, w.Body.String())
+}
@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/challenge/pkg/auth"
+	"github.com/challenge/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// responseTypes are all the types the controllers write as JSON. Add new ones
+// here so they are checked for secrets, TestResponseTypesAreListed fails when
+// a *Response type of this package is missing.
+var responseTypes = []interface{}{
+	UserResponse{},
+	ProfileResponse{},
+	AdminUserResponse{},
+	LoginResponse{},
+	LoginChallengeResponse{},
+	RecoveryCodesResponse{},
+	HealthResponse{},
+	MessageResponse{},
+	MarkReadResponse{},
+	InboxEntryResponse{},
+	models.Message{},
+	models.MessagePage{},
+	models.MessageStatus{},
+	models.MessageRevision{},
+	models.UnreadSummary{},
+	models.Conversation{},
+	models.ConversationMember{},
+	models.Attachment{},
+	models.TOTPEnrollment{},
+	models.AccountDeletion{},
+	models.Block{},
+	PrivacyResponse{},
+	DataExportResponse{},
+	auth.JWKS{},
+}
+
+// secretNames are JSON field names, lowercased without separators, that must
+// never be sent to clients
+var secretNames = []string{"password", "secret", "hash", "storagekey", "privatekey"}
+
+// allowedSecrets are fields meant to be sent to their owner once
+var allowedSecrets = map[string]bool{
+	"TOTPEnrollment.secret": true,
+}
+
+func TestResponseTypesHaveNoSecrets(t *testing.T) {
+	for _, response := range responseTypes {
+		typ := reflect.TypeOf(response)
+		t.Run(typ.Name(), func(t *testing.T) {
+			for _, field := range secretFields(typ, typ.Name(), map[reflect.Type]bool{}) {
+				t.Errorf("%s is marshalled", field)
+			}
+		})
+	}
+}
+
+func TestResponseTypesAreListed(t *testing.T) {
+	listed := map[string]bool{}
+	for _, response := range responseTypes {
+		listed[reflect.TypeOf(response).Name()] = true
+	}
+
+	files, err := filepath.Glob("*.go")
+	assert.NoError(t, err)
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+
+		parsed, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+		assert.NoError(t, err)
+		for name, object := range parsed.Scope.Objects {
+			if object.Kind == ast.Typ && strings.HasSuffix(name, "Response") && !listed[name] {
+				t.Errorf("%s declared in %s is not in responseTypes", name, file)
+			}
+		}
+	}
+}
+
+func TestUserIsNotMarshalledWithPassword(t *testing.T) {
+	user := models.User{ID: 1, Username: "testuser", Password: "$argon2id$hash"}
+
+	data, err := json.Marshal(user)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "argon2id")
+
+	data, err = json.Marshal(newProfileResponse(&user))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "argon2id")
+}
+
+// secretFields walks the fields encoding/json marshals from typ and returns
+// the paths of those named like a secret
+func secretFields(typ reflect.Type, path string, seen map[reflect.Type]bool) []string {
+	for typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array || typ.Kind() == reflect.Map {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || seen[typ] {
+		return nil
+	}
+	seen[typ] = true
+
+	// The persisted user is mapped to a response type, never sent as is
+	if typ == reflect.TypeOf(models.User{}) {
+		return []string{path + " (models.User)"}
+	}
+
+	var secrets []string
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		// Embedded structs without a name are flattened into their parent
+		if field.Anonymous && name == "" {
+			secrets = append(secrets, secretFields(field.Type, path, seen)...)
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		fieldPath := typ.Name() + "." + name
+		if isSecretName(name) && !allowedSecrets[fieldPath] {
+			secrets = append(secrets, path+"."+name)
+		}
+
+		secrets = append(secrets, secretFields(field.Type, path+"."+name, seen)...)
+	}
+
+	return secrets
+}
+
+func isSecretName(name string) bool {
+	name = strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(name))
+	for _, secret := range secretNames {
+		if strings.Contains(name, secret) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestSecretFields(t *testing.T) {
+	type nested struct {
+		PasswordHash string `json:"password_hash"`
+	}
+	type leaky struct {
+		Name     string    `json:"name"`
+		Password string    `json:"password"`
+		Ignored  string    `json:"-"`
+		Items    []*nested `json:"items"`
+		Secret   string
+	}
+
+	typ := reflect.TypeOf(leaky{})
+	assert.Equal(t, []string{"leaky.password", "leaky.items.password_hash", "leaky.Secret"}, secretFields(typ, typ.Name(), map[reflect.Type]bool{}))
+}
@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const sseHeartbeatPeriod = 30 * time.Second
+
+// sseReplayBatchSize is the number of missed messages loaded at a time when a
+// client reconnects, so a replay never holds the whole inbox in memory
+const sseReplayBatchSize = 100
+
+// StreamMessagesSSE pushes every message sent to the logged user as a
+// text/event-stream. Clients reconnecting with a Last-Event-ID header first
+// receive the messages they missed since that id, in batches.
+func (h Handler) StreamMessagesSSE(w http.ResponseWriter, r *http.Request) {
+	requestUser, ok := r.Context().Value("user_id").(uint64)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	var lastEventID uint64
+	replay := false
+	if lastEventStr := r.Header.Get("Last-Event-ID"); lastEventStr != "" {
+		id, err := strconv.ParseUint(lastEventStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		lastEventID = id
+		replay = true
+	}
+
+	// Subscribe before replaying so no message is lost between both steps
+	sub := h.Service.Subscribe(requestUser)
+	defer h.Service.Unsubscribe(sub)
+
+	var missed []models.Message
+	if replay {
+		var err error
+		missed, err = h.Service.GetMessagesAfter(requestUser, lastEventID, sseReplayBatchSize)
+		if err != nil {
+			errors.HandleError(w, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// Messages published during the replay are also in the subscription. They
+	// are skipped by id rather than against the last replayed id, as messages
+	// can be published in another order than their ids were assigned.
+	replayed := make(map[uint64]bool)
+	for {
+		for _, message := range missed {
+			if err := writeSSEMessage(w, message); err != nil {
+				return
+			}
+			replayed[message.Id] = true
+			lastEventID = message.Id
+		}
+		flusher.Flush()
+
+		if len(missed) < sseReplayBatchSize {
+			break
+		}
+
+		// The response has started, so errors can only end the stream and
+		// let the client reconnect from the last id it received
+		var err error
+		missed, err = h.Service.GetMessagesAfter(requestUser, lastEventID, sseReplayBatchSize)
+		if err != nil {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(sseHeartbeatPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case message, ok := <-sub.Messages:
+			if !ok {
+				return
+			}
+			if replayed[message.Id] {
+				delete(replayed, message.Id)
+				continue
+			}
+
+			if err := writeSSEMessage(w, message); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeSSEMessage(w http.ResponseWriter, message models.Message) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: message\ndata: %s\n\n", message.Id, data)
+	return err
+}
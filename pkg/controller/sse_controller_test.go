@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/challenge/pkg/hub"
+	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newSSEServer(handler Handler, userID uint64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), "user_id", userID)
+		handler.StreamMessagesSSE(w, r.WithContext(ctx))
+	}))
+}
+
+func readSSEEvent(t *testing.T, reader *bufio.Reader) []string {
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		assert.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestStreamMessagesSSE(t *testing.T) {
+	h := hub.NewHub()
+	sub := h.Subscribe(2)
+
+	mockService := new(service.MockService)
+	mockService.On("Subscribe", uint64(2)).Return(sub)
+	mockService.On("Unsubscribe", sub).Return()
+	mockService.On("GetMessagesAfter", uint64(2), uint64(4), uint64(sseReplayBatchSize)).Return([]models.Message{
+		{Id: 5, SenderID: 1, RecipientID: 2, Content: models.Content{Type: "text", Text: "missed"}},
+	}, nil)
+	server := newSSEServer(NewHandler(mockService), 2)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Last-Event-ID", "4")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	assert.Equal(t, []string{
+		"id: 5",
+		"event: message",
+		`data: {"id":5,"sender":1,"recipient":2,"timestamp":"","content":{"type":"text","text":"missed"}}`,
+	}, readSSEEvent(t, reader))
+
+	// Already replayed messages are skipped
+	h.Publish(2, models.Message{Id: 5, SenderID: 1, RecipientID: 2})
+	h.Publish(2, models.Message{Id: 6, SenderID: 1, RecipientID: 2, Content: models.Content{Type: "text", Text: "live"}})
+
+	assert.Equal(t, []string{
+		"id: 6",
+		"event: message",
+		`data: {"id":6,"sender":1,"recipient":2,"timestamp":"","content":{"type":"text","text":"live"}}`,
+	}, readSSEEvent(t, reader))
+
+	// Messages published out of order are not lost
+	h.Publish(2, models.Message{Id: 8, SenderID: 1, RecipientID: 2})
+	h.Publish(2, models.Message{Id: 7, SenderID: 3, RecipientID: 2})
+	assert.Equal(t, "id: 8", readSSEEvent(t, reader)[0])
+	assert.Equal(t, "id: 7", readSSEEvent(t, reader)[0])
+}
+
+func TestStreamMessagesSSE_ReplaysInBatches(t *testing.T) {
+	h := hub.NewHub()
+	sub := h.Subscribe(2)
+
+	batch := make([]models.Message, 0, sseReplayBatchSize)
+	for id := uint64(1); id <= sseReplayBatchSize; id++ {
+		batch = append(batch, models.Message{Id: id, SenderID: 1, RecipientID: 2})
+	}
+
+	mockService := new(service.MockService)
+	mockService.On("Subscribe", uint64(2)).Return(sub)
+	mockService.On("Unsubscribe", sub).Return()
+	mockService.On("GetMessagesAfter", uint64(2), uint64(0), uint64(sseReplayBatchSize)).Return(batch, nil).Once()
+	mockService.On("GetMessagesAfter", uint64(2), uint64(sseReplayBatchSize), uint64(sseReplayBatchSize)).
+		Return([]models.Message{{Id: sseReplayBatchSize + 1, SenderID: 1, RecipientID: 2}}, nil).Once()
+	server := newSSEServer(NewHandler(mockService), 2)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Last-Event-ID", "0")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	for id := 1; id <= sseReplayBatchSize+1; id++ {
+		assert.Equal(t, fmt.Sprintf("id: %d", id), readSSEEvent(t, reader)[0])
+	}
+	mockService.AssertNumberOfCalls(t, "GetMessagesAfter", 2)
+}
+
+func TestStreamMessagesSSE_InvalidLastEventID(t *testing.T) {
+	mockService := new(service.MockService)
+	handler := NewHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/messages/stream", nil)
+	req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(2)))
+	req.Header.Set("Last-Event-ID", "invalid")
+	w := httptest.NewRecorder()
+
+	handler.StreamMessagesSSE(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "Invalid Last-Event-ID\n", w.Body.String())
+	mockService.AssertNotCalled(t, "Subscribe", mock.Anything)
+}
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/helpers"
+)
+
+type ConfirmTOTPRequest struct {
+	Code string `json:"code"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// EnrollTOTP generates the TOTP secret of the logged user
+func (h Handler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	enrollment, err := h.Service.EnrollTOTP(requestUserID(r))
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, enrollment)
+}
+
+// ConfirmTOTP enables two-factor authentication of the logged user and returns
+// the recovery codes
+func (h Handler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	var req ConfirmTOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Code == "" {
+		http.Error(w, "Invalid code", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.Service.ConfirmTOTP(requestUserID(r), req.Code)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, RecoveryCodesResponse{RecoveryCodes: codes})
+}
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnrollTOTP(t *testing.T) {
+	mockService := new(service.MockService)
+	mockService.On("EnrollTOTP", uint64(1)).Return(&models.TOTPEnrollment{
+		Secret: "SECRET", URI: "otpauth://totp/challenge:testuser?secret=SECRET",
+	}, nil)
+	handler := NewHandler(mockService)
+
+	ctx := context.WithValue(context.Background(), "user_id", uint64(1))
+	req := httptest.NewRequest(http.MethodPost, "/users/me/2fa", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	handler.EnrollTOTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"secret":"SECRET","uri":"otpauth://totp/challenge:testuser?secret=SECRET"}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestConfirmTOTP(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		setupMock    func(mock *service.MockService)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "success",
+			body: `{"code":"123456"}`,
+			setupMock: func(mock *service.MockService) {
+				mock.On("ConfirmTOTP", uint64(1), "123456").Return([]string{"aaaa-bbbb-cccc-dddd"}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"recovery_codes":["aaaa-bbbb-cccc-dddd"]}`,
+		},
+		{
+			name: "invalid code",
+			body: `{"code":"000000"}`,
+			setupMock: func(mock *service.MockService) {
+				mock.On("ConfirmTOTP", uint64(1), "000000").Return(nil, httperrors.BadRequestError("invalid two-factor code"))
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "invalid two-factor code\n",
+		},
+		{
+			name:         "missing code",
+			body:         `{}`,
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid code\n",
+		},
+		{
+			name:         "invalid request body",
+			body:         `{"code":123456}`,
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid request body\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			ctx := context.WithValue(context.Background(), "user_id", uint64(1))
+			req := httptest.NewRequest(http.MethodPost, "/users/me/2fa/confirm", strings.NewReader(tt.body)).WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			handler.ConfirmTOTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
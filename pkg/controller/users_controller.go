@@ -3,7 +3,10 @@ package controller
 import (
 	"encoding/json"
 	"github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/models"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/challenge/pkg/helpers"
 )
@@ -12,6 +15,29 @@ type UserResponse struct {
 	ID uint64 `json:"id"`
 }
 
+// ProfileResponse is the public profile of a user
+type ProfileResponse struct {
+	ID          uint64     `json:"id"`
+	Username    string     `json:"username"`
+	DisplayName string     `json:"display_name"`
+	AvatarURL   string     `json:"avatar_url"`
+	StatusText  string     `json:"status_text"`
+	Role        string     `json:"role"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+}
+
+type UpdateProfileRequest struct {
+	DisplayName *string `json:"display_name"`
+	AvatarURL   *string `json:"avatar_url"`
+	StatusText  *string `json:"status_text"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
 type CreateUserRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
@@ -43,3 +69,137 @@ func (h Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
 
 	helpers.RespondJSON(w, UserResponse{ID: user.ID})
 }
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+// ChangePassword changes the password of the logged user and logs out every session
+func (h Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.OldPassword == "" || req.NewPassword == "" {
+		http.Error(w, "Invalid password", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.ChangePassword(requestUserID(r), req.OldPassword, req.NewPassword); err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteAccount schedules the deletion of the account of the logged user. The
+// account is disabled right away and its data erased in the background.
+func (h Handler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	var req DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Password == "" {
+		http.Error(w, "Invalid password", http.StatusBadRequest)
+		return
+	}
+
+	deletion, err := h.Service.DeleteAccount(requestUserID(r), req.Password)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSONStatus(w, http.StatusAccepted, deletion)
+}
+
+// GetUser returns the profile of a user by id
+func (h Handler) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.Service.GetUserProfile(userID)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, newProfileResponse(user))
+}
+
+// FindUser returns the profile of the user with the username in the query
+func (h Handler) FindUser(w http.ResponseWriter, r *http.Request) {
+	username := r.URL.Query().Get("username")
+	if username == "" {
+		http.Error(w, "Invalid username", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.Service.GetUserProfileByUsername(username)
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, newProfileResponse(user))
+}
+
+// GetProfile returns the profile of the logged user
+func (h Handler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	user, err := h.Service.GetUserProfile(requestUserID(r))
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, newProfileResponse(user))
+}
+
+// UpdateProfile changes the fields of the logged user profile present in the body
+func (h Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	var req UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.DisplayName == nil && req.AvatarURL == nil && req.StatusText == nil {
+		http.Error(w, "No profile fields to update", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.Service.UpdateUserProfile(requestUserID(r), models.ProfileUpdate{
+		DisplayName: req.DisplayName,
+		AvatarURL:   req.AvatarURL,
+		StatusText:  req.StatusText,
+	})
+	if err != nil {
+		errors.HandleError(w, err)
+		return
+	}
+
+	helpers.RespondJSON(w, newProfileResponse(user))
+}
+
+func newProfileResponse(user *models.User) ProfileResponse {
+	return ProfileResponse{
+		ID:          user.ID,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		AvatarURL:   user.AvatarURL,
+		StatusText:  user.StatusText,
+		Role:        user.Role,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+		DeletedAt:   user.DeletedAt,
+	}
+}
@@ -2,14 +2,18 @@ package controller
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	httperrors "github.com/challenge/pkg/errors"
 	"github.com/challenge/pkg/models"
 	"github.com/challenge/pkg/service"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestCreateUser(t *testing.T) {
@@ -110,3 +114,268 @@ func TestCreateUser(t *testing.T) {
 		})
 	}
 }
+
+func TestChangePassword(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		setupMock    func(mock *service.MockService)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "success",
+			body: `{"old_password":"Old-passphrase","new_password":"New-passphrase"}`,
+			setupMock: func(mock *service.MockService) {
+				mock.On("ChangePassword", uint64(1), "Old-passphrase", "New-passphrase").Return(nil)
+			},
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name: "rejected by service",
+			body: `{"old_password":"Old-passphrase","new_password":"short"}`,
+			setupMock: func(mock *service.MockService) {
+				mock.On("ChangePassword", uint64(1), "Old-passphrase", "short").
+					Return(httperrors.BadRequestError("password must have at least 10 characters"))
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "password must have at least 10 characters\n",
+		},
+		{
+			name:         "missing password",
+			body:         `{"old_password":"Old-passphrase"}`,
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid password\n",
+		},
+		{
+			name:         "invalid request body",
+			body:         `{`,
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid request body\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodPost, "/users/me/password", strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+			w := httptest.NewRecorder()
+
+			handler.ChangePassword(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	user := &models.User{ID: 2, Username: "peer", Password: "hash", DisplayName: "Peer", Role: models.RoleAgent, CreatedAt: createdAt, UpdatedAt: createdAt}
+
+	tests := []struct {
+		name         string
+		id           string
+		setupMock    func(mock *service.MockService)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "success",
+			id:   "2",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetUserProfile", uint64(2)).Return(user, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"id":2,"username":"peer","display_name":"Peer","avatar_url":"","status_text":"","role":"agent","created_at":"2025-01-02T03:04:05Z","updated_at":"2025-01-02T03:04:05Z"}`,
+		},
+		{
+			name: "not found",
+			id:   "3",
+			setupMock: func(mock *service.MockService) {
+				mock.On("GetUserProfile", uint64(3)).Return(nil, httperrors.NotFoundError("user not found"))
+			},
+			expectedCode: http.StatusNotFound,
+			expectedBody: "user not found\n",
+		},
+		{
+			name:         "invalid id",
+			id:           "peer",
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid user id\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodGet, "/users/"+tt.id, nil)
+			req.SetPathValue("id", tt.id)
+			w := httptest.NewRecorder()
+
+			handler.GetUser(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			assert.NotContains(t, w.Body.String(), "hash")
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFindUser(t *testing.T) {
+	mockService := new(service.MockService)
+	mockService.On("GetUserProfileByUsername", "peer").Return(&models.User{ID: 2, Username: "peer", Password: "hash"}, nil)
+	handler := NewHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/users?username=peer", nil)
+	w := httptest.NewRecorder()
+	handler.FindUser(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"username":"peer"`)
+	assert.NotContains(t, w.Body.String(), "hash")
+
+	req = httptest.NewRequest(http.MethodGet, "/users", nil)
+	w = httptest.NewRecorder()
+	handler.FindUser(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "Invalid username\n", w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestUpdateProfile(t *testing.T) {
+	statusText := "away"
+
+	tests := []struct {
+		name         string
+		body         string
+		setupMock    func(mock *service.MockService)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "success",
+			body: `{"status_text":"away"}`,
+			setupMock: func(mock *service.MockService) {
+				mock.On("UpdateUserProfile", uint64(1), models.ProfileUpdate{StatusText: &statusText}).
+					Return(&models.User{ID: 1, Username: "testuser", StatusText: "away", Role: models.RoleUser}, nil)
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `{"id":1,"username":"testuser","display_name":"","avatar_url":"","status_text":"away","role":"user","created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}`,
+		},
+		{
+			name: "validation error",
+			body: `{"avatar_url":"ftp://example.com"}`,
+			setupMock: func(mock *service.MockService) {
+				avatarURL := "ftp://example.com"
+				mock.On("UpdateUserProfile", uint64(1), models.ProfileUpdate{AvatarURL: &avatarURL}).Return(nil, httperrors.BadRequestError("invalid avatar url"))
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "invalid avatar url\n",
+		},
+		{
+			name:         "no fields",
+			body:         `{}`,
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "No profile fields to update\n",
+		},
+		{
+			name:         "invalid request body",
+			body:         `{"display_name":1}`,
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid request body\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			ctx := context.WithValue(context.Background(), "user_id", uint64(1))
+			req := httptest.NewRequest(http.MethodPatch, "/users/me", strings.NewReader(tt.body)).WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			handler.UpdateProfile(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeleteAccount(t *testing.T) {
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		body         string
+		setupMock    func(mock *service.MockService)
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "success",
+			body: `{"password":"Passphrase-1"}`,
+			setupMock: func(mock *service.MockService) {
+				mock.On("DeleteAccount", uint64(1), "Passphrase-1").Return(&models.AccountDeletion{
+					ID: 1, UserID: 1, RequestedBy: 1, MessagePolicy: models.DeletionPolicyAnonymize,
+					Status: models.AccountDeletionPending, CreatedAt: createdAt,
+				}, nil)
+			},
+			expectedCode: http.StatusAccepted,
+			expectedBody: `{"id":1,"user":1,"requested_by":1,"message_policy":"anonymize","status":"pending","messages_processed":0,"created_at":"2025-01-02T03:04:05Z","completed_at":null}`,
+		},
+		{
+			name: "wrong password",
+			body: `{"password":"Wrong-passphrase"}`,
+			setupMock: func(mock *service.MockService) {
+				mock.On("DeleteAccount", uint64(1), "Wrong-passphrase").Return(nil, httperrors.BadRequestError("invalid current password"))
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "invalid current password\n",
+		},
+		{
+			name:         "missing password",
+			body:         `{}`,
+			setupMock:    func(mock *service.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid password\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(service.MockService)
+			tt.setupMock(mockService)
+			handler := NewHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodDelete, "/users/me", strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(context.Background(), "user_id", uint64(1)))
+			w := httptest.NewRecorder()
+
+			handler.DeleteAccount(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.Equal(t, tt.expectedBody, w.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/challenge/pkg/hub"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = (wsPongWait * 9) / 10
+	wsMaxMessage = 512
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// StreamMessagesWS pushes every message sent to the logged user over a WebSocket
+func (h Handler) StreamMessagesWS(w http.ResponseWriter, r *http.Request) {
+	requestUser, ok := r.Context().Value("user_id").(uint64)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println("websocket upgrade failed:", err)
+		return
+	}
+	defer conn.Close()
+
+	sub := h.Service.Subscribe(requestUser)
+	defer h.Service.Unsubscribe(sub)
+
+	// The client is not expected to send anything, the read loop only
+	// handles control frames and detects disconnections
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn.SetReadLimit(wsMaxMessage)
+		_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case message, ok := <-sub.Messages:
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				_ = conn.WriteMessage(websocket.CloseMessage, wsCloseMessage(sub.Err()))
+				return
+			}
+
+			if err := conn.WriteJSON(message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// wsCloseMessage tells the client why the hub closed its subscription
+func wsCloseMessage(err error) []byte {
+	if errors.Is(err, hub.ErrDisconnected) {
+		return websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "account disabled")
+	}
+
+	// The hub dropped this connection because it could not keep up
+	return websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "connection too slow")
+}
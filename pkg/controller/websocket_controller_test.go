@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/challenge/pkg/hub"
+	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/service"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestStreamMessagesWS(t *testing.T) {
+	h := hub.NewHub()
+	sub := h.Subscribe(2)
+
+	mockService := new(service.MockService)
+	mockService.On("Subscribe", uint64(2)).Return(sub)
+	mockService.On("Unsubscribe", sub).Run(func(args mock.Arguments) {
+		h.Unsubscribe(sub)
+	}).Return()
+	handler := NewHandler(mockService)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), "user_id", uint64(2))
+		handler.StreamMessagesWS(w, r.WithContext(ctx))
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	h.Publish(2, models.Message{
+		Id:          1,
+		SenderID:    1,
+		RecipientID: 2,
+		Content:     models.Content{Type: "text", Text: "Hello"},
+	})
+
+	var received models.Message
+	err = conn.ReadJSON(&received)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), received.Id)
+	assert.Equal(t, "Hello", received.Content.Text)
+}
+
+func TestStreamMessagesWS_Unauthorized(t *testing.T) {
+	mockService := new(service.MockService)
+	handler := NewHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/messages/ws", nil)
+	w := httptest.NewRecorder()
+
+	handler.StreamMessagesWS(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	mockService.AssertNotCalled(t, "Subscribe", mock.Anything)
+}
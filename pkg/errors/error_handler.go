@@ -3,12 +3,16 @@ package errors
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 type ErrorResponse struct {
-	Status  int    `json:"status"`
-	Message string `json:"message"`
+	Status     int           `json:"status"`
+	Message    string        `json:"message"`
+	RetryAfter time.Duration `json:"-"`
 }
 
 func (e ErrorResponse) Error() string {
@@ -22,6 +26,20 @@ func BadRequestError(msg string) ErrorResponse {
 	}
 }
 
+func UnauthorizedError(msg string) ErrorResponse {
+	return ErrorResponse{
+		Status:  http.StatusUnauthorized,
+		Message: msg,
+	}
+}
+
+func ForbiddenError(msg string) ErrorResponse {
+	return ErrorResponse{
+		Status:  http.StatusForbidden,
+		Message: msg,
+	}
+}
+
 func NotFoundError(msg string) ErrorResponse {
 	return ErrorResponse{
 		Status:  http.StatusNotFound,
@@ -29,6 +47,21 @@ func NotFoundError(msg string) ErrorResponse {
 	}
 }
 
+func RequestEntityTooLargeError(msg string) ErrorResponse {
+	return ErrorResponse{
+		Status:  http.StatusRequestEntityTooLarge,
+		Message: msg,
+	}
+}
+
+func TooManyRequestsError(msg string, retryAfter time.Duration) ErrorResponse {
+	return ErrorResponse{
+		Status:     http.StatusTooManyRequests,
+		Message:    msg,
+		RetryAfter: retryAfter,
+	}
+}
+
 func InternalServerError(msg string, err error) ErrorResponse {
 	return ErrorResponse{
 		Status:  http.StatusInternalServerError,
@@ -39,6 +72,9 @@ func InternalServerError(msg string, err error) ErrorResponse {
 func HandleError(w http.ResponseWriter, err error) {
 	var er ErrorResponse
 	if errors.As(err, &er) {
+		if er.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(er.RetryAfter.Seconds()))))
+		}
 		http.Error(w, er.Error(), er.Status)
 	} else {
 		http.Error(w, fmt.Sprintf("Internal Server Error: %v", err), http.StatusInternalServerError)
@@ -12,3 +12,12 @@ func RespondJSON(w http.ResponseWriter, resp interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	_, _ = w.Write(retJSON)
 }
+
+// RespondJSONStatus translates an interface to json for a response with the given status code
+func RespondJSONStatus(w http.ResponseWriter, status int, resp interface{}) {
+	retJSON, _ := json.Marshal(resp)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(retJSON)
+}
@@ -2,9 +2,45 @@ package helpers
 
 const DefaultMessagesLimit = "100"
 
+// MaxMessagesLimit is the most messages returned by a single request, larger
+// limits are lowered to it
+const MaxMessagesLimit = 100
+
+// DefaultInboxLimit is the number of conversations returned per inbox page
+const DefaultInboxLimit = "50"
+
+// DefaultUsersLimit is the number of users listed per page by the admin API
+const DefaultUsersLimit = "100"
+
+// MaxAttachmentSize is the maximum size in bytes of an uploaded attachment
+const MaxAttachmentSize = 10 << 20
+
+// AttachmentVideoSource is the source of videos that reference an uploaded attachment
+const AttachmentVideoSource = "attachment"
+
+// MaxDisplayNameLength is the maximum number of characters of a display name
+const MaxDisplayNameLength = 64
+
+// MaxStatusTextLength is the maximum number of characters of a status text
+const MaxStatusTextLength = 140
+
 var (
 	MessageTypes = map[string]bool{
 		"text":  true,
 		"image": true,
 		"video": true}
+
+	ImageMimeTypes = map[string]bool{
+		"image/jpeg": true,
+		"image/png":  true,
+		"image/gif":  true,
+		"image/webp": true}
+
+	VideoMimeTypes = map[string]bool{
+		"video/mp4":  true,
+		"video/webm": true}
+
+	VideoSources = map[string]bool{
+		"youtube": true,
+		"vimeo":   true}
 )
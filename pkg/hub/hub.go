@@ -0,0 +1,123 @@
+package hub
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/challenge/pkg/models"
+)
+
+// DefaultBufferSize is the number of messages a subscription can hold
+// before it is considered too slow and dropped
+const DefaultBufferSize = 64
+
+var (
+	// ErrTooSlow closes a subscription whose buffer was full
+	ErrTooSlow = errors.New("connection too slow")
+	// ErrDisconnected closes the subscriptions of a user who lost access
+	ErrDisconnected = errors.New("user disconnected")
+)
+
+// Subscription receives the messages published to a single user
+type Subscription struct {
+	UserID   uint64
+	Messages chan models.Message
+
+	once sync.Once
+	err  error
+}
+
+// Err tells why the hub closed the subscription, it is nil if it was
+// unsubscribed. It is only set once Messages is closed.
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+func (s *Subscription) close(err error) {
+	s.once.Do(func() {
+		s.err = err
+		close(s.Messages)
+	})
+}
+
+// Hub fans out persisted messages to every open subscription of their recipient
+type Hub struct {
+	mu            sync.RWMutex
+	subscriptions map[uint64]map[*Subscription]struct{}
+	bufferSize    int
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subscriptions: make(map[uint64]map[*Subscription]struct{}),
+		bufferSize:    DefaultBufferSize,
+	}
+}
+
+// Subscribe registers a new subscription for the given user
+func (h *Hub) Subscribe(userID uint64) *Subscription {
+	sub := &Subscription{
+		UserID:   userID,
+		Messages: make(chan models.Message, h.bufferSize),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subscriptions[userID] == nil {
+		h.subscriptions[userID] = make(map[*Subscription]struct{})
+	}
+	h.subscriptions[userID][sub] = struct{}{}
+
+	return sub
+}
+
+// Unsubscribe removes the subscription and closes its channel
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(sub, nil)
+}
+
+// Disconnect closes every subscription of the user
+func (h *Hub) Disconnect(userID uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscriptions[userID] {
+		h.remove(sub, ErrDisconnected)
+	}
+}
+
+// Publish delivers the message to every subscription of the user. Subscriptions
+// whose buffer is full are dropped so a slow client cannot block the sender.
+func (h *Hub) Publish(userID uint64, message models.Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscriptions[userID] {
+		select {
+		case sub.Messages <- message:
+		default:
+			h.remove(sub, ErrTooSlow)
+		}
+	}
+}
+
+func (h *Hub) remove(sub *Subscription, err error) {
+	subs, ok := h.subscriptions[sub.UserID]
+	if !ok {
+		return
+	}
+
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subscriptions, sub.UserID)
+	}
+	sub.close(err)
+}
@@ -0,0 +1,72 @@
+package hub
+
+import (
+	"testing"
+
+	"github.com/challenge/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHub_Publish(t *testing.T) {
+	h := NewHub()
+	first := h.Subscribe(1)
+	second := h.Subscribe(1)
+	other := h.Subscribe(2)
+
+	h.Publish(1, models.Message{Id: 10, RecipientID: 1})
+
+	assert.Equal(t, uint64(10), (<-first.Messages).Id)
+	assert.Equal(t, uint64(10), (<-second.Messages).Id)
+	assert.Len(t, other.Messages, 0)
+}
+
+func TestHub_Unsubscribe(t *testing.T) {
+	h := NewHub()
+	sub := h.Subscribe(1)
+
+	h.Unsubscribe(sub)
+	h.Unsubscribe(sub)
+	h.Publish(1, models.Message{Id: 1})
+
+	_, ok := <-sub.Messages
+	assert.False(t, ok)
+	assert.NoError(t, sub.Err())
+	assert.Empty(t, h.subscriptions)
+}
+
+func TestHub_Disconnect(t *testing.T) {
+	h := NewHub()
+	first := h.Subscribe(1)
+	second := h.Subscribe(1)
+	other := h.Subscribe(2)
+
+	h.Disconnect(1)
+
+	for _, sub := range []*Subscription{first, second} {
+		_, ok := <-sub.Messages
+		assert.False(t, ok)
+		assert.Equal(t, ErrDisconnected, sub.Err())
+	}
+
+	h.Publish(2, models.Message{Id: 1})
+	assert.Len(t, other.Messages, 1)
+	assert.Len(t, h.subscriptions, 1)
+}
+
+func TestHub_PublishDropsSlowSubscription(t *testing.T) {
+	h := NewHub()
+	h.bufferSize = 1
+	slow := h.Subscribe(1)
+
+	h.Publish(1, models.Message{Id: 1})
+	h.Publish(1, models.Message{Id: 2})
+
+	message, ok := <-slow.Messages
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), message.Id)
+
+	_, ok = <-slow.Messages
+	assert.False(t, ok)
+	assert.Equal(t, ErrTooSlow, slow.Err())
+	assert.Empty(t, h.subscriptions)
+}
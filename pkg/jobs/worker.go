@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Job does one unit of work and tells whether there is more work left. Jobs
+// must keep their progress in the database so they can resume after a restart.
+type Job func() (more bool, err error)
+
+// Worker runs a job in the background every interval or as soon as it is woken
+// up, until the job has no work left
+type Worker struct {
+	name     string
+	interval time.Duration
+	wake     chan struct{}
+}
+
+func NewWorker(name string, interval time.Duration) *Worker {
+	return &Worker{
+		name:     name,
+		interval: interval,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Wake makes the worker run its job without waiting for the next interval
+func (w *Worker) Wake() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run runs the job until ctx is done. It runs it once right away to pick up
+// the work left by a previous process.
+func (w *Worker) Run(ctx context.Context, job Job) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.drain(ctx, job)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+func (w *Worker) drain(ctx context.Context, job Job) {
+	for ctx.Err() == nil {
+		more, err := job()
+		if err != nil {
+			log.Printf("Job %s failed: %v", w.name, err)
+			return
+		}
+		if !more {
+			return
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorker_RunsUntilNoWorkLeft(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var runs atomic.Int32
+	done := make(chan struct{})
+	worker := NewWorker("test", time.Hour)
+	go worker.Run(ctx, func() (bool, error) {
+		if runs.Add(1) == 3 {
+			close(done)
+			return false, nil
+		}
+		return true, nil
+	})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("job did not run")
+	}
+	assert.Equal(t, int32(3), runs.Load())
+}
+
+func TestWorker_Wake(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runs := make(chan struct{}, 10)
+	worker := NewWorker("test", time.Hour)
+	go worker.Run(ctx, func() (bool, error) {
+		runs <- struct{}{}
+		return false, errors.New("ignored")
+	})
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-runs:
+		case <-time.After(time.Second):
+			t.Fatal("job did not run")
+		}
+		worker.Wake()
+	}
+}
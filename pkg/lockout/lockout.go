@@ -0,0 +1,173 @@
+package lockout
+
+import (
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/challenge/pkg/models"
+)
+
+// sweepInterval is how often the attempts that no longer matter are dropped
+const sweepInterval = time.Minute
+
+// Store keeps the failed attempts of each key. GetLoginAttempt returns nil
+// without error when the key has no failures. DeleteStaleLoginAttempts drops
+// the attempts whose last failure is older than lastFailureBefore and that are
+// no longer blocked at now.
+type Store interface {
+	GetLoginAttempt(key string) (*models.LoginAttempt, error)
+	SaveLoginAttempt(attempt *models.LoginAttempt) error
+	DeleteLoginAttempt(key string) error
+	DeleteStaleLoginAttempts(lastFailureBefore, now time.Time) error
+}
+
+// Config sets how fast failed attempts are slowed down and locked out
+type Config struct {
+	// UserMaxFailures is the number of failures after which a username is locked out
+	UserMaxFailures int
+	// IPMaxFailures is the number of failures after which a client IP is locked out
+	IPMaxFailures int
+	// BaseDelay is the wait after the first failure, doubled on each following one
+	BaseDelay time.Duration
+	// LockoutDuration is how long a key stays locked out
+	LockoutDuration time.Duration
+	// ResetAfter forgets the failures of a key after this long without new ones
+	ResetAfter time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		UserMaxFailures: 5,
+		IPMaxFailures:   20,
+		BaseDelay:       time.Second,
+		LockoutDuration: 15 * time.Minute,
+		ResetAfter:      time.Hour,
+	}
+}
+
+// Key identifies what failed attempts are counted for
+type Key struct {
+	ID          string
+	maxFailures int
+}
+
+// Guard tracks failed login attempts and tells how long a caller must wait
+// before trying again
+type Guard struct {
+	mu        sync.Mutex
+	config    Config
+	store     Store
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewGuard(config Config, store Store) *Guard {
+	return &Guard{config: config, store: store, now: time.Now}
+}
+
+func (g *Guard) UserKey(username string) Key {
+	return Key{ID: "user:" + strings.ToLower(username), maxFailures: g.config.UserMaxFailures}
+}
+
+func (g *Guard) IPKey(ip string) Key {
+	return Key{ID: "ip:" + ip, maxFailures: g.config.IPMaxFailures}
+}
+
+// Check returns how long the caller must wait before trying again with any of the keys
+func (g *Guard) Check(keys ...Key) (time.Duration, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	var wait time.Duration
+	for _, key := range keys {
+		attempt, err := g.store.GetLoginAttempt(key.ID)
+		if err != nil {
+			return 0, err
+		}
+		if attempt != nil && attempt.BlockedUntil.Sub(now) > wait {
+			wait = attempt.BlockedUntil.Sub(now)
+		}
+	}
+
+	return wait, nil
+}
+
+// Fail records a failed attempt for every key and returns the ones that got
+// locked out by it
+func (g *Guard) Fail(keys ...Key) ([]models.LoginAttempt, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	g.sweep(now)
+
+	var locked []models.LoginAttempt
+	for _, key := range keys {
+		attempt, err := g.store.GetLoginAttempt(key.ID)
+		if err != nil {
+			return nil, err
+		}
+		if attempt == nil || now.Sub(attempt.LastFailureAt) > g.config.ResetAfter {
+			attempt = &models.LoginAttempt{Key: key.ID}
+		}
+
+		attempt.Failures++
+		attempt.LastFailureAt = now
+		if attempt.Failures >= key.maxFailures {
+			attempt.BlockedUntil = now.Add(g.config.LockoutDuration)
+			locked = append(locked, *attempt)
+		} else {
+			attempt.BlockedUntil = now.Add(g.backoff(attempt.Failures))
+		}
+
+		if err := g.store.SaveLoginAttempt(attempt); err != nil {
+			return nil, err
+		}
+	}
+
+	return locked, nil
+}
+
+// Reset forgets the failed attempts of the keys
+func (g *Guard) Reset(keys ...Key) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, key := range keys {
+		if err := g.store.DeleteLoginAttempt(key.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sweep drops the attempts that would be reset on the next failure and are no
+// longer blocked, they are the same as no attempt at all. Without it every
+// failure for a new username or IP would be kept forever. A failed sweep is
+// only logged, the failure being recorded matters more and the next sweep
+// drops the same attempts.
+func (g *Guard) sweep(now time.Time) {
+	if now.Sub(g.lastSweep) < sweepInterval {
+		return
+	}
+	g.lastSweep = now
+
+	if err := g.store.DeleteStaleLoginAttempts(now.Add(-g.config.ResetAfter), now); err != nil {
+		log.Printf("Cannot delete stale login attempts: %v", err)
+	}
+}
+
+// backoff doubles the delay on each failure, never going over a lockout
+func (g *Guard) backoff(failures int) time.Duration {
+	delay := float64(g.config.BaseDelay) * math.Pow(2, float64(failures-1))
+	if delay > float64(g.config.LockoutDuration) {
+		return g.config.LockoutDuration
+	}
+
+	return time.Duration(delay)
+}
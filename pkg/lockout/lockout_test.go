@@ -0,0 +1,133 @@
+package lockout
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestGuard() (*Guard, *time.Time) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	guard := NewGuard(Config{
+		UserMaxFailures: 3,
+		IPMaxFailures:   5,
+		BaseDelay:       time.Second,
+		LockoutDuration: time.Minute,
+		ResetAfter:      time.Hour,
+	}, NewMemoryStore())
+	guard.now = func() time.Time { return now }
+
+	return guard, &now
+}
+
+func TestGuard_Backoff(t *testing.T) {
+	guard, now := newTestGuard()
+	key := guard.UserKey("Alice")
+
+	wait, err := guard.Check(key)
+	assert.NoError(t, err)
+	assert.Zero(t, wait)
+
+	expected := []time.Duration{time.Second, 2 * time.Second}
+	for _, delay := range expected {
+		locked, err := guard.Fail(key)
+		assert.NoError(t, err)
+		assert.Empty(t, locked)
+
+		wait, err = guard.Check(key)
+		assert.NoError(t, err)
+		assert.Equal(t, delay, wait)
+		*now = now.Add(delay)
+	}
+
+	locked, err := guard.Fail(key)
+	assert.NoError(t, err)
+	assert.Len(t, locked, 1)
+	assert.Equal(t, "user:alice", locked[0].Key)
+	assert.Equal(t, 3, locked[0].Failures)
+
+	wait, err = guard.Check(guard.UserKey("alice"))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, wait)
+}
+
+func TestGuard_KeysAreIndependent(t *testing.T) {
+	guard, _ := newTestGuard()
+	user, ip := guard.UserKey("alice"), guard.IPKey("10.0.0.1")
+
+	for i := 0; i < 3; i++ {
+		_, err := guard.Fail(user, ip)
+		assert.NoError(t, err)
+	}
+
+	wait, err := guard.Check(guard.UserKey("bob"), ip)
+	assert.NoError(t, err)
+	assert.Equal(t, 4*time.Second, wait)
+
+	wait, err = guard.Check(guard.UserKey("bob"), guard.IPKey("10.0.0.2"))
+	assert.NoError(t, err)
+	assert.Zero(t, wait)
+}
+
+func TestGuard_ResetAndExpiry(t *testing.T) {
+	guard, now := newTestGuard()
+	key := guard.UserKey("alice")
+
+	_, err := guard.Fail(key)
+	assert.NoError(t, err)
+	assert.NoError(t, guard.Reset(key))
+
+	wait, err := guard.Check(key)
+	assert.NoError(t, err)
+	assert.Zero(t, wait)
+
+	// Failures older than ResetAfter are forgotten
+	_, _ = guard.Fail(key)
+	_, _ = guard.Fail(key)
+	*now = now.Add(2 * time.Hour)
+	locked, err := guard.Fail(key)
+	assert.NoError(t, err)
+	assert.Empty(t, locked)
+}
+
+func TestGuard_Sweep(t *testing.T) {
+	store := NewMemoryStore()
+	guard, now := newTestGuard()
+	guard.store = store
+
+	_, err := guard.Fail(guard.IPKey("10.0.0.1"))
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = guard.Fail(guard.UserKey("alice"))
+		assert.NoError(t, err)
+	}
+	assert.Len(t, store.attempts, 2)
+
+	// Only attempts past ResetAfter and no longer blocked are dropped
+	*now = now.Add(2 * time.Hour)
+	_, err = guard.Fail(guard.UserKey("bob"))
+	assert.NoError(t, err)
+	assert.Len(t, store.attempts, 1)
+	assert.Contains(t, store.attempts, "user:bob")
+}
+
+// failingSweepStore cannot delete stale attempts
+type failingSweepStore struct {
+	*MemoryStore
+}
+
+func (s failingSweepStore) DeleteStaleLoginAttempts(lastFailureBefore, now time.Time) error {
+	return errors.New("database is closed")
+}
+
+func TestGuard_FailRecordsWhenSweepFails(t *testing.T) {
+	store := failingSweepStore{NewMemoryStore()}
+	guard, _ := newTestGuard()
+	guard.store = store
+
+	_, err := guard.Fail(guard.UserKey("alice"))
+	assert.NoError(t, err)
+	assert.Contains(t, store.attempts, "user:alice")
+}
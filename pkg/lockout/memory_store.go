@@ -0,0 +1,58 @@
+package lockout
+
+import (
+	"sync"
+	"time"
+
+	"github.com/challenge/pkg/models"
+)
+
+// MemoryStore keeps the failed attempts in memory, they are lost on restart
+type MemoryStore struct {
+	mu       sync.Mutex
+	attempts map[string]models.LoginAttempt
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{attempts: map[string]models.LoginAttempt{}}
+}
+
+func (s *MemoryStore) GetLoginAttempt(key string) (*models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+
+	return &attempt, nil
+}
+
+func (s *MemoryStore) SaveLoginAttempt(attempt *models.LoginAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attempts[attempt.Key] = *attempt
+	return nil
+}
+
+func (s *MemoryStore) DeleteLoginAttempt(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
+
+func (s *MemoryStore) DeleteStaleLoginAttempts(lastFailureBefore, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, attempt := range s.attempts {
+		if attempt.LastFailureAt.Before(lastFailureBefore) && !attempt.BlockedUntil.After(now) {
+			delete(s.attempts, key)
+		}
+	}
+	return nil
+}
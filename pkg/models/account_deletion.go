@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Policies of what happens to the messages sent by a deleted user
+const (
+	// DeletionPolicyDelete removes their messages, revisions and attachments
+	DeletionPolicyDelete = "delete"
+	// DeletionPolicyAnonymize keeps their messages but unlinks them from the account
+	DeletionPolicyAnonymize = "anonymize"
+	// DeletionPolicyRetain keeps their messages linked to the anonymized account
+	DeletionPolicyRetain = "retain"
+)
+
+const (
+	AccountDeletionPending   = "pending"
+	AccountDeletionCompleted = "completed"
+)
+
+// DeletedUserID is the sender of the messages of deleted users under the
+// anonymize policy
+const DeletedUserID = 0
+
+// AccountDeletion is a request to erase the personal data of a user. It is
+// processed in batches by a background job, so it survives restarts.
+type AccountDeletion struct {
+	ID                uint64     `json:"id"`
+	UserID            uint64     `json:"user" gorm:"uniqueIndex"`
+	RequestedBy       uint64     `json:"requested_by"`
+	MessagePolicy     string     `json:"message_policy"`
+	Status            string     `json:"status" gorm:"index"`
+	MessagesProcessed int64      `json:"messages_processed"`
+	CreatedAt         time.Time  `json:"created_at"`
+	CompletedAt       *time.Time `json:"completed_at"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Block hides the messages of a user from another one and stops them from
+// sending new ones
+type Block struct {
+	UserID    uint64    `json:"-" gorm:"primaryKey;autoIncrement:false"`
+	BlockedID uint64    `json:"user" gorm:"primaryKey;autoIncrement:false;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
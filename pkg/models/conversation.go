@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+const (
+	ConversationRoleOwner  = "owner"
+	ConversationRoleAdmin  = "admin"
+	ConversationRoleMember = "member"
+)
+
+type Conversation struct {
+	ID        uint64               `json:"id"`
+	Name      string               `json:"name"`
+	CreatedBy uint64               `json:"created_by"`
+	CreatedAt time.Time            `json:"created_at"`
+	Members   []ConversationMember `json:"members,omitempty" gorm:"foreignKey:ConversationID"`
+}
+
+type ConversationMember struct {
+	ConversationID uint64    `json:"-" gorm:"primaryKey;autoIncrement:false"`
+	UserID         uint64    `json:"user" gorm:"primaryKey;autoIncrement:false"`
+	Role           string    `json:"role"`
+	JoinedAt       time.Time `json:"joined_at" gorm:"autoCreateTime"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+const (
+	DataExportPending    = "pending"
+	DataExportReady      = "ready"
+	DataExportDownloaded = "downloaded"
+	DataExportExpired    = "expired"
+	DataExportFailed     = "failed"
+)
+
+// DataExport is a ZIP archive with the personal data of a user. It is built
+// by a background job and can be downloaded once before it expires, with a
+// token only handed out when the export is requested.
+type DataExport struct {
+	ID          uint64     `json:"id"`
+	UserID      uint64     `json:"user" gorm:"index"`
+	Status      string     `json:"status" gorm:"index"`
+	TokenHash   string     `json:"-" gorm:"uniqueIndex"`
+	StorageKey  string     `json:"-"`
+	Size        int64      `json:"size"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// LoginAttempt counts the failed logins of a username or a client IP
+type LoginAttempt struct {
+	Key           string `gorm:"primaryKey"`
+	Failures      int
+	LastFailureAt time.Time
+	BlockedUntil  time.Time
+}
+
+// LoginLockout is the audit record of a username or IP being locked out
+type LoginLockout struct {
+	ID          uint64    `json:"id"`
+	Key         string    `json:"key" gorm:"index"`
+	Username    string    `json:"username"`
+	IP          string    `json:"ip"`
+	Failures    int       `json:"failures"`
+	LockedUntil time.Time `json:"locked_until"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -1,16 +1,160 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	MessageStatusSent      = "sent"
+	MessageStatusDelivered = "delivered"
+	MessageStatusRead      = "read"
+)
+
+const (
+	ContentTypeText  = "text"
+	ContentTypeImage = "image"
+	ContentTypeVideo = "video"
+	// ContentTypeDeleted is the content type of the placeholder returned for deleted messages
+	ContentTypeDeleted = "deleted"
+)
+
+const (
+	RevisionActionEdit   = "edit"
+	RevisionActionDelete = "delete"
+)
+
 type Message struct {
-	Id          uint64  `json:"id"`
-	SenderID    uint64  `json:"sender" db:"sender_id"`
-	Sender      User    `json:"-" gorm:"foreignKey:sender_id"`
-	RecipientID uint64  `json:"recipient" db:"recipient_id"`
-	Recipient   User    `json:"-" gorm:"foreignKey:recipient_id"`
-	Timestamp   string  `json:"timestamp"`
-	Content     Content `json:"content" gorm:"embedded"`
+	Id             uint64     `json:"id"`
+	SenderID       uint64     `json:"sender" db:"sender_id" gorm:"index:idx_messages_sender,priority:1"`
+	Sender         User       `json:"-" gorm:"foreignKey:sender_id"`
+	RecipientID    uint64     `json:"recipient" db:"recipient_id" gorm:"index:idx_messages_unread,priority:1;index:idx_messages_sender,priority:2"`
+	Recipient      User       `json:"-" gorm:"foreignKey:recipient_id"`
+	ConversationID uint64     `json:"conversation,omitempty" db:"conversation_id" gorm:"index"`
+	Timestamp      string     `json:"timestamp"`
+	Content        Content    `json:"content" gorm:"embedded"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty" db:"delivered_at"`
+	ReadAt         *time.Time `json:"read_at,omitempty" db:"read_at" gorm:"index:idx_messages_unread,priority:2"`
+	EditedAt       *time.Time `json:"edited_at,omitempty" db:"edited_at"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+}
+
+// MarshalJSON replaces the content of deleted messages with a tombstone so it
+// never reaches clients. The original content stays in the database.
+func (m Message) MarshalJSON() ([]byte, error) {
+	type message Message
+	if m.DeletedAt != nil {
+		m.Content = Content{Type: ContentTypeDeleted}
+	}
+
+	return json.Marshal(message(m))
 }
 
+// MessageRevision keeps the content a message had before it was edited or deleted
+type MessageRevision struct {
+	ID        uint64    `json:"id"`
+	MessageID uint64    `json:"message" gorm:"index"`
+	Action    string    `json:"action"`
+	EditedBy  uint64    `json:"edited_by"`
+	EditedAt  time.Time `json:"edited_at"`
+	Content   Content   `json:"previous_content" gorm:"embedded;embeddedPrefix:previous_"`
+}
+
+// Status returns whether the message was only sent, delivered or read by its recipient
+func (m Message) Status() string {
+	if m.ReadAt != nil {
+		return MessageStatusRead
+	}
+	if m.DeliveredAt != nil {
+		return MessageStatusDelivered
+	}
+
+	return MessageStatusSent
+}
+
+// UnreadCount is the number of unread messages received from a single sender
+type UnreadCount struct {
+	SenderID uint64 `json:"sender"`
+	Count    int64  `json:"count"`
+}
+
+type UnreadSummary struct {
+	Total   int64         `json:"total"`
+	Senders []UnreadCount `json:"senders"`
+}
+
+type MessageStatus struct {
+	ID          uint64     `json:"id"`
+	Status      string     `json:"status"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+	ReadAt      *time.Time `json:"read_at,omitempty"`
+}
+
+const (
+	PageBefore = "before"
+	PageAfter  = "after"
+)
+
+// MessagePage is a cursor paginated list of messages
+type MessagePage struct {
+	Messages   []Message `json:"messages"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+	HasMore    bool      `json:"has_more"`
+}
+
+// InboxEntry is a direct conversation of a user with a peer, with the latest
+// message exchanged and the number of messages from the peer not read yet.
+// It holds the persisted peer, controllers map it to a response.
+type InboxEntry struct {
+	Peer        User
+	LastMessage Message
+	UnreadCount int64
+}
+
+// Content is the payload of a message. Which fields are set depends on its type:
+// text messages only have Text, images have URL, Width, Height and MimeType and
+// videos have URL, Duration (in seconds) and Source. Images and videos can
+// reference an uploaded attachment instead of an URL.
 type Content struct {
-	Type string `json:"type" db:"type"`
-	Text string `json:"text" db:"text"`
+	Type         string `json:"type" db:"type"`
+	Text         string `json:"text,omitempty" db:"text"`
+	URL          string `json:"url,omitempty" db:"url"`
+	AttachmentID uint64 `json:"attachment,omitempty" db:"attachment_id" gorm:"index"`
+	Width        uint32 `json:"width,omitempty" db:"width"`
+	Height       uint32 `json:"height,omitempty" db:"height"`
+	MimeType     string `json:"mime_type,omitempty" db:"mime_type"`
+	Duration     uint32 `json:"duration,omitempty" db:"duration"`
+	Source       string `json:"source,omitempty" db:"source"`
+}
+
+// MarshalJSON only outputs the fields that belong to the content type
+func (c Content) MarshalJSON() ([]byte, error) {
+	switch c.Type {
+	case ContentTypeImage:
+		return json.Marshal(struct {
+			Type         string `json:"type"`
+			URL          string `json:"url,omitempty"`
+			AttachmentID uint64 `json:"attachment,omitempty"`
+			Width        uint32 `json:"width"`
+			Height       uint32 `json:"height"`
+			MimeType     string `json:"mime_type"`
+		}{c.Type, c.URL, c.AttachmentID, c.Width, c.Height, c.MimeType})
+	case ContentTypeVideo:
+		return json.Marshal(struct {
+			Type         string `json:"type"`
+			URL          string `json:"url,omitempty"`
+			AttachmentID uint64 `json:"attachment,omitempty"`
+			Duration     uint32 `json:"duration"`
+			Source       string `json:"source"`
+		}{c.Type, c.URL, c.AttachmentID, c.Duration, c.Source})
+	case ContentTypeDeleted:
+		return json.Marshal(struct {
+			Type string `json:"type"`
+		}{c.Type})
+	default:
+		return json.Marshal(struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}{c.Type, c.Text})
+	}
 }
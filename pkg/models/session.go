@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// RefreshToken is a long lived token used to get new access tokens. Only its
+// hash is stored. Every token obtained by rotating the one issued at login
+// shares the same session id.
+type RefreshToken struct {
+	ID              uint64
+	UserID          uint64 `gorm:"index"`
+	SessionID       string `gorm:"index"`
+	TokenHash       string `json:"-" gorm:"uniqueIndex"`
+	AccessTokenID   string
+	AccessExpiresAt time.Time
+	ExpiresAt       time.Time
+	CreatedAt       time.Time
+	RevokedAt       *time.Time
+}
+
+// RevokedToken is an access token that must be rejected until it expires
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey"`
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+// AuthTokens are the tokens handed out on login and refresh. When the user has
+// two-factor authentication, login only hands out a ChallengeToken.
+type AuthTokens struct {
+	UserID         uint64
+	AccessToken    string
+	RefreshToken   string
+	ChallengeToken string
+	ExpiresIn      int64
+}
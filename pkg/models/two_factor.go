@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// TOTPCredential is the TOTP secret of a user. Two-factor authentication is
+// only enabled once the user confirmed it with a valid code.
+type TOTPCredential struct {
+	UserID       uint64 `gorm:"primaryKey"`
+	Secret       string `json:"-"`
+	LastUsedStep int64
+	ConfirmedAt  *time.Time
+	CreatedAt    time.Time
+}
+
+// RecoveryCode can be used once instead of a TOTP code. Only its hash is stored.
+type RecoveryCode struct {
+	ID        uint64
+	UserID    uint64 `gorm:"index"`
+	CodeHash  string `json:"-" gorm:"uniqueIndex"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// LoginChallenge is handed out when the password of a user with two-factor
+// authentication is correct, and exchanged with a code for the session tokens.
+// Only its hash is stored.
+type LoginChallenge struct {
+	ID        uint64
+	UserID    uint64
+	TokenHash string `json:"-" gorm:"uniqueIndex"`
+	Attempts  int
+	ExpiresAt time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
+
+// TOTPEnrollment is what a user needs to add the secret to an authenticator app
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
@@ -1,7 +1,38 @@
 package models
 
+import "time"
+
+// Roles of users. Agents and admins take part in support conversations and
+// admins can also manage users.
+const (
+	RoleUser  = "user"
+	RoleAgent = "agent"
+	RoleAdmin = "admin"
+)
+
+// User is the persisted user. It has no JSON representation on purpose: the
+// controllers and the data export map it to their own types, so a new column
+// is never sent to clients by accident. The password is still excluded in case
+// it ever gets encoded.
 type User struct {
-	ID       uint64 `json:"id"`
-	Username string `json:"username"`
-	Password string `json:"password"`
+	ID          uint64
+	Username    string `gorm:"index"`
+	Password    string `json:"-"`
+	DisplayName string
+	AvatarURL   string
+	StatusText  string
+	Role        string `gorm:"not null;default:user"`
+	DisabledAt  *time.Time
+	DeletedAt   *time.Time
+	// ContactsOnly only lets users the user has messaged before message them
+	ContactsOnly bool `gorm:"not null;default:false"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// ProfileUpdate holds the profile fields to change, nil fields are left as they are
+type ProfileUpdate struct {
+	DisplayName *string
+	AvatarURL   *string
+	StatusText  *string
 }
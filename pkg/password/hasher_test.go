@@ -0,0 +1,61 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fastArgon2 keeps the tests quick, the parameters do not change the format
+var fastArgon2 = Argon2id{Params: Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}}
+
+func TestHasher_Hash(t *testing.T) {
+	hasher := NewHasher(fastArgon2)
+
+	hash, err := hasher.Hash("Secure-passphrase")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	other, err := hasher.Hash("Secure-passphrase")
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, other, "salts must be random")
+}
+
+func TestHasher_Verify(t *testing.T) {
+	argon2Hash, _ := fastArgon2.Hash("Secure-passphrase")
+	stronger := Argon2id{Params: fastArgon2.Params}
+	stronger.Params.Iterations = 2
+	bcryptHash, _ := Bcrypt{Cost: bcrypt.MinCost}.Hash("Secure-passphrase")
+
+	tests := []struct {
+		name                string
+		hasher              *Hasher
+		encoded             string
+		password            string
+		expectedMatch       bool
+		expectedNeedsRehash bool
+		expectedError       error
+	}{
+		{name: "argon2id", hasher: NewHasher(fastArgon2), encoded: argon2Hash, password: "Secure-passphrase", expectedMatch: true},
+		{name: "argon2id wrong password", hasher: NewHasher(fastArgon2), encoded: argon2Hash, password: "Wrong-passphrase"},
+		{name: "argon2id outdated parameters", hasher: NewHasher(stronger), encoded: argon2Hash, password: "Secure-passphrase", expectedMatch: true, expectedNeedsRehash: true},
+		{name: "bcrypt upgraded to argon2id", hasher: NewHasher(fastArgon2), encoded: bcryptHash, password: "Secure-passphrase", expectedMatch: true, expectedNeedsRehash: true},
+		{name: "bcrypt wrong password", hasher: NewHasher(fastArgon2), encoded: bcryptHash, password: "Wrong-passphrase"},
+		{name: "bcrypt current cost", hasher: NewHasher(Bcrypt{Cost: bcrypt.MinCost}), encoded: bcryptHash, password: "Secure-passphrase", expectedMatch: true},
+		{name: "bcrypt outdated cost", hasher: NewHasher(Bcrypt{Cost: bcrypt.DefaultCost}), encoded: bcryptHash, password: "Secure-passphrase", expectedMatch: true, expectedNeedsRehash: true},
+		{name: "argon2id downgraded to bcrypt", hasher: NewHasher(Bcrypt{Cost: bcrypt.MinCost}), encoded: argon2Hash, password: "Secure-passphrase", expectedMatch: true, expectedNeedsRehash: true},
+		{name: "unknown format", hasher: NewHasher(fastArgon2), encoded: "plaintext", password: "plaintext", expectedError: ErrUnknownHash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, needsRehash, err := tt.hasher.Verify(tt.encoded, tt.password)
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedMatch, match)
+			assert.Equal(t, tt.expectedNeedsRehash, needsRehash)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+// commonPasswords holds the bundled list of passwords too common to be used, lowercased
+var commonPasswords = loadCommonPasswords(commonPasswordsFile)
+
+// Policy sets the requirements a new password has to meet
+type Policy struct {
+	// MinLength is the minimum number of characters
+	MinLength int
+	// MaxLength is the maximum number of characters
+	MaxLength int
+	// MinClasses is how many of lowercase, uppercase, digits and symbols must be present
+	MinClasses int
+	// RejectUsername rejects passwords containing the username
+	RejectUsername bool
+	// RejectCommon rejects passwords from the bundled common passwords list
+	RejectCommon bool
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		MinLength:      10,
+		MaxLength:      128,
+		MinClasses:     2,
+		RejectUsername: true,
+		RejectCommon:   true,
+	}
+}
+
+// Validate returns an error describing the first requirement the password does not meet
+func (p Policy) Validate(username, password string) error {
+	length := len([]rune(password))
+	if length < p.MinLength {
+		return fmt.Errorf("password must have at least %d characters", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return fmt.Errorf("password must have at most %d characters", p.MaxLength)
+	}
+
+	if classes := characterClasses(password); classes < p.MinClasses {
+		return fmt.Errorf("password must contain at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinClasses)
+	}
+
+	lower := strings.ToLower(password)
+	if p.RejectUsername && username != "" && strings.Contains(lower, strings.ToLower(username)) {
+		return errors.New("password must not contain the username")
+	}
+
+	if p.RejectCommon && commonPasswords[lower] {
+		return errors.New("password is too common")
+	}
+
+	return nil
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+
+	return classes
+}
+
+func loadCommonPasswords(list string) map[string]bool {
+	passwords := map[string]bool{}
+	scanner := bufio.NewScanner(strings.NewReader(list))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			passwords[strings.ToLower(line)] = true
+		}
+	}
+
+	return passwords
+}
@@ -0,0 +1,40 @@
+package password
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_Validate(t *testing.T) {
+	policy := DefaultPolicy()
+
+	tests := []struct {
+		name          string
+		username      string
+		password      string
+		expectedError error
+	}{
+		{name: "valid", username: "alice", password: "correct horse battery"},
+		{name: "valid with classes", username: "alice", password: "Tr0ub4dor&3"},
+		{name: "too short", username: "alice", password: "Sh0rt!", expectedError: errors.New("password must have at least 10 characters")},
+		{name: "too long", username: "alice", password: string(make([]byte, 129)), expectedError: errors.New("password must have at most 128 characters")},
+		{name: "single class", username: "alice", password: "onlylowercase", expectedError: errors.New("password must contain at least 2 of lowercase letters, uppercase letters, digits and symbols")},
+		{name: "contains username", username: "Alice", password: "alice-2025-pass", expectedError: errors.New("password must not contain the username")},
+		{name: "common password", username: "alice", password: "Password123", expectedError: errors.New("password is too common")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedError, policy.Validate(tt.username, tt.password))
+		})
+	}
+}
+
+func TestPolicy_Disabled(t *testing.T) {
+	policy := Policy{MinLength: 4}
+
+	assert.NoError(t, policy.Validate("alice", "alice"))
+	assert.NoError(t, policy.Validate("alice", "password"))
+}
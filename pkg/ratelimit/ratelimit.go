@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/challenge/pkg/helpers"
+)
+
+// Limit allows Requests requests per Period, refilled continuously
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Backend keeps the token buckets
+type Backend interface {
+	Take(key string, limit Limit) (Result, error)
+}
+
+// Limiter applies the configured limit of each route
+type Limiter struct {
+	backend Backend
+	limits  map[string]Limit
+}
+
+func NewLimiter(backend Backend, limits map[string]Limit) *Limiter {
+	return &Limiter{backend: backend, limits: limits}
+}
+
+// Limit wraps a handler with the limit configured for the route. Requests are
+// counted per authenticated user, so auth.ValidateUser must run before it, or
+// per client IP on anonymous routes. Routes without a limit are not wrapped.
+func (l *Limiter) Limit(route string) func(_ http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		limit, ok := l.limits[route]
+		if !ok {
+			return next
+		}
+
+		return func(w http.ResponseWriter, r *http.Request) {
+			result, err := l.backend.Take(route+":"+clientKey(r), limit)
+			if err != nil {
+				// Do not take the route down when the backend fails
+				log.Println("rate limit backend error:", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+			w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, ceilSeconds(limit.Period)))
+
+			if !result.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+	}
+}
+
+// ParseLimits parses a comma separated list of route=requests/period, e.g.
+// "send_message=60/1m,create_user=10/1h"
+func ParseLimits(config string) (map[string]Limit, error) {
+	limits := map[string]Limit{}
+	for _, entry := range strings.Split(config, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, value, ok := strings.Cut(entry, "=")
+		requestsStr, periodStr, ok2 := strings.Cut(value, "/")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("invalid rate limit %q", entry)
+		}
+
+		requests, err := strconv.Atoi(requestsStr)
+		if err != nil || requests <= 0 {
+			return nil, fmt.Errorf("invalid rate limit requests %q", entry)
+		}
+		period, err := time.ParseDuration(periodStr)
+		if err != nil || period <= 0 {
+			return nil, fmt.Errorf("invalid rate limit period %q", entry)
+		}
+
+		limits[strings.TrimSpace(route)] = Limit{Requests: requests, Period: period}
+	}
+
+	return limits, nil
+}
+
+func clientKey(r *http.Request) string {
+	if userID, ok := r.Context().Value("user_id").(uint64); ok {
+		return "user:" + strconv.FormatUint(userID, 10)
+	}
+
+	return "ip:" + helpers.ClientIP(r)
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
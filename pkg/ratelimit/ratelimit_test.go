@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBackend_Take(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	backend := NewMemoryBackend()
+	backend.now = func() time.Time { return now }
+	limit := Limit{Requests: 2, Period: time.Minute}
+
+	result, _ := backend.Take("key", limit)
+	assert.Equal(t, Result{Allowed: true, Remaining: 1, Reset: 30 * time.Second}, result)
+
+	result, _ = backend.Take("key", limit)
+	assert.Equal(t, Result{Allowed: true, Remaining: 0, Reset: time.Minute}, result)
+
+	result, _ = backend.Take("key", limit)
+	assert.Equal(t, Result{Allowed: false, Remaining: 0, Reset: time.Minute, RetryAfter: 30 * time.Second}, result)
+
+	// Other keys have their own bucket
+	result, _ = backend.Take("other", limit)
+	assert.True(t, result.Allowed)
+
+	// One token is refilled every 30 seconds
+	now = now.Add(30 * time.Second)
+	result, _ = backend.Take("key", limit)
+	assert.True(t, result.Allowed)
+	result, _ = backend.Take("key", limit)
+	assert.False(t, result.Allowed)
+}
+
+func TestMemoryBackend_Sweep(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	backend := NewMemoryBackend()
+	backend.now = func() time.Time { return now }
+
+	_, _ = backend.Take("short", Limit{Requests: 1, Period: time.Second})
+	_, _ = backend.Take("long", Limit{Requests: 1, Period: time.Hour})
+
+	now = now.Add(2 * time.Minute)
+	_, _ = backend.Take("other", Limit{Requests: 1, Period: time.Second})
+
+	assert.NotContains(t, backend.buckets, "short")
+	assert.Contains(t, backend.buckets, "long")
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits("send_message=60/1m, create_user=10/1h,")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Limit{
+		"send_message": {Requests: 60, Period: time.Minute},
+		"create_user":  {Requests: 10, Period: time.Hour},
+	}, limits)
+
+	for _, config := range []string{"send_message", "send_message=60", "send_message=0/1m", "send_message=60/soon"} {
+		_, err := ParseLimits(config)
+		assert.Error(t, err, config)
+	}
+}
+
+type failingBackend struct{}
+
+func (failingBackend) Take(string, Limit) (Result, error) {
+	return Result{}, errors.New("backend down")
+}
+
+func TestLimiter_Limit(t *testing.T) {
+	limiter := NewLimiter(NewMemoryBackend(), map[string]Limit{
+		"send_message": {Requests: 1, Period: time.Minute},
+	})
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	handler := limiter.Limit("send_message")(ok)
+
+	serve := func(userID uint64, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/messages", nil)
+		req.RemoteAddr = remoteAddr
+		if userID != 0 {
+			req = req.WithContext(context.WithValue(req.Context(), "user_id", userID))
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	w := serve(1, "10.0.0.1:1234")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "1;w=60", w.Header().Get("RateLimit-Policy"))
+
+	w = serve(1, "10.0.0.2:1234")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+	// Another user and anonymous clients are counted separately
+	assert.Equal(t, http.StatusOK, serve(2, "10.0.0.1:1234").Code)
+	assert.Equal(t, http.StatusOK, serve(0, "10.0.0.1:1234").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(0, "10.0.0.1:4321").Code)
+
+	// Routes without a limit are not wrapped
+	w = httptest.NewRecorder()
+	limiter.Limit("other")(ok)(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+
+	// A failing backend lets requests through
+	w = httptest.NewRecorder()
+	NewLimiter(failingBackend{}, limiter.limits).Limit("send_message")(ok)(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
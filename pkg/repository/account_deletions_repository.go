@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/challenge/pkg/models"
+	"gorm.io/gorm"
+)
+
+var ErrAccountDeleted = errors.New("account was already deleted")
+
+// CreateAccountDeletion marks the user as deleted, disables their account and
+// revokes their sessions right away. Their data is erased later by the
+// deletion job. It fails with ErrAccountDeleted when the user was already
+// deleted.
+func (r RepositoryImpl) CreateAccountDeletion(deletion *models.AccountDeletion) (*models.AccountDeletion, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Model(&models.User{}).
+			Where("id = ? AND deleted_at IS NULL", deletion.UserID).
+			Updates(map[string]interface{}{
+				"deleted_at":  deletion.CreatedAt,
+				"disabled_at": gorm.Expr("COALESCE(disabled_at, ?)", deletion.CreatedAt),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAccountDeleted
+		}
+
+		if err := revokeRefreshTokens(tx, deletion.CreatedAt, "user_id = ?", deletion.UserID); err != nil {
+			return err
+		}
+
+		return tx.Create(&deletion).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deletion, nil
+}
+
+func (r RepositoryImpl) GetAccountDeletion(userID uint64) (*models.AccountDeletion, error) {
+	var deletion models.AccountDeletion
+	if err := r.DB.
+		Where("user_id = ?", userID).
+		First(&deletion).Error; err != nil {
+		return nil, err
+	}
+
+	return &deletion, nil
+}
+
+// NextAccountDeletion returns the oldest deletion that is still pending. It
+// uses Find instead of First so polling an empty queue is not logged as an error.
+func (r RepositoryImpl) NextAccountDeletion() (*models.AccountDeletion, error) {
+	var deletions []models.AccountDeletion
+	if err := r.DB.
+		Where("status = ?", models.AccountDeletionPending).
+		Order("id").
+		Limit(1).
+		Find(&deletions).Error; err != nil {
+		return nil, err
+	}
+	if len(deletions) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &deletions[0], nil
+}
+
+// DeleteUserMessages applies the message policy of the deletion to up to
+// limit messages sent by the user and returns how many it changed. Changed
+// messages no longer match, so calling it until it returns 0 goes through
+// every message even if it is interrupted.
+func (r RepositoryImpl) DeleteUserMessages(deletion *models.AccountDeletion, limit uint64) (int64, error) {
+	if deletion.MessagePolicy == models.DeletionPolicyRetain {
+		return 0, nil
+	}
+
+	var count int64
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var ids []uint64
+		if err := tx.
+			Model(&models.Message{}).
+			Where("sender_id = ?", deletion.UserID).
+			Order("id").
+			Limit(int(limit)).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+
+		count = int64(len(ids))
+		if count == 0 {
+			return nil
+		}
+
+		if deletion.MessagePolicy == models.DeletionPolicyDelete {
+			if err := tx.Where("message_id IN ?", ids).Delete(&models.MessageRevision{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("id IN ?", ids).Delete(&models.Message{}).Error; err != nil {
+				return err
+			}
+		} else {
+			if err := tx.
+				Model(&models.MessageRevision{}).
+				Where("message_id IN ?", ids).
+				Update("edited_by", models.DeletedUserID).Error; err != nil {
+				return err
+			}
+			if err := tx.
+				Model(&models.Message{}).
+				Where("id IN ?", ids).
+				Update("sender_id", models.DeletedUserID).Error; err != nil {
+				return err
+			}
+		}
+
+		deletion.MessagesProcessed += count
+		return tx.
+			Model(&models.AccountDeletion{}).
+			Where("id = ?", deletion.ID).
+			Update("messages_processed", deletion.MessagesProcessed).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// GetUserAttachments returns up to limit attachments uploaded by the user
+func (r RepositoryImpl) GetUserAttachments(userID, limit uint64) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	if err := r.DB.
+		Where("owner_id = ?", userID).
+		Order("id").
+		Limit(int(limit)).
+		Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+func (r RepositoryImpl) DeleteAttachment(id uint64) error {
+	return r.DB.Delete(&models.Attachment{}, id).Error
+}
+
+// CompleteAccountDeletion erases the profile, credentials and memberships of
+// the user and marks the deletion as completed. The user row is kept so the
+// messages they exchanged still point to an account.
+func (r RepositoryImpl) CompleteAccountDeletion(deletion *models.AccountDeletion, at time.Time) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Where("id = ?", deletion.UserID).First(&user).Error; err != nil {
+			return err
+		}
+
+		if err := tx.
+			Model(&models.User{}).
+			Where("id = ?", deletion.UserID).
+			Updates(map[string]interface{}{
+				"username":      "",
+				"password":      "",
+				"display_name":  "",
+				"avatar_url":    "",
+				"status_text":   "",
+				"role":          models.RoleUser,
+				"contacts_only": false,
+			}).Error; err != nil {
+			return err
+		}
+
+		if user.Username != "" {
+			if err := tx.Where("username = ?", user.Username).Delete(&models.LoginLockout{}).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, model := range []interface{}{
+			&models.TOTPCredential{},
+			&models.RecoveryCode{},
+			&models.LoginChallenge{},
+			&models.RefreshToken{},
+			&models.ConversationMember{},
+			&models.Block{},
+		} {
+			if err := tx.Where("user_id = ?", deletion.UserID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("blocked_id = ?", deletion.UserID).Delete(&models.Block{}).Error; err != nil {
+			return err
+		}
+
+		if deletion.MessagePolicy == models.DeletionPolicyAnonymize {
+			if err := tx.
+				Model(&models.Attachment{}).
+				Where("owner_id = ?", deletion.UserID).
+				Update("owner_id", models.DeletedUserID).Error; err != nil {
+				return err
+			}
+		}
+
+		deletion.Status = models.AccountDeletionCompleted
+		deletion.CompletedAt = &at
+		return tx.
+			Model(&models.AccountDeletion{}).
+			Where("id = ?", deletion.ID).
+			Updates(map[string]interface{}{
+				"status":       deletion.Status,
+				"completed_at": at,
+			}).Error
+	})
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/challenge/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// setupDeletionTestDB creates a user with messages, a revision, an attachment,
+// a session and a conversation membership, and a peer that messages them
+func setupDeletionTestDB(t *testing.T) (*gorm.DB, *RepositoryImpl) {
+	db := SetupTestDB(t)
+	repo := &RepositoryImpl{DB: db}
+
+	assert.NoError(t, db.Create(&models.User{ID: 1, Username: "alice", Password: "hash", DisplayName: "Alice", Role: models.RoleAgent}).Error)
+	assert.NoError(t, db.Create(&models.User{ID: 2, Username: "bob", Password: "hash"}).Error)
+	for i := 1; i <= 3; i++ {
+		assert.NoError(t, db.Create(&models.Message{SenderID: 1, RecipientID: 2, Content: models.Content{Type: "text", Text: "hi"}}).Error)
+	}
+	assert.NoError(t, db.Create(&models.Message{SenderID: 2, RecipientID: 1, Content: models.Content{Type: "text", Text: "hello"}}).Error)
+	assert.NoError(t, db.Create(&models.MessageRevision{MessageID: 1, EditedBy: 1, Action: models.RevisionActionEdit}).Error)
+	assert.NoError(t, db.Create(&models.Attachment{OwnerID: 1, StorageKey: "key"}).Error)
+	assert.NoError(t, db.Create(&models.RefreshToken{UserID: 1, SessionID: "s", TokenHash: "h", AccessTokenID: "jti", AccessExpiresAt: time.Now().Add(time.Minute)}).Error)
+	assert.NoError(t, db.Create(&models.ConversationMember{ConversationID: 1, UserID: 1, Role: models.ConversationRoleMember}).Error)
+	assert.NoError(t, db.Create(&models.Block{UserID: 1, BlockedID: 2}).Error)
+	assert.NoError(t, db.Create(&models.Block{UserID: 2, BlockedID: 1}).Error)
+
+	return db, repo
+}
+
+func TestRepositoryImpl_CreateAccountDeletion(t *testing.T) {
+	db, repo := setupDeletionTestDB(t)
+	now := time.Now()
+
+	deletion, err := repo.CreateAccountDeletion(&models.AccountDeletion{
+		UserID: 1, RequestedBy: 1, MessagePolicy: models.DeletionPolicyAnonymize, Status: models.AccountDeletionPending, CreatedAt: now,
+	})
+	assert.NoError(t, err)
+	assert.NotZero(t, deletion.ID)
+
+	user, err := repo.GetUser(1)
+	assert.NoError(t, err)
+	assert.NotNil(t, user.DeletedAt)
+	assert.NotNil(t, user.DisabledAt)
+
+	_, err = repo.GetUserByUsername("alice")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	var token models.RefreshToken
+	assert.NoError(t, db.Where("user_id = ?", 1).First(&token).Error)
+	assert.NotNil(t, token.RevokedAt)
+
+	_, err = repo.CreateAccountDeletion(&models.AccountDeletion{UserID: 1, Status: models.AccountDeletionPending, CreatedAt: now})
+	assert.Equal(t, ErrAccountDeleted, err)
+
+	next, err := repo.NextAccountDeletion()
+	assert.NoError(t, err)
+	assert.Equal(t, deletion.ID, next.ID)
+}
+
+func TestRepositoryImpl_DeleteUserMessages(t *testing.T) {
+	tests := []struct {
+		name              string
+		policy            string
+		expectedProcessed int64
+		expectedSent      int64
+		expectedTotal     int64
+	}{
+		{name: "delete", policy: models.DeletionPolicyDelete, expectedProcessed: 3, expectedSent: 0, expectedTotal: 1},
+		{name: "anonymize", policy: models.DeletionPolicyAnonymize, expectedProcessed: 3, expectedSent: 0, expectedTotal: 4},
+		{name: "retain", policy: models.DeletionPolicyRetain, expectedProcessed: 0, expectedSent: 3, expectedTotal: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, repo := setupDeletionTestDB(t)
+			deletion, err := repo.CreateAccountDeletion(&models.AccountDeletion{
+				UserID: 1, MessagePolicy: tt.policy, Status: models.AccountDeletionPending, CreatedAt: time.Now(),
+			})
+			assert.NoError(t, err)
+
+			// Batches of 2 need two calls to go through the 3 messages
+			for {
+				count, err := repo.DeleteUserMessages(deletion, 2)
+				assert.NoError(t, err)
+				if count == 0 {
+					break
+				}
+			}
+
+			var sent, total int64
+			assert.NoError(t, db.Model(&models.Message{}).Where("sender_id = ?", 1).Count(&sent).Error)
+			assert.NoError(t, db.Model(&models.Message{}).Count(&total).Error)
+			assert.Equal(t, tt.expectedSent, sent)
+			assert.Equal(t, tt.expectedTotal, total)
+
+			stored, err := repo.GetAccountDeletion(1)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedProcessed, stored.MessagesProcessed)
+
+			var revisions int64
+			assert.NoError(t, db.Model(&models.MessageRevision{}).Where("edited_by = ?", 1).Count(&revisions).Error)
+			if tt.policy == models.DeletionPolicyRetain {
+				assert.Equal(t, int64(1), revisions)
+			} else {
+				assert.Zero(t, revisions)
+			}
+		})
+	}
+}
+
+func TestRepositoryImpl_CompleteAccountDeletion(t *testing.T) {
+	db, repo := setupDeletionTestDB(t)
+	now := time.Now()
+	assert.NoError(t, db.Create(&models.LoginLockout{Key: "user:alice", Username: "alice"}).Error)
+
+	deletion, err := repo.CreateAccountDeletion(&models.AccountDeletion{
+		UserID: 1, MessagePolicy: models.DeletionPolicyAnonymize, Status: models.AccountDeletionPending, CreatedAt: now,
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, repo.CompleteAccountDeletion(deletion, now))
+	assert.Equal(t, models.AccountDeletionCompleted, deletion.Status)
+
+	user, err := repo.GetUser(1)
+	assert.NoError(t, err)
+	assert.Empty(t, user.Username)
+	assert.Empty(t, user.Password)
+	assert.Empty(t, user.DisplayName)
+	assert.Equal(t, models.RoleUser, user.Role)
+
+	for _, model := range []interface{}{&models.RefreshToken{}, &models.ConversationMember{}} {
+		var count int64
+		assert.NoError(t, db.Model(model).Where("user_id = ?", 1).Count(&count).Error)
+		assert.Zero(t, count)
+	}
+
+	var lockouts, attachments, blocks int64
+	assert.NoError(t, db.Model(&models.LoginLockout{}).Count(&lockouts).Error)
+	assert.NoError(t, db.Model(&models.Attachment{}).Where("owner_id = ?", 1).Count(&attachments).Error)
+	assert.NoError(t, db.Model(&models.Block{}).Count(&blocks).Error)
+	assert.Zero(t, lockouts)
+	assert.Zero(t, attachments)
+	assert.Zero(t, blocks)
+
+	_, err = repo.NextAccountDeletion()
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestRepositoryImpl_GetUserAttachments(t *testing.T) {
+	_, repo := setupDeletionTestDB(t)
+
+	attachments, err := repo.GetUserAttachments(1, 10)
+	assert.NoError(t, err)
+	assert.Len(t, attachments, 1)
+
+	assert.NoError(t, repo.DeleteAttachment(attachments[0].ID))
+
+	attachments, err = repo.GetUserAttachments(1, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, attachments)
+}
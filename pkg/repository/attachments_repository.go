@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"github.com/challenge/pkg/models"
+)
+
+func (r RepositoryImpl) SaveAttachment(attachment *models.Attachment) (*models.Attachment, error) {
+	if err := r.DB.Create(&attachment).Error; err != nil {
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+func (r RepositoryImpl) GetAttachment(id uint64) (*models.Attachment, error) {
+	var attachment models.Attachment
+	if err := r.DB.
+		Where("id = ?", id).
+		First(&attachment).Error; err != nil {
+		return nil, err
+	}
+
+	return &attachment, nil
+}
+
+// IsAttachmentRecipient tells whether the user sent or received a message
+// referencing the attachment, directly or through a conversation. Deleted
+// messages no longer give access to their attachment.
+func (r RepositoryImpl) IsAttachmentRecipient(attachmentID, userID uint64) (bool, error) {
+	var count int64
+	if err := r.DB.
+		Model(&models.Message{}).
+		Where("attachment_id = ? AND deleted_at IS NULL", attachmentID).
+		Where(r.DB.
+			Where("sender_id = ? OR recipient_id = ?", userID, userID).
+			Or("conversation_id IN (?)", r.DB.
+				Model(&models.ConversationMember{}).
+				Select("conversation_id").
+				Where("user_id = ?", userID))).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/challenge/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryImpl_Attachments(t *testing.T) {
+	repo := &RepositoryImpl{DB: SetupTestDB(t)}
+
+	attachment, err := repo.SaveAttachment(&models.Attachment{
+		OwnerID: 1, FileName: "cat.png", MimeType: "image/png", Size: 10, SHA256: "abc", StorageKey: "key",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), attachment.ID)
+
+	saved, err := repo.GetAttachment(attachment.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "key", saved.StorageKey)
+
+	_, err = repo.SaveMessage(&models.Message{
+		SenderID: 1, RecipientID: 2, Content: models.Content{Type: "image", AttachmentID: attachment.ID},
+	})
+	assert.NoError(t, err)
+
+	conversation := createTestConversation(t, repo)
+	_, err = repo.SaveConversationMember(&models.ConversationMember{ConversationID: conversation.ID, UserID: 5, Role: models.ConversationRoleMember})
+	assert.NoError(t, err)
+	_, err = repo.SaveConversationMessage(&models.Message{
+		SenderID: 1, ConversationID: conversation.ID, Content: models.Content{Type: "image", AttachmentID: attachment.ID},
+	})
+	assert.NoError(t, err)
+
+	for userID, expected := range map[uint64]bool{1: true, 2: true, 5: true, 3: false} {
+		allowed, err := repo.IsAttachmentRecipient(attachment.ID, userID)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, allowed, "user %d", userID)
+	}
+
+	// once its messages are deleted the attachment is no longer shared
+	deletedAt := time.Now()
+	assert.NoError(t, repo.DB.Model(&models.Message{}).Where("attachment_id = ?", attachment.ID).Update("deleted_at", deletedAt).Error)
+	allowed, err := repo.IsAttachmentRecipient(attachment.ID, 2)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/challenge/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BlockUser adds a user to the block list of another one, blocking a user
+// twice keeps the first block
+func (r RepositoryImpl) BlockUser(userID, blockedID uint64, at time.Time) error {
+	return r.DB.
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.Block{UserID: userID, BlockedID: blockedID, CreatedAt: at}).Error
+}
+
+func (r RepositoryImpl) UnblockUser(userID, blockedID uint64) error {
+	return r.DB.
+		Where("user_id = ? AND blocked_id = ?", userID, blockedID).
+		Delete(&models.Block{}).Error
+}
+
+// GetBlockedUsers returns the block list of the user, newest first
+func (r RepositoryImpl) GetBlockedUsers(userID uint64) ([]models.Block, error) {
+	blocks := []models.Block{}
+	if err := r.DB.
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&blocks).Error; err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+// IsBlocked tells whether the user blocked another one
+func (r RepositoryImpl) IsBlocked(userID, blockedID uint64) (bool, error) {
+	var count int64
+	if err := r.DB.
+		Model(&models.Block{}).
+		Where("user_id = ? AND blocked_id = ?", userID, blockedID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// GetBlockerIDs returns the ids of the users who blocked the user
+func (r RepositoryImpl) GetBlockerIDs(blockedID uint64) ([]uint64, error) {
+	var ids []uint64
+	if err := r.DB.
+		Model(&models.Block{}).
+		Where("blocked_id = ?", blockedID).
+		Pluck("user_id", &ids).Error; err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// HasSentMessage tells whether a user ever sent a direct message to another one
+func (r RepositoryImpl) HasSentMessage(senderID, recipientID uint64) (bool, error) {
+	var ids []uint64
+	if err := r.DB.
+		Model(&models.Message{}).
+		Where("sender_id = ? AND recipient_id = ?", senderID, recipientID).
+		Limit(1).
+		Pluck("id", &ids).Error; err != nil {
+		return false, err
+	}
+
+	return len(ids) > 0, nil
+}
+
+func (r RepositoryImpl) UpdateUserContactsOnly(userID uint64, contactsOnly bool) error {
+	return r.DB.
+		Model(&models.User{}).
+		Where("id = ?", userID).
+		Update("contacts_only", contactsOnly).Error
+}
+
+// hideBlockedSenders leaves out the messages sent by users the recipient blocked
+func hideBlockedSenders(recipientID uint64) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("sender_id NOT IN (SELECT blocked_id FROM blocks WHERE user_id = ?)", recipientID)
+	}
+}
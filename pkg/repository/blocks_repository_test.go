@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/challenge/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryImpl_BlockUser(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := &RepositoryImpl{DB: db}
+	first := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	assert.NoError(t, repo.BlockUser(1, 2, first))
+	assert.NoError(t, repo.BlockUser(1, 2, time.Now()))
+	assert.NoError(t, repo.BlockUser(1, 3, time.Now()))
+
+	blocks, err := repo.GetBlockedUsers(1)
+	assert.NoError(t, err)
+	assert.Len(t, blocks, 2)
+	assert.Equal(t, uint64(3), blocks[0].BlockedID)
+	assert.Equal(t, uint64(2), blocks[1].BlockedID)
+	assert.True(t, first.Equal(blocks[1].CreatedAt))
+
+	blocked, err := repo.IsBlocked(1, 2)
+	assert.NoError(t, err)
+	assert.True(t, blocked)
+
+	blocked, err = repo.IsBlocked(2, 1)
+	assert.NoError(t, err)
+	assert.False(t, blocked)
+
+	assert.NoError(t, repo.UnblockUser(1, 2))
+	assert.NoError(t, repo.UnblockUser(1, 2))
+	blocked, err = repo.IsBlocked(1, 2)
+	assert.NoError(t, err)
+	assert.False(t, blocked)
+
+	blocks, err = repo.GetBlockedUsers(2)
+	assert.NoError(t, err)
+	assert.Empty(t, blocks)
+
+	assert.NoError(t, repo.BlockUser(4, 3, time.Now()))
+	blockers, err := repo.GetBlockerIDs(3)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []uint64{1, 4}, blockers)
+
+	closed := &RepositoryImpl{DB: SetupTestDBConnectionClosed(t)}
+	assert.Equal(t, errors.New("sql: database is closed"), closed.BlockUser(1, 2, time.Now()))
+	_, err = closed.IsBlocked(1, 2)
+	assert.Equal(t, errors.New("sql: database is closed"), err)
+}
+
+func TestRepositoryImpl_HideBlockedSenders(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := &RepositoryImpl{DB: db}
+	for _, sender := range []uint64{1, 3, 1, 3} {
+		_, err := repo.SaveMessage(&models.Message{
+			SenderID:    sender,
+			RecipientID: 2,
+			Timestamp:   time.Now().String(),
+			Content:     models.Content{Type: "text", Text: "test message"},
+		})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, repo.BlockUser(2, 3, time.Now()))
+	// a block by someone else does not hide anything from the recipient
+	assert.NoError(t, repo.BlockUser(1, 1, time.Now()))
+
+	messages, err := repo.GetMessagesFromUser(2, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+	for _, message := range messages {
+		assert.Equal(t, uint64(1), message.SenderID)
+	}
+
+	messages, err = repo.GetMessagesAfter(2, 1, 10)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, uint64(3), messages[0].Id)
+
+	counts, err := repo.CountUnreadMessages(2)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.UnreadCount{{SenderID: 1, Count: 2}}, counts)
+
+	messages, err = repo.GetMessagesBetweenUsers(2, 3, 0, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, messages)
+
+	// the blocked user still sees what they sent
+	messages, err = repo.GetMessagesBetweenUsers(3, 2, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+
+	conversation, err := repo.CreateConversation(&models.Conversation{
+		Name:    "team",
+		Members: []models.ConversationMember{{UserID: 2}, {UserID: 3}},
+	})
+	assert.NoError(t, err)
+	_, err = repo.SaveConversationMessage(&models.Message{SenderID: 3, ConversationID: conversation.ID})
+	assert.NoError(t, err)
+
+	messages, err = repo.GetConversationMessages(conversation.ID, 2, 0, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, messages)
+
+	messages, err = repo.GetConversationMessages(conversation.ID, 3, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+}
+
+func TestRepositoryImpl_HasSentMessage(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := &RepositoryImpl{DB: db}
+	_, err := repo.SaveMessage(&models.Message{
+		SenderID:    1,
+		RecipientID: 2,
+		Timestamp:   time.Now().String(),
+		Content:     models.Content{Type: "text", Text: "test message"},
+	})
+	assert.NoError(t, err)
+
+	sent, err := repo.HasSentMessage(1, 2)
+	assert.NoError(t, err)
+	assert.True(t, sent)
+
+	sent, err = repo.HasSentMessage(2, 1)
+	assert.NoError(t, err)
+	assert.False(t, sent)
+}
+
+func TestRepositoryImpl_UpdateUserContactsOnly(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := &RepositoryImpl{DB: db}
+	assert.NoError(t, db.Create(&models.User{ID: 1, Username: "alice", Password: "hash"}).Error)
+
+	assert.NoError(t, repo.UpdateUserContactsOnly(1, true))
+	user, err := repo.GetUser(1)
+	assert.NoError(t, err)
+	assert.True(t, user.ContactsOnly)
+
+	assert.NoError(t, repo.UpdateUserContactsOnly(1, false))
+	user, err = repo.GetUser(1)
+	assert.NoError(t, err)
+	assert.False(t, user.ContactsOnly)
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/challenge/pkg/models"
+	"gorm.io/gorm"
+)
+
+var ErrNotConversationMember = errors.New("user is not a member of the conversation")
+
+func (r RepositoryImpl) CreateConversation(conversation *models.Conversation) (*models.Conversation, error) {
+	if err := r.DB.Create(&conversation).Error; err != nil {
+		return nil, err
+	}
+
+	return conversation, nil
+}
+
+func (r RepositoryImpl) GetConversation(id uint64) (*models.Conversation, error) {
+	var conversation models.Conversation
+	if err := r.DB.
+		Preload("Members", func(db *gorm.DB) *gorm.DB {
+			return db.Order("joined_at, user_id")
+		}).
+		Where("id = ?", id).
+		First(&conversation).Error; err != nil {
+		return nil, err
+	}
+
+	return &conversation, nil
+}
+
+func (r RepositoryImpl) GetConversationMember(conversationID, userID uint64) (*models.ConversationMember, error) {
+	var member models.ConversationMember
+	if err := r.DB.
+		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		First(&member).Error; err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+func (r RepositoryImpl) SaveConversationMember(member *models.ConversationMember) (*models.ConversationMember, error) {
+	if err := r.DB.Save(&member).Error; err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+func (r RepositoryImpl) DeleteConversationMember(conversationID, userID uint64) error {
+	return r.DB.
+		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		Delete(&models.ConversationMember{}).Error
+}
+
+// SaveConversationMessage stores a message in a conversation, failing with
+// ErrNotConversationMember when the sender does not belong to it
+func (r RepositoryImpl) SaveConversationMessage(message *models.Message) (*models.Message, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkConversationMember(tx, message.ConversationID, message.SenderID); err != nil {
+			return err
+		}
+
+		return tx.Create(&message).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// GetConversationMessages returns up to limit messages of a conversation
+// starting at the given id, failing with ErrNotConversationMember when the
+// user does not belong to it
+func (r RepositoryImpl) GetConversationMessages(conversationID, userID, start, limit uint64) ([]models.Message, error) {
+	var messages []models.Message
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkConversationMember(tx, conversationID, userID); err != nil {
+			return err
+		}
+
+		return tx.
+			Scopes(hideBlockedSenders(userID)).
+			Where("conversation_id = ? AND id >= ?", conversationID, start).
+			Order("id").
+			Limit(int(limit)).
+			Find(&messages).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func checkConversationMember(tx *gorm.DB, conversationID, userID uint64) error {
+	var count int64
+	if err := tx.
+		Model(&models.ConversationMember{}).
+		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return ErrNotConversationMember
+	}
+
+	return nil
+}
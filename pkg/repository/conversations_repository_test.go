@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"errors"
+	"github.com/challenge/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func createTestConversation(t *testing.T, repo *RepositoryImpl) *models.Conversation {
+	conversation, err := repo.CreateConversation(&models.Conversation{
+		Name:      "team",
+		CreatedBy: 1,
+		Members: []models.ConversationMember{
+			{UserID: 1, Role: models.ConversationRoleOwner},
+			{UserID: 2, Role: models.ConversationRoleMember},
+		},
+	})
+	assert.NoError(t, err)
+	return conversation
+}
+
+func TestRepositoryImpl_CreateConversation(t *testing.T) {
+	repo := &RepositoryImpl{DB: SetupTestDB(t)}
+
+	conversation := createTestConversation(t, repo)
+	assert.Equal(t, uint64(1), conversation.ID)
+
+	saved, err := repo.GetConversation(conversation.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "team", saved.Name)
+	assert.Len(t, saved.Members, 2)
+	assert.Equal(t, uint64(1), saved.Members[0].UserID)
+	assert.Equal(t, models.ConversationRoleOwner, saved.Members[0].Role)
+
+	closed := &RepositoryImpl{DB: SetupTestDBConnectionClosed(t)}
+	_, err = closed.CreateConversation(&models.Conversation{Name: "team"})
+	assert.Equal(t, errors.New("sql: database is closed"), err)
+}
+
+func TestRepositoryImpl_ConversationMembers(t *testing.T) {
+	repo := &RepositoryImpl{DB: SetupTestDB(t)}
+	conversation := createTestConversation(t, repo)
+
+	_, err := repo.SaveConversationMember(&models.ConversationMember{
+		ConversationID: conversation.ID,
+		UserID:         3,
+		Role:           models.ConversationRoleAdmin,
+	})
+	assert.NoError(t, err)
+
+	member, err := repo.GetConversationMember(conversation.ID, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ConversationRoleAdmin, member.Role)
+
+	err = repo.DeleteConversationMember(conversation.ID, 3)
+	assert.NoError(t, err)
+
+	_, err = repo.GetConversationMember(conversation.ID, 3)
+	assert.Error(t, err)
+}
+
+func TestRepositoryImpl_SaveConversationMessage(t *testing.T) {
+	repo := &RepositoryImpl{DB: SetupTestDB(t)}
+	conversation := createTestConversation(t, repo)
+
+	tests := []struct {
+		name          string
+		sender        uint64
+		expectedError error
+	}{
+		{
+			name:          "success",
+			sender:        2,
+			expectedError: nil,
+		},
+		{
+			name:          "failure - sender is not a member",
+			sender:        3,
+			expectedError: ErrNotConversationMember,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, err := repo.SaveConversationMessage(&models.Message{
+				SenderID:       tt.sender,
+				ConversationID: conversation.ID,
+				Timestamp:      time.Now().String(),
+				Content:        models.Content{Type: "text", Text: "hello team"},
+			})
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, message)
+			} else {
+				assert.NoError(t, err)
+				assert.NotZero(t, message.Id)
+			}
+		})
+	}
+}
+
+func TestRepositoryImpl_GetConversationMessages(t *testing.T) {
+	repo := &RepositoryImpl{DB: SetupTestDB(t)}
+	conversation := createTestConversation(t, repo)
+	for i := 0; i < 3; i++ {
+		_, err := repo.SaveConversationMessage(&models.Message{
+			SenderID:       1,
+			ConversationID: conversation.ID,
+			Timestamp:      time.Now().String(),
+			Content:        models.Content{Type: "text", Text: "hello team"},
+		})
+		assert.NoError(t, err)
+	}
+
+	messages, err := repo.GetConversationMessages(conversation.ID, 2, 2, 10)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+	assert.Equal(t, uint64(2), messages[0].Id)
+
+	messages, err = repo.GetConversationMessages(conversation.ID, 2, 0, 1)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+
+	_, err = repo.GetConversationMessages(conversation.ID, 3, 0, 10)
+	assert.ErrorIs(t, err, ErrNotConversationMember)
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/challenge/pkg/models"
+	"gorm.io/gorm"
+)
+
+var ErrDataExportInProgress = errors.New("a data export is already in progress")
+
+// CreateDataExport saves a new export. It fails with ErrDataExportInProgress
+// when the user already has a pending one.
+func (r RepositoryImpl) CreateDataExport(export *models.DataExport) (*models.DataExport, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var pending int64
+		if err := tx.
+			Model(&models.DataExport{}).
+			Where("user_id = ? AND status = ?", export.UserID, models.DataExportPending).
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return ErrDataExportInProgress
+		}
+
+		return tx.Create(&export).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+func (r RepositoryImpl) GetDataExport(id uint64) (*models.DataExport, error) {
+	var export models.DataExport
+	if err := r.DB.
+		Where("id = ?", id).
+		First(&export).Error; err != nil {
+		return nil, err
+	}
+
+	return &export, nil
+}
+
+// NextDataExport returns the oldest pending export. It uses Find instead of
+// First so polling an empty queue is not logged as an error.
+func (r RepositoryImpl) NextDataExport() (*models.DataExport, error) {
+	var exports []models.DataExport
+	if err := r.DB.
+		Where("status = ?", models.DataExportPending).
+		Order("id").
+		Limit(1).
+		Find(&exports).Error; err != nil {
+		return nil, err
+	}
+	if len(exports) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &exports[0], nil
+}
+
+// UpdateDataExport saves the status, file and expiration of an export
+func (r RepositoryImpl) UpdateDataExport(export *models.DataExport) error {
+	return r.DB.
+		Model(&models.DataExport{}).
+		Where("id = ?", export.ID).
+		Updates(map[string]interface{}{
+			"status":       export.Status,
+			"storage_key":  export.StorageKey,
+			"size":         export.Size,
+			"completed_at": export.CompletedAt,
+			"expires_at":   export.ExpiresAt,
+		}).Error
+}
+
+// ClaimDataExport marks a ready export as downloaded. It returns false when
+// the export was already downloaded or expired, so it can only be claimed once.
+func (r RepositoryImpl) ClaimDataExport(id uint64, at time.Time) (bool, error) {
+	result := r.DB.
+		Model(&models.DataExport{}).
+		Where("id = ? AND status = ? AND expires_at > ?", id, models.DataExportReady, at).
+		Update("status", models.DataExportDownloaded)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// GetExpiredDataExports returns up to limit ready exports that expired before at
+func (r RepositoryImpl) GetExpiredDataExports(at time.Time, limit uint64) ([]models.DataExport, error) {
+	var exports []models.DataExport
+	if err := r.DB.
+		Where("status = ? AND expires_at <= ?", models.DataExportReady, at).
+		Order("id").
+		Limit(int(limit)).
+		Find(&exports).Error; err != nil {
+		return nil, err
+	}
+
+	return exports, nil
+}
+
+// StreamUserMessages calls fn with every message sent or received by the
+// user, including the ones sent to their conversations, oldest first, without loading them all in memory. fn must not use
+// the repository, the connection is busy until the stream ends.
+func (r RepositoryImpl) StreamUserMessages(userID uint64, fn func(message *models.Message) error) error {
+	rows, err := r.DB.
+		Model(&models.Message{}).
+		Where("sender_id = ? OR recipient_id = ? OR conversation_id IN (SELECT conversation_id FROM conversation_members WHERE user_id = ?)", userID, userID, userID).
+		Order("id").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var message models.Message
+		if err := r.DB.ScanRows(rows, &message); err != nil {
+			return err
+		}
+		if err := fn(&message); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/challenge/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestRepositoryImpl_CreateDataExport(t *testing.T) {
+	repo := &RepositoryImpl{DB: SetupTestDB(t)}
+
+	export, err := repo.CreateDataExport(&models.DataExport{UserID: 1, Status: models.DataExportPending, TokenHash: "a"})
+	assert.NoError(t, err)
+	assert.NotZero(t, export.ID)
+
+	_, err = repo.CreateDataExport(&models.DataExport{UserID: 1, Status: models.DataExportPending, TokenHash: "b"})
+	assert.Equal(t, ErrDataExportInProgress, err)
+
+	_, err = repo.CreateDataExport(&models.DataExport{UserID: 2, Status: models.DataExportPending, TokenHash: "c"})
+	assert.NoError(t, err)
+
+	next, err := repo.NextDataExport()
+	assert.NoError(t, err)
+	assert.Equal(t, export.ID, next.ID)
+}
+
+func TestRepositoryImpl_ClaimDataExport(t *testing.T) {
+	repo := &RepositoryImpl{DB: SetupTestDB(t)}
+	now := time.Now()
+	expiresAt := now.Add(time.Hour)
+
+	export, err := repo.CreateDataExport(&models.DataExport{UserID: 1, Status: models.DataExportPending, TokenHash: "a"})
+	assert.NoError(t, err)
+
+	claimed, err := repo.ClaimDataExport(export.ID, now)
+	assert.NoError(t, err)
+	assert.False(t, claimed, "pending exports cannot be claimed")
+
+	export.Status = models.DataExportReady
+	export.StorageKey = "key"
+	export.Size = 10
+	export.CompletedAt = &now
+	export.ExpiresAt = &expiresAt
+	assert.NoError(t, repo.UpdateDataExport(export))
+
+	_, err = repo.NextDataExport()
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	expired, err := repo.GetExpiredDataExports(expiresAt, 10)
+	assert.NoError(t, err)
+	assert.Len(t, expired, 1)
+
+	claimed, err = repo.ClaimDataExport(export.ID, expiresAt)
+	assert.NoError(t, err)
+	assert.False(t, claimed, "expired exports cannot be claimed")
+
+	claimed, err = repo.ClaimDataExport(export.ID, now)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+
+	claimed, err = repo.ClaimDataExport(export.ID, now)
+	assert.NoError(t, err)
+	assert.False(t, claimed, "exports can only be claimed once")
+
+	stored, err := repo.GetDataExport(export.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DataExportDownloaded, stored.Status)
+	assert.Equal(t, int64(10), stored.Size)
+}
+
+func TestRepositoryImpl_StreamUserMessages(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := &RepositoryImpl{DB: db}
+
+	assert.NoError(t, db.Create(&models.Message{SenderID: 1, RecipientID: 2, Content: models.Content{Type: "text", Text: "one"}}).Error)
+	assert.NoError(t, db.Create(&models.Message{SenderID: 3, RecipientID: 2, Content: models.Content{Type: "text", Text: "other"}}).Error)
+	assert.NoError(t, db.Create(&models.Message{SenderID: 2, RecipientID: 1, Content: models.Content{Type: "text", Text: "two"}}).Error)
+	assert.NoError(t, db.Create(&models.ConversationMember{ConversationID: 5, UserID: 1}).Error)
+	assert.NoError(t, db.Create(&models.Message{SenderID: 3, ConversationID: 5, Content: models.Content{Type: "text", Text: "group"}}).Error)
+	assert.NoError(t, db.Create(&models.Message{SenderID: 3, ConversationID: 6, Content: models.Content{Type: "text", Text: "other group"}}).Error)
+
+	var texts []string
+	err := repo.StreamUserMessages(1, func(message *models.Message) error {
+		texts = append(texts, message.Content.Text)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"one", "two", "group"}, texts)
+
+	stop := errors.New("stop")
+	err = repo.StreamUserMessages(1, func(message *models.Message) error {
+		return stop
+	})
+	assert.Equal(t, stop, err)
+}
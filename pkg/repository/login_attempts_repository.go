@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/challenge/pkg/models"
+	"gorm.io/gorm"
+)
+
+// GetLoginAttempt returns nil without error when the key has no failures, as
+// expected by lockout.Store
+func (r RepositoryImpl) GetLoginAttempt(key string) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := r.DB.
+		Where("key = ?", key).
+		First(&attempt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &attempt, nil
+}
+
+func (r RepositoryImpl) SaveLoginAttempt(attempt *models.LoginAttempt) error {
+	return r.DB.Save(attempt).Error
+}
+
+func (r RepositoryImpl) DeleteLoginAttempt(key string) error {
+	return r.DB.
+		Where("key = ?", key).
+		Delete(&models.LoginAttempt{}).Error
+}
+
+// DeleteStaleLoginAttempts drops the attempts that are no longer blocked and
+// whose last failure is older than lastFailureBefore
+func (r RepositoryImpl) DeleteStaleLoginAttempts(lastFailureBefore, now time.Time) error {
+	return r.DB.
+		Where("last_failure_at < ? AND blocked_until <= ?", lastFailureBefore, now).
+		Delete(&models.LoginAttempt{}).Error
+}
+
+func (r RepositoryImpl) SaveLoginLockout(lockout *models.LoginLockout) (*models.LoginLockout, error) {
+	if err := r.DB.Create(&lockout).Error; err != nil {
+		return nil, err
+	}
+
+	return lockout, nil
+}
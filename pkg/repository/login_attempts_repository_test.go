@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/challenge/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryImpl_LoginAttempts(t *testing.T) {
+	repo := &RepositoryImpl{DB: SetupTestDB(t)}
+
+	attempt, err := repo.GetLoginAttempt("user:alice")
+	assert.NoError(t, err)
+	assert.Nil(t, attempt)
+
+	err = repo.SaveLoginAttempt(&models.LoginAttempt{Key: "user:alice", Failures: 1, LastFailureAt: time.Now()})
+	assert.NoError(t, err)
+	err = repo.SaveLoginAttempt(&models.LoginAttempt{Key: "user:alice", Failures: 2, LastFailureAt: time.Now()})
+	assert.NoError(t, err)
+
+	attempt, err = repo.GetLoginAttempt("user:alice")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, attempt.Failures)
+
+	assert.NoError(t, repo.DeleteLoginAttempt("user:alice"))
+	attempt, err = repo.GetLoginAttempt("user:alice")
+	assert.NoError(t, err)
+	assert.Nil(t, attempt)
+
+	now := time.Now()
+	assert.NoError(t, repo.SaveLoginAttempt(&models.LoginAttempt{Key: "ip:stale", Failures: 1, LastFailureAt: now.Add(-2 * time.Hour), BlockedUntil: now.Add(-2 * time.Hour)}))
+	assert.NoError(t, repo.SaveLoginAttempt(&models.LoginAttempt{Key: "ip:locked", Failures: 9, LastFailureAt: now.Add(-2 * time.Hour), BlockedUntil: now.Add(time.Hour)}))
+	assert.NoError(t, repo.SaveLoginAttempt(&models.LoginAttempt{Key: "ip:recent", Failures: 1, LastFailureAt: now, BlockedUntil: now}))
+	assert.NoError(t, repo.DeleteStaleLoginAttempts(now.Add(-time.Hour), now))
+	for key, kept := range map[string]bool{"ip:stale": false, "ip:locked": true, "ip:recent": true} {
+		attempt, err := repo.GetLoginAttempt(key)
+		assert.NoError(t, err)
+		assert.Equal(t, kept, attempt != nil, key)
+	}
+
+	lockout, err := repo.SaveLoginLockout(&models.LoginLockout{Key: "user:alice", Username: "alice", IP: "10.0.0.1", Failures: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), lockout.ID)
+}
@@ -1,6 +1,13 @@
 package repository
 
-import "github.com/challenge/pkg/models"
+import (
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/challenge/pkg/models"
+	"gorm.io/gorm"
+)
 
 func (r RepositoryImpl) SaveMessage(message *models.Message) (*models.Message, error) {
 	if err := r.DB.Create(&message).Error; err != nil {
@@ -10,13 +17,266 @@ func (r RepositoryImpl) SaveMessage(message *models.Message) (*models.Message, e
 	return message, nil
 }
 
+func (r RepositoryImpl) GetMessage(id uint64) (*models.Message, error) {
+	var message models.Message
+	if err := r.DB.
+		Where("id = ?", id).
+		First(&message).Error; err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
 func (r RepositoryImpl) GetMessagesFromUser(id, start, limit uint64) ([]models.Message, error) {
 	var messages []models.Message
 	if err := r.DB.
-		Where("recipient_id = ? AND id BETWEEN ? AND ?", id, start, start+limit-1).
+		Where("recipient_id = ? AND id >= ?", id, start).
+		Scopes(hideBlockedSenders(id)).
+		Order("id").
+		Limit(int(limit)).
 		Find(&messages).Error; err != nil {
 		return nil, err
 	}
 
 	return messages, nil
 }
+
+// GetMessagesPage returns up to limit messages received by the user, walking
+// from the cursor message id in the given direction. A zero cursor starts
+// from the newest message when going backwards and from the oldest otherwise.
+// Messages are always returned oldest first, along with whether more exist.
+func (r RepositoryImpl) GetMessagesPage(id, cursor uint64, direction string, limit uint64) ([]models.Message, bool, error) {
+	query := r.DB.Where("recipient_id = ?", id).Scopes(hideBlockedSenders(id))
+	if direction == models.PageAfter {
+		query = query.Where("id > ?", cursor).Order("id")
+	} else {
+		if cursor > 0 {
+			query = query.Where("id < ?", cursor)
+		}
+		query = query.Order("id DESC")
+	}
+
+	var messages []models.Message
+	if err := query.
+		Limit(int(limit) + 1).
+		Find(&messages).Error; err != nil {
+		return nil, false, err
+	}
+
+	hasMore := uint64(len(messages)) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+
+	if direction != models.PageAfter {
+		slices.Reverse(messages)
+	}
+
+	return messages, hasMore, nil
+}
+
+// GetMessagesBetweenUsers returns the messages exchanged in both directions
+// between two users, oldest first
+func (r RepositoryImpl) GetMessagesBetweenUsers(id, peer, start, limit uint64) ([]models.Message, error) {
+	var messages []models.Message
+	if err := r.DB.
+		Where("((sender_id = ? AND recipient_id = ?) OR (sender_id = ? AND recipient_id = ?)) AND id >= ?",
+			id, peer, peer, id, start).
+		Scopes(hideBlockedSenders(id)).
+		Order("id").
+		Limit(int(limit)).
+		Find(&messages).Error; err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// GetMessagesAfter returns up to limit messages received by the user with an id
+// greater than after, ordered by id. Messages sent by others to the
+// conversations of the user are included, as they are pushed on the same stream.
+func (r RepositoryImpl) GetMessagesAfter(id, after, limit uint64) ([]models.Message, error) {
+	var messages []models.Message
+	if err := r.DB.
+		Where("(recipient_id = ? OR (sender_id <> ? AND conversation_id IN (SELECT conversation_id FROM conversation_members WHERE user_id = ?))) AND id > ?",
+			id, id, id, after).
+		Scopes(hideBlockedSenders(id)).
+		Order("id").
+		Limit(int(limit)).
+		Find(&messages).Error; err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// MarkMessagesDelivered sets the delivery time of the given messages received
+// by the user that were not delivered yet
+func (r RepositoryImpl) MarkMessagesDelivered(recipientID uint64, ids []uint64, at time.Time) error {
+	return r.DB.
+		Model(&models.Message{}).
+		Where("recipient_id = ? AND id IN ? AND delivered_at IS NULL", recipientID, ids).
+		Update("delivered_at", at).Error
+}
+
+// MarkMessagesRead sets the read time of every unread message received by the
+// user up to the given id, optionally only the ones from a single sender.
+// Messages that were not delivered yet are marked as delivered too.
+func (r RepositoryImpl) MarkMessagesRead(recipientID, senderID, upTo uint64, at time.Time) (int64, error) {
+	query := r.DB.
+		Model(&models.Message{}).
+		Where("recipient_id = ? AND id <= ? AND read_at IS NULL", recipientID, upTo)
+	if senderID != 0 {
+		query = query.Where("sender_id = ?", senderID)
+	}
+
+	result := query.Updates(map[string]interface{}{
+		"read_at":      at,
+		"delivered_at": gorm.Expr("COALESCE(delivered_at, ?)", at),
+	})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+// CountUnreadMessages returns the number of unread messages received by the
+// user grouped by sender
+func (r RepositoryImpl) CountUnreadMessages(recipientID uint64) ([]models.UnreadCount, error) {
+	var counts []models.UnreadCount
+	if err := r.DB.
+		Model(&models.Message{}).
+		Select("sender_id, COUNT(*) AS count").
+		Where("recipient_id = ? AND read_at IS NULL", recipientID).
+		Scopes(hideBlockedSenders(recipientID)).
+		Group("sender_id").
+		Order("sender_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// UpdateMessage saves the changes made to a message along with the revision
+// holding its previous content
+func (r RepositoryImpl) UpdateMessage(message *models.Message, revision *models.MessageRevision) (*models.Message, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&revision).Error; err != nil {
+			return err
+		}
+
+		return tx.Save(&message).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+func (r RepositoryImpl) GetMessageRevisions(messageID uint64) ([]models.MessageRevision, error) {
+	var revisions []models.MessageRevision
+	if err := r.DB.
+		Where("message_id = ?", messageID).
+		Order("id").
+		Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// GetInbox returns up to limit direct conversations of the user ordered by their
+// latest message, newest first. When before is not 0 only conversations whose
+// latest message is older than it are returned. Peers blocked by the user and
+// messages of deleted users are left out.
+func (r RepositoryImpl) GetInbox(userID, before, limit uint64) ([]models.InboxEntry, error) {
+	var latest []struct {
+		PeerID        uint64
+		LastMessageID uint64
+	}
+	// Both halves of the union are served by an index on the user column, so
+	// only the messages of the user are read. They are all read on every page
+	// though, as the latest message of each peer is only known after grouping
+	// them: the cost grows with the history of the user. This is accepted until
+	// it shows up, the fix is a summary row per (user, peer) updated by
+	// SaveMessage and MarkMessagesRead.
+	if err := r.DB.Raw(`SELECT peer_id, MAX(id) AS last_message_id FROM (
+			SELECT recipient_id AS peer_id, id FROM messages WHERE sender_id = @user AND conversation_id = 0
+			UNION ALL
+			SELECT sender_id AS peer_id, id FROM messages WHERE recipient_id = @user AND conversation_id = 0
+		)
+		WHERE peer_id <> @deleted AND peer_id NOT IN (SELECT blocked_id FROM blocks WHERE user_id = @user)
+		GROUP BY peer_id
+		HAVING @before = 0 OR MAX(id) < @before
+		ORDER BY last_message_id DESC
+		LIMIT @limit`,
+		sql.Named("user", userID),
+		sql.Named("deleted", models.DeletedUserID),
+		sql.Named("before", before),
+		sql.Named("limit", limit),
+	).Scan(&latest).Error; err != nil {
+		return nil, err
+	}
+
+	entries := make([]models.InboxEntry, 0, len(latest))
+	if len(latest) == 0 {
+		return entries, nil
+	}
+
+	peerIDs := make([]uint64, 0, len(latest))
+	messageIDs := make([]uint64, 0, len(latest))
+	for _, row := range latest {
+		peerIDs = append(peerIDs, row.PeerID)
+		messageIDs = append(messageIDs, row.LastMessageID)
+	}
+
+	var messages []models.Message
+	if err := r.DB.Where("id IN ?", messageIDs).Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	messagesByID := make(map[uint64]models.Message, len(messages))
+	for _, message := range messages {
+		messagesByID[message.Id] = message
+	}
+
+	var peers []models.User
+	if err := r.DB.Where("id IN ?", peerIDs).Find(&peers).Error; err != nil {
+		return nil, err
+	}
+	peersByID := make(map[uint64]models.User, len(peers))
+	for _, peer := range peers {
+		peersByID[peer.ID] = peer
+	}
+
+	var counts []models.UnreadCount
+	if err := r.DB.
+		Model(&models.Message{}).
+		Select("sender_id, COUNT(*) AS count").
+		Where("recipient_id = ? AND read_at IS NULL AND conversation_id = 0 AND sender_id IN ?", userID, peerIDs).
+		Group("sender_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	unread := make(map[uint64]int64, len(counts))
+	for _, count := range counts {
+		unread[count.SenderID] = count.Count
+	}
+
+	for _, row := range latest {
+		peer, ok := peersByID[row.PeerID]
+		if !ok {
+			peer = models.User{ID: row.PeerID}
+		}
+		entries = append(entries, models.InboxEntry{
+			Peer:        peer,
+			LastMessage: messagesByID[row.LastMessageID],
+			UnreadCount: unread[row.PeerID],
+		})
+	}
+
+	return entries, nil
+}
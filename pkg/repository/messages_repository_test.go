@@ -144,3 +144,407 @@ func TestRepositoryImpl_GetMessagesFromUser(t *testing.T) {
 		})
 	}
 }
+
+func TestRepositoryImpl_GetMessagesAfter(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := &RepositoryImpl{DB: db}
+	for i := 0; i < 3; i++ {
+		_, err := repo.SaveMessage(&models.Message{
+			SenderID:    1,
+			RecipientID: 2,
+			Timestamp:   time.Now().String(),
+			Content:     models.Content{Type: "text", Text: "test message"},
+		})
+		assert.NoError(t, err)
+	}
+	_, err := repo.SaveMessage(&models.Message{
+		SenderID:    2,
+		RecipientID: 1,
+		Timestamp:   time.Now().String(),
+		Content:     models.Content{Type: "text", Text: "reply"},
+	})
+	assert.NoError(t, err)
+
+	messages, err := repo.GetMessagesAfter(2, 1, 10)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+	assert.Equal(t, uint64(2), messages[0].Id)
+	assert.Equal(t, uint64(3), messages[1].Id)
+
+	messages, err = repo.GetMessagesAfter(2, 0, 2)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+	assert.Equal(t, uint64(2), messages[1].Id)
+
+	// messages of the conversations of the user are replayed too, except their own
+	assert.NoError(t, db.Create(&models.ConversationMember{ConversationID: 7, UserID: 2, Role: models.ConversationRoleMember}).Error)
+	for _, sender := range []uint64{1, 2} {
+		_, err = repo.SaveMessage(&models.Message{
+			SenderID:       sender,
+			ConversationID: 7,
+			Timestamp:      time.Now().String(),
+			Content:        models.Content{Type: "text", Text: "group message"},
+		})
+		assert.NoError(t, err)
+	}
+	_, err = repo.SaveMessage(&models.Message{
+		SenderID:       1,
+		ConversationID: 8,
+		Timestamp:      time.Now().String(),
+		Content:        models.Content{Type: "text", Text: "other group"},
+	})
+	assert.NoError(t, err)
+
+	messages, err = repo.GetMessagesAfter(2, 3, 10)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, uint64(5), messages[0].Id)
+
+	closed := &RepositoryImpl{DB: SetupTestDBConnectionClosed(t)}
+	_, err = closed.GetMessagesAfter(2, 1, 10)
+	assert.Equal(t, errors.New("sql: database is closed"), err)
+}
+
+func TestRepositoryImpl_GetMessagesBetweenUsers(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := &RepositoryImpl{DB: db}
+	pairs := [][2]uint64{{1, 2}, {2, 1}, {1, 3}, {3, 2}, {1, 2}}
+	for _, pair := range pairs {
+		_, err := repo.SaveMessage(&models.Message{
+			SenderID:    pair[0],
+			RecipientID: pair[1],
+			Timestamp:   time.Now().String(),
+			Content:     models.Content{Type: "text", Text: "test message"},
+		})
+		assert.NoError(t, err)
+	}
+
+	messages, err := repo.GetMessagesBetweenUsers(1, 2, 0, 100)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 3)
+	assert.Equal(t, uint64(1), messages[0].Id)
+	assert.Equal(t, uint64(2), messages[1].Id)
+	assert.Equal(t, uint64(5), messages[2].Id)
+
+	messages, err = repo.GetMessagesBetweenUsers(2, 1, 2, 1)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, uint64(2), messages[0].Id)
+
+	closed := &RepositoryImpl{DB: SetupTestDBConnectionClosed(t)}
+	_, err = closed.GetMessagesBetweenUsers(1, 2, 0, 100)
+	assert.Equal(t, errors.New("sql: database is closed"), err)
+}
+
+func TestRepositoryImpl_GetMessagesPage(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := &RepositoryImpl{DB: db}
+	// Messages 1, 3, 5, 7 and 9 are sent to user 2
+	for i := 0; i < 10; i++ {
+		recipient := uint64(2)
+		if i%2 == 1 {
+			recipient = 3
+		}
+		_, err := repo.SaveMessage(&models.Message{
+			SenderID:    1,
+			RecipientID: recipient,
+			Timestamp:   time.Now().String(),
+			Content:     models.Content{Type: "text", Text: "test message"},
+		})
+		assert.NoError(t, err)
+	}
+
+	ids := func(messages []models.Message) []uint64 {
+		result := []uint64{}
+		for _, message := range messages {
+			result = append(result, message.Id)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name            string
+		cursor          uint64
+		direction       string
+		limit           uint64
+		expectedIDs     []uint64
+		expectedHasMore bool
+	}{
+		{
+			name:            "newest page",
+			cursor:          0,
+			direction:       models.PageBefore,
+			limit:           2,
+			expectedIDs:     []uint64{7, 9},
+			expectedHasMore: true,
+		},
+		{
+			name:            "before cursor",
+			cursor:          7,
+			direction:       models.PageBefore,
+			limit:           2,
+			expectedIDs:     []uint64{3, 5},
+			expectedHasMore: true,
+		},
+		{
+			name:            "before cursor reaching the oldest message",
+			cursor:          3,
+			direction:       models.PageBefore,
+			limit:           2,
+			expectedIDs:     []uint64{1},
+			expectedHasMore: false,
+		},
+		{
+			name:            "after cursor",
+			cursor:          3,
+			direction:       models.PageAfter,
+			limit:           2,
+			expectedIDs:     []uint64{5, 7},
+			expectedHasMore: true,
+		},
+		{
+			name:            "after the newest message",
+			cursor:          9,
+			direction:       models.PageAfter,
+			limit:           2,
+			expectedIDs:     []uint64{},
+			expectedHasMore: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, hasMore, err := repo.GetMessagesPage(2, tt.cursor, tt.direction, tt.limit)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedIDs, ids(messages))
+			assert.Equal(t, tt.expectedHasMore, hasMore)
+		})
+	}
+
+	closed := &RepositoryImpl{DB: SetupTestDBConnectionClosed(t)}
+	_, _, err := closed.GetMessagesPage(2, 0, models.PageBefore, 10)
+	assert.Equal(t, errors.New("sql: database is closed"), err)
+}
+
+func TestRepositoryImpl_GetMessage(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := &RepositoryImpl{DB: db}
+	_, err := repo.SaveMessage(&models.Message{
+		SenderID:    1,
+		RecipientID: 2,
+		Timestamp:   time.Now().String(),
+		Content:     models.Content{Type: "text", Text: "test message"},
+	})
+	assert.NoError(t, err)
+
+	message, err := repo.GetMessage(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "test message", message.Content.Text)
+
+	_, err = repo.GetMessage(2)
+	assert.Error(t, err)
+}
+
+func TestRepositoryImpl_MarkMessages(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := &RepositoryImpl{DB: db}
+	senders := []uint64{1, 3, 1, 1}
+	for _, sender := range senders {
+		_, err := repo.SaveMessage(&models.Message{
+			SenderID:    sender,
+			RecipientID: 2,
+			Timestamp:   time.Now().String(),
+			Content:     models.Content{Type: "text", Text: "test message"},
+		})
+		assert.NoError(t, err)
+	}
+
+	delivered := time.Now().Add(-time.Minute).UTC()
+	err := repo.MarkMessagesDelivered(2, []uint64{1, 2}, delivered)
+	assert.NoError(t, err)
+
+	// Messages received by another user are not affected
+	err = repo.MarkMessagesDelivered(3, []uint64{3}, delivered)
+	assert.NoError(t, err)
+
+	read := time.Now().UTC()
+	updated, err := repo.MarkMessagesRead(2, 1, 3, read)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), updated)
+
+	var messages []models.Message
+	err = db.Order("id").Find(&messages).Error
+	assert.NoError(t, err)
+
+	assert.Equal(t, models.MessageStatusRead, messages[0].Status())
+	assert.WithinDuration(t, delivered, *messages[0].DeliveredAt, time.Millisecond)
+	assert.WithinDuration(t, read, *messages[0].ReadAt, time.Millisecond)
+	assert.Equal(t, models.MessageStatusDelivered, messages[1].Status())
+	assert.Equal(t, models.MessageStatusRead, messages[2].Status())
+	assert.WithinDuration(t, read, *messages[2].DeliveredAt, time.Millisecond)
+	assert.Equal(t, models.MessageStatusSent, messages[3].Status())
+
+	closed := &RepositoryImpl{DB: SetupTestDBConnectionClosed(t)}
+	_, err = closed.MarkMessagesRead(2, 0, 3, read)
+	assert.Equal(t, errors.New("sql: database is closed"), err)
+}
+
+func TestRepositoryImpl_CountUnreadMessages(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := &RepositoryImpl{DB: db}
+	pairs := [][2]uint64{{1, 2}, {3, 2}, {1, 2}, {1, 3}, {3, 2}}
+	for _, pair := range pairs {
+		_, err := repo.SaveMessage(&models.Message{
+			SenderID:    pair[0],
+			RecipientID: pair[1],
+			Timestamp:   time.Now().String(),
+			Content:     models.Content{Type: "text", Text: "test message"},
+		})
+		assert.NoError(t, err)
+	}
+	_, err := repo.MarkMessagesRead(2, 3, 2, time.Now())
+	assert.NoError(t, err)
+
+	counts, err := repo.CountUnreadMessages(2)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.UnreadCount{
+		{SenderID: 1, Count: 2},
+		{SenderID: 3, Count: 1},
+	}, counts)
+
+	assert.True(t, db.Migrator().HasIndex(&models.Message{}, "idx_messages_unread"))
+
+	closed := &RepositoryImpl{DB: SetupTestDBConnectionClosed(t)}
+	_, err = closed.CountUnreadMessages(2)
+	assert.Equal(t, errors.New("sql: database is closed"), err)
+}
+
+func TestRepositoryImpl_UpdateMessage(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := &RepositoryImpl{DB: db}
+	message, err := repo.SaveMessage(&models.Message{
+		SenderID:    1,
+		RecipientID: 2,
+		Timestamp:   time.Now().String(),
+		Content:     models.Content{Type: "text", Text: "original"},
+	})
+	assert.NoError(t, err)
+
+	now := time.Now()
+	revision := &models.MessageRevision{
+		MessageID: message.Id,
+		Action:    models.RevisionActionEdit,
+		EditedBy:  1,
+		EditedAt:  now,
+		Content:   message.Content,
+	}
+	message.Content.Text = "edited"
+	message.EditedAt = &now
+
+	_, err = repo.UpdateMessage(message, revision)
+	assert.NoError(t, err)
+
+	saved, err := repo.GetMessage(message.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, "edited", saved.Content.Text)
+	assert.NotNil(t, saved.EditedAt)
+
+	revisions, err := repo.GetMessageRevisions(message.Id)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 1)
+	assert.Equal(t, "original", revisions[0].Content.Text)
+	assert.Equal(t, models.RevisionActionEdit, revisions[0].Action)
+
+	var users int64
+	db.Model(&models.User{}).Count(&users)
+	assert.Equal(t, int64(0), users)
+
+	closed := &RepositoryImpl{DB: SetupTestDBConnectionClosed(t)}
+	_, err = closed.UpdateMessage(message, &models.MessageRevision{MessageID: message.Id})
+	assert.Error(t, err)
+}
+
+func TestRepositoryImpl_SaveMessage_MediaContent(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := &RepositoryImpl{DB: db}
+	contents := []models.Content{
+		{Type: "image", URL: "https://example.com/a.png", Width: 640, Height: 480, MimeType: "image/png"},
+		{Type: "video", URL: "https://youtu.be/abc", Duration: 90, Source: "youtube"},
+	}
+
+	for _, content := range contents {
+		message, err := repo.SaveMessage(&models.Message{
+			SenderID:    1,
+			RecipientID: 2,
+			Timestamp:   time.Now().String(),
+			Content:     content,
+		})
+		assert.NoError(t, err)
+
+		saved, err := repo.GetMessage(message.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, content, saved.Content)
+	}
+}
+
+func TestRepositoryImpl_GetInbox(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := &RepositoryImpl{DB: db}
+	for _, user := range []models.User{{ID: 1, Username: "alice"}, {ID: 2, Username: "bob"}, {ID: 3, Username: "carol"}, {ID: 4, Username: "dave"}} {
+		assert.NoError(t, db.Create(&user).Error)
+	}
+	messages := []models.Message{
+		{SenderID: 2, RecipientID: 1},
+		{SenderID: 1, RecipientID: 3},
+		{SenderID: 2, RecipientID: 1},
+		{SenderID: 3, RecipientID: 1},
+		{SenderID: 1, RecipientID: 2},
+		{SenderID: 4, RecipientID: 1},
+		{SenderID: 1, ConversationID: 7},
+		{SenderID: models.DeletedUserID, RecipientID: 1},
+	}
+	for i := range messages {
+		messages[i].Timestamp = time.Now().String()
+		messages[i].Content = models.Content{Type: "text", Text: "test message"}
+		_, err := repo.SaveMessage(&messages[i])
+		assert.NoError(t, err)
+	}
+	_, err := repo.MarkMessagesRead(1, 3, 4, time.Now())
+	assert.NoError(t, err)
+	assert.NoError(t, repo.BlockUser(1, 4, time.Now()))
+
+	entries, err := repo.GetInbox(1, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "bob", entries[0].Peer.Username)
+	assert.Equal(t, uint64(5), entries[0].LastMessage.Id)
+	assert.Equal(t, int64(2), entries[0].UnreadCount)
+	assert.Equal(t, "carol", entries[1].Peer.Username)
+	assert.Equal(t, uint64(4), entries[1].LastMessage.Id)
+	assert.Zero(t, entries[1].UnreadCount)
+
+	entries, err = repo.GetInbox(1, 0, 1)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	entries, err = repo.GetInbox(1, entries[0].LastMessage.Id, 1)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, uint64(3), entries[0].Peer.ID)
+
+	entries, err = repo.GetInbox(3, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, uint64(1), entries[0].Peer.ID)
+	assert.Equal(t, uint64(4), entries[0].LastMessage.Id)
+
+	entries, err = repo.GetInbox(9, 0, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	assert.True(t, db.Migrator().HasIndex(&models.Message{}, "idx_messages_sender"))
+
+	closed := &RepositoryImpl{DB: SetupTestDBConnectionClosed(t)}
+	_, err = closed.GetInbox(1, 0, 10)
+	assert.Equal(t, errors.New("sql: database is closed"), err)
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/challenge/pkg/models"
 	"gorm.io/gorm"
 )
@@ -10,8 +12,73 @@ type Repository interface {
 	CreateUser(user *models.User) (*models.User, error)
 	GetUser(id uint64) (*models.User, error)
 	GetUserByUsername(username string) (*models.User, error)
+	UpdateUserPassword(userID uint64, password string) error
+	UpdateUserProfile(userID uint64, update models.ProfileUpdate) (*models.User, error)
+	ListUsers(after, limit uint64) ([]models.User, error)
+	UpdateUserRole(userID uint64, role string) error
+	UpdateUserDisabled(userID uint64, disabledAt *time.Time) error
 	SaveMessage(message *models.Message) (*models.Message, error)
+	GetMessage(id uint64) (*models.Message, error)
 	GetMessagesFromUser(id, start, limit uint64) ([]models.Message, error)
+	GetMessagesPage(id, cursor uint64, direction string, limit uint64) ([]models.Message, bool, error)
+	GetMessagesAfter(id, after, limit uint64) ([]models.Message, error)
+	GetMessagesBetweenUsers(id, peer, start, limit uint64) ([]models.Message, error)
+	MarkMessagesDelivered(recipientID uint64, ids []uint64, at time.Time) error
+	MarkMessagesRead(recipientID, senderID, upTo uint64, at time.Time) (int64, error)
+	GetInbox(userID, before, limit uint64) ([]models.InboxEntry, error)
+	CountUnreadMessages(recipientID uint64) ([]models.UnreadCount, error)
+	UpdateMessage(message *models.Message, revision *models.MessageRevision) (*models.Message, error)
+	GetMessageRevisions(messageID uint64) ([]models.MessageRevision, error)
+	CreateConversation(conversation *models.Conversation) (*models.Conversation, error)
+	GetConversation(id uint64) (*models.Conversation, error)
+	GetConversationMember(conversationID, userID uint64) (*models.ConversationMember, error)
+	SaveConversationMember(member *models.ConversationMember) (*models.ConversationMember, error)
+	DeleteConversationMember(conversationID, userID uint64) error
+	SaveConversationMessage(message *models.Message) (*models.Message, error)
+	GetConversationMessages(conversationID, userID, start, limit uint64) ([]models.Message, error)
+	SaveAttachment(attachment *models.Attachment) (*models.Attachment, error)
+	GetAttachment(id uint64) (*models.Attachment, error)
+	IsAttachmentRecipient(attachmentID, userID uint64) (bool, error)
+	CreateRefreshToken(token *models.RefreshToken) (*models.RefreshToken, error)
+	GetRefreshToken(tokenHash string) (*models.RefreshToken, error)
+	RotateRefreshToken(old, next *models.RefreshToken, at time.Time) (*models.RefreshToken, error)
+	RevokeSession(userID uint64, sessionID string, at time.Time) error
+	RevokeUserSessions(userID uint64, at time.Time) error
+	GetLoginAttempt(key string) (*models.LoginAttempt, error)
+	SaveLoginAttempt(attempt *models.LoginAttempt) error
+	DeleteLoginAttempt(key string) error
+	DeleteStaleLoginAttempts(lastFailureBefore, now time.Time) error
+	SaveLoginLockout(lockout *models.LoginLockout) (*models.LoginLockout, error)
+	GetTOTPCredential(userID uint64) (*models.TOTPCredential, error)
+	SaveTOTPCredential(credential *models.TOTPCredential) (*models.TOTPCredential, error)
+	ConfirmTOTPCredential(userID uint64, step int64, codes []models.RecoveryCode, at time.Time) error
+	UseTOTPStep(userID uint64, step int64) (bool, error)
+	UseRecoveryCode(userID uint64, codeHash string, at time.Time) (bool, error)
+	CreateLoginChallenge(challenge *models.LoginChallenge) (*models.LoginChallenge, error)
+	GetLoginChallenge(tokenHash string) (*models.LoginChallenge, error)
+	FailLoginChallenge(id uint64) error
+	DeleteLoginChallenge(id uint64) (bool, error)
+	CreateAccountDeletion(deletion *models.AccountDeletion) (*models.AccountDeletion, error)
+	GetAccountDeletion(userID uint64) (*models.AccountDeletion, error)
+	NextAccountDeletion() (*models.AccountDeletion, error)
+	DeleteUserMessages(deletion *models.AccountDeletion, limit uint64) (int64, error)
+	GetUserAttachments(userID, limit uint64) ([]models.Attachment, error)
+	DeleteAttachment(id uint64) error
+	CompleteAccountDeletion(deletion *models.AccountDeletion, at time.Time) error
+	CreateDataExport(export *models.DataExport) (*models.DataExport, error)
+	GetDataExport(id uint64) (*models.DataExport, error)
+	NextDataExport() (*models.DataExport, error)
+	UpdateDataExport(export *models.DataExport) error
+	ClaimDataExport(id uint64, at time.Time) (bool, error)
+	GetExpiredDataExports(at time.Time, limit uint64) ([]models.DataExport, error)
+	StreamUserMessages(userID uint64, fn func(message *models.Message) error) error
+	BlockUser(userID, blockedID uint64, at time.Time) error
+	UnblockUser(userID, blockedID uint64) error
+	GetBlockedUsers(userID uint64) ([]models.Block, error)
+	IsBlocked(userID, blockedID uint64) (bool, error)
+	GetBlockerIDs(blockedID uint64) ([]uint64, error)
+	HasSentMessage(senderID, recipientID uint64) (bool, error)
+	UpdateUserContactsOnly(userID uint64, contactsOnly bool) error
 }
 
 type RepositoryImpl struct {
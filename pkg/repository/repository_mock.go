@@ -6,6 +6,7 @@ import (
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"testing"
+	"time"
 )
 
 // MockRepository is a mock repository for testing purposes
@@ -50,6 +51,14 @@ func (m *MockRepository) SaveMessage(message *models.Message) (*models.Message,
 	return args.Get(0).(*models.Message), args.Error(1)
 }
 
+func (m *MockRepository) GetMessage(id uint64) (*models.Message, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Message), args.Error(1)
+}
+
 func (m *MockRepository) GetMessagesFromUser(id, start, limit uint64) ([]models.Message, error) {
 	args := m.Called(id, start, limit)
 	if args.Get(0) == nil {
@@ -58,13 +67,449 @@ func (m *MockRepository) GetMessagesFromUser(id, start, limit uint64) ([]models.
 	return args.Get(0).([]models.Message), args.Error(1)
 }
 
+func (m *MockRepository) GetMessagesPage(id, cursor uint64, direction string, limit uint64) ([]models.Message, bool, error) {
+	args := m.Called(id, cursor, direction, limit)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).([]models.Message), args.Bool(1), args.Error(2)
+}
+
+func (m *MockRepository) GetMessagesAfter(id, after, limit uint64) ([]models.Message, error) {
+	args := m.Called(id, after, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Message), args.Error(1)
+}
+
+func (m *MockRepository) GetMessagesBetweenUsers(id, peer, start, limit uint64) ([]models.Message, error) {
+	args := m.Called(id, peer, start, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Message), args.Error(1)
+}
+
+func (m *MockRepository) MarkMessagesDelivered(recipientID uint64, ids []uint64, at time.Time) error {
+	args := m.Called(recipientID, ids, at)
+	return args.Error(0)
+}
+
+func (m *MockRepository) MarkMessagesRead(recipientID, senderID, upTo uint64, at time.Time) (int64, error) {
+	args := m.Called(recipientID, senderID, upTo, at)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) CountUnreadMessages(recipientID uint64) ([]models.UnreadCount, error) {
+	args := m.Called(recipientID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.UnreadCount), args.Error(1)
+}
+
+func (m *MockRepository) UpdateMessage(message *models.Message, revision *models.MessageRevision) (*models.Message, error) {
+	args := m.Called(message, revision)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Message), args.Error(1)
+}
+
+func (m *MockRepository) GetMessageRevisions(messageID uint64) ([]models.MessageRevision, error) {
+	args := m.Called(messageID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.MessageRevision), args.Error(1)
+}
+
+func (m *MockRepository) CreateConversation(conversation *models.Conversation) (*models.Conversation, error) {
+	args := m.Called(conversation)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Conversation), args.Error(1)
+}
+
+func (m *MockRepository) GetConversation(id uint64) (*models.Conversation, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Conversation), args.Error(1)
+}
+
+func (m *MockRepository) GetConversationMember(conversationID, userID uint64) (*models.ConversationMember, error) {
+	args := m.Called(conversationID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ConversationMember), args.Error(1)
+}
+
+func (m *MockRepository) SaveConversationMember(member *models.ConversationMember) (*models.ConversationMember, error) {
+	args := m.Called(member)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ConversationMember), args.Error(1)
+}
+
+func (m *MockRepository) DeleteConversationMember(conversationID, userID uint64) error {
+	args := m.Called(conversationID, userID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) SaveConversationMessage(message *models.Message) (*models.Message, error) {
+	args := m.Called(message)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Message), args.Error(1)
+}
+
+func (m *MockRepository) GetConversationMessages(conversationID, userID, start, limit uint64) ([]models.Message, error) {
+	args := m.Called(conversationID, userID, start, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Message), args.Error(1)
+}
+
+func (m *MockRepository) SaveAttachment(attachment *models.Attachment) (*models.Attachment, error) {
+	args := m.Called(attachment)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Attachment), args.Error(1)
+}
+
+func (m *MockRepository) GetAttachment(id uint64) (*models.Attachment, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Attachment), args.Error(1)
+}
+
+func (m *MockRepository) IsAttachmentRecipient(attachmentID, userID uint64) (bool, error) {
+	args := m.Called(attachmentID, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) CreateRefreshToken(token *models.RefreshToken) (*models.RefreshToken, error) {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RefreshToken), args.Error(1)
+}
+
+func (m *MockRepository) GetRefreshToken(tokenHash string) (*models.RefreshToken, error) {
+	args := m.Called(tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RefreshToken), args.Error(1)
+}
+
+func (m *MockRepository) RotateRefreshToken(old, next *models.RefreshToken, at time.Time) (*models.RefreshToken, error) {
+	args := m.Called(old, next, at)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RefreshToken), args.Error(1)
+}
+
+func (m *MockRepository) RevokeSession(userID uint64, sessionID string, at time.Time) error {
+	args := m.Called(userID, sessionID, at)
+	return args.Error(0)
+}
+
+func (m *MockRepository) RevokeUserSessions(userID uint64, at time.Time) error {
+	args := m.Called(userID, at)
+	return args.Error(0)
+}
+
+func (m *MockRepository) UpdateUserPassword(userID uint64, password string) error {
+	args := m.Called(userID, password)
+	return args.Error(0)
+}
+
+func (m *MockRepository) UpdateUserProfile(userID uint64, update models.ProfileUpdate) (*models.User, error) {
+	args := m.Called(userID, update)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockRepository) ListUsers(after, limit uint64) ([]models.User, error) {
+	args := m.Called(after, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+func (m *MockRepository) UpdateUserRole(userID uint64, role string) error {
+	args := m.Called(userID, role)
+	return args.Error(0)
+}
+
+func (m *MockRepository) UpdateUserDisabled(userID uint64, disabledAt *time.Time) error {
+	args := m.Called(userID, disabledAt)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetLoginAttempt(key string) (*models.LoginAttempt, error) {
+	args := m.Called(key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LoginAttempt), args.Error(1)
+}
+
+func (m *MockRepository) SaveLoginAttempt(attempt *models.LoginAttempt) error {
+	args := m.Called(attempt)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeleteLoginAttempt(key string) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeleteStaleLoginAttempts(lastFailureBefore, now time.Time) error {
+	args := m.Called(lastFailureBefore, now)
+	return args.Error(0)
+}
+
+func (m *MockRepository) SaveLoginLockout(lockout *models.LoginLockout) (*models.LoginLockout, error) {
+	args := m.Called(lockout)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LoginLockout), args.Error(1)
+}
+
+func (m *MockRepository) GetTOTPCredential(userID uint64) (*models.TOTPCredential, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TOTPCredential), args.Error(1)
+}
+
+func (m *MockRepository) SaveTOTPCredential(credential *models.TOTPCredential) (*models.TOTPCredential, error) {
+	args := m.Called(credential)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TOTPCredential), args.Error(1)
+}
+
+func (m *MockRepository) ConfirmTOTPCredential(userID uint64, step int64, codes []models.RecoveryCode, at time.Time) error {
+	args := m.Called(userID, step, codes, at)
+	return args.Error(0)
+}
+
+func (m *MockRepository) UseTOTPStep(userID uint64, step int64) (bool, error) {
+	args := m.Called(userID, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) UseRecoveryCode(userID uint64, codeHash string, at time.Time) (bool, error) {
+	args := m.Called(userID, codeHash, at)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) CreateLoginChallenge(challenge *models.LoginChallenge) (*models.LoginChallenge, error) {
+	args := m.Called(challenge)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LoginChallenge), args.Error(1)
+}
+
+func (m *MockRepository) GetLoginChallenge(tokenHash string) (*models.LoginChallenge, error) {
+	args := m.Called(tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LoginChallenge), args.Error(1)
+}
+
+func (m *MockRepository) FailLoginChallenge(id uint64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeleteLoginChallenge(id uint64) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) CreateAccountDeletion(deletion *models.AccountDeletion) (*models.AccountDeletion, error) {
+	args := m.Called(deletion)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AccountDeletion), args.Error(1)
+}
+
+func (m *MockRepository) GetAccountDeletion(userID uint64) (*models.AccountDeletion, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AccountDeletion), args.Error(1)
+}
+
+func (m *MockRepository) NextAccountDeletion() (*models.AccountDeletion, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AccountDeletion), args.Error(1)
+}
+
+func (m *MockRepository) DeleteUserMessages(deletion *models.AccountDeletion, limit uint64) (int64, error) {
+	args := m.Called(deletion, limit)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) GetUserAttachments(userID, limit uint64) ([]models.Attachment, error) {
+	args := m.Called(userID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Attachment), args.Error(1)
+}
+
+func (m *MockRepository) DeleteAttachment(id uint64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CompleteAccountDeletion(deletion *models.AccountDeletion, at time.Time) error {
+	args := m.Called(deletion, at)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateDataExport(export *models.DataExport) (*models.DataExport, error) {
+	args := m.Called(export)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DataExport), args.Error(1)
+}
+
+func (m *MockRepository) GetDataExport(id uint64) (*models.DataExport, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DataExport), args.Error(1)
+}
+
+func (m *MockRepository) NextDataExport() (*models.DataExport, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DataExport), args.Error(1)
+}
+
+func (m *MockRepository) UpdateDataExport(export *models.DataExport) error {
+	args := m.Called(export)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ClaimDataExport(id uint64, at time.Time) (bool, error) {
+	args := m.Called(id, at)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) GetExpiredDataExports(at time.Time, limit uint64) ([]models.DataExport, error) {
+	args := m.Called(at, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.DataExport), args.Error(1)
+}
+
+// StreamUserMessages calls fn with the messages passed to Return before the error
+func (m *MockRepository) StreamUserMessages(userID uint64, fn func(message *models.Message) error) error {
+	args := m.Called(userID, fn)
+	if messages, ok := args.Get(0).([]models.Message); ok {
+		for i := range messages {
+			if err := fn(&messages[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockRepository) GetInbox(userID, before, limit uint64) ([]models.InboxEntry, error) {
+	args := m.Called(userID, before, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.InboxEntry), args.Error(1)
+}
+
+func (m *MockRepository) BlockUser(userID, blockedID uint64, at time.Time) error {
+	args := m.Called(userID, blockedID, at)
+	return args.Error(0)
+}
+
+func (m *MockRepository) UnblockUser(userID, blockedID uint64) error {
+	args := m.Called(userID, blockedID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetBlockedUsers(userID uint64) ([]models.Block, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Block), args.Error(1)
+}
+
+func (m *MockRepository) IsBlocked(userID, blockedID uint64) (bool, error) {
+	args := m.Called(userID, blockedID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) GetBlockerIDs(blockedID uint64) ([]uint64, error) {
+	args := m.Called(blockedID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uint64), args.Error(1)
+}
+
+func (m *MockRepository) HasSentMessage(senderID, recipientID uint64) (bool, error) {
+	args := m.Called(senderID, recipientID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) UpdateUserContactsOnly(userID uint64, contactsOnly bool) error {
+	args := m.Called(userID, contactsOnly)
+	return args.Error(0)
+}
+
 func SetupTestDB(t *testing.T) *gorm.DB {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	if err != nil {
 		t.Fatalf("Failed to connect to test database: %v", err)
 	}
 
-	err = db.AutoMigrate(&models.User{}, &models.Message{})
+	err = db.AutoMigrate(&models.User{}, &models.Message{}, &models.MessageRevision{}, &models.Conversation{}, &models.ConversationMember{}, &models.Attachment{}, &models.RefreshToken{}, &models.RevokedToken{}, &models.LoginAttempt{}, &models.LoginLockout{}, &models.TOTPCredential{}, &models.RecoveryCode{}, &models.LoginChallenge{}, &models.AccountDeletion{}, &models.DataExport{}, &models.Block{})
 	if err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
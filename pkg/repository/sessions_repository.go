@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/challenge/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrRefreshTokenRevoked = errors.New("refresh token was already revoked")
+
+func (r RepositoryImpl) CreateRefreshToken(token *models.RefreshToken) (*models.RefreshToken, error) {
+	if err := r.DB.Create(&token).Error; err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (r RepositoryImpl) GetRefreshToken(tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := r.DB.
+		Where("token_hash = ?", tokenHash).
+		First(&token).Error; err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// RotateRefreshToken revokes the old refresh token together with its access
+// token and saves the next one. It fails with ErrRefreshTokenRevoked when the
+// old token was revoked in the meantime, so a token can only be used once.
+func (r RepositoryImpl) RotateRefreshToken(old, next *models.RefreshToken, at time.Time) (*models.RefreshToken, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", old.ID).
+			Update("revoked_at", at)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenRevoked
+		}
+
+		if err := revokeAccessTokens(tx, []models.RefreshToken{*old}, at); err != nil {
+			return err
+		}
+
+		return tx.Create(&next).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return next, nil
+}
+
+// RevokeSession revokes every refresh token of a session still in use and the
+// access tokens issued with them
+func (r RepositoryImpl) RevokeSession(userID uint64, sessionID string, at time.Time) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		return revokeRefreshTokens(tx, at, "user_id = ? AND session_id = ?", userID, sessionID)
+	})
+}
+
+// RevokeUserSessions revokes every session of the user
+func (r RepositoryImpl) RevokeUserSessions(userID uint64, at time.Time) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		return revokeRefreshTokens(tx, at, "user_id = ?", userID)
+	})
+}
+
+// revokeRefreshTokens revokes the refresh tokens matching the conditions that
+// are still in use and the access tokens issued with them
+func revokeRefreshTokens(tx *gorm.DB, at time.Time, query string, args ...interface{}) error {
+	var tokens []models.RefreshToken
+	if err := tx.
+		Where(query, args...).
+		Where("revoked_at IS NULL").
+		Find(&tokens).Error; err != nil {
+		return err
+	}
+
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	if err := revokeAccessTokens(tx, tokens, at); err != nil {
+		return err
+	}
+
+	ids := make([]uint64, 0, len(tokens))
+	for _, token := range tokens {
+		ids = append(ids, token.ID)
+	}
+
+	return tx.
+		Model(&models.RefreshToken{}).
+		Where("id IN ?", ids).
+		Update("revoked_at", at).Error
+}
+
+// revokeAccessTokens adds the access tokens issued with the refresh tokens to
+// the revocation list, dropping the entries that already expired
+func revokeAccessTokens(tx *gorm.DB, tokens []models.RefreshToken, at time.Time) error {
+	var revoked []models.RevokedToken
+	for _, token := range tokens {
+		if token.AccessTokenID != "" && token.AccessExpiresAt.After(at) {
+			revoked = append(revoked, models.RevokedToken{JTI: token.AccessTokenID, ExpiresAt: token.AccessExpiresAt})
+		}
+	}
+
+	if len(revoked) > 0 {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&revoked).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Where("expires_at <= ?", at).Delete(&models.RevokedToken{}).Error
+}
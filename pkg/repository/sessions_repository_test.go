@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/challenge/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryImpl_RotateRefreshToken(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := &RepositoryImpl{DB: db}
+	now := time.Now()
+
+	first, err := repo.CreateRefreshToken(&models.RefreshToken{
+		UserID: 1, SessionID: "session", TokenHash: "first", AccessTokenID: "access-1",
+		AccessExpiresAt: now.Add(time.Minute), ExpiresAt: now.Add(time.Hour),
+	})
+	assert.NoError(t, err)
+
+	second := &models.RefreshToken{
+		UserID: 1, SessionID: "session", TokenHash: "second", AccessTokenID: "access-2",
+		AccessExpiresAt: now.Add(time.Minute), ExpiresAt: now.Add(time.Hour),
+	}
+	_, err = repo.RotateRefreshToken(first, second, now)
+	assert.NoError(t, err)
+
+	saved, err := repo.GetRefreshToken("first")
+	assert.NoError(t, err)
+	assert.NotNil(t, saved.RevokedAt)
+
+	var revoked []models.RevokedToken
+	assert.NoError(t, db.Find(&revoked).Error)
+	assert.Len(t, revoked, 1)
+	assert.Equal(t, "access-1", revoked[0].JTI)
+
+	// The old token cannot be rotated twice
+	_, err = repo.RotateRefreshToken(first, &models.RefreshToken{UserID: 1, SessionID: "session", TokenHash: "third"}, now)
+	assert.Equal(t, ErrRefreshTokenRevoked, err)
+}
+
+func TestRepositoryImpl_RevokeSession(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := &RepositoryImpl{DB: db}
+	now := time.Now()
+
+	for _, token := range []*models.RefreshToken{
+		{UserID: 1, SessionID: "session", TokenHash: "a", AccessTokenID: "access-a", AccessExpiresAt: now.Add(time.Minute), ExpiresAt: now.Add(time.Hour)},
+		{UserID: 1, SessionID: "other", TokenHash: "b", AccessTokenID: "access-b", AccessExpiresAt: now.Add(time.Minute), ExpiresAt: now.Add(time.Hour)},
+		{UserID: 2, SessionID: "session", TokenHash: "c", AccessTokenID: "access-c", AccessExpiresAt: now.Add(time.Minute), ExpiresAt: now.Add(time.Hour)},
+	} {
+		_, err := repo.CreateRefreshToken(token)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, db.Create(&models.RevokedToken{JTI: "expired", ExpiresAt: now.Add(-time.Minute)}).Error)
+
+	err := repo.RevokeSession(1, "session", now)
+	assert.NoError(t, err)
+
+	for hash, expected := range map[string]bool{"a": true, "b": false, "c": false} {
+		token, err := repo.GetRefreshToken(hash)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, token.RevokedAt != nil, "token %s", hash)
+	}
+
+	var revoked []models.RevokedToken
+	assert.NoError(t, db.Find(&revoked).Error)
+	assert.Len(t, revoked, 1)
+	assert.Equal(t, "access-a", revoked[0].JTI)
+}
+
+func TestRepositoryImpl_RevokeUserSessions(t *testing.T) {
+	repo := &RepositoryImpl{DB: SetupTestDB(t)}
+	now := time.Now()
+
+	for _, token := range []*models.RefreshToken{
+		{UserID: 1, SessionID: "first", TokenHash: "a", AccessTokenID: "access-a", AccessExpiresAt: now.Add(time.Minute), ExpiresAt: now.Add(time.Hour)},
+		{UserID: 1, SessionID: "second", TokenHash: "b", AccessTokenID: "access-b", AccessExpiresAt: now.Add(time.Minute), ExpiresAt: now.Add(time.Hour)},
+		{UserID: 2, SessionID: "third", TokenHash: "c", AccessTokenID: "access-c", AccessExpiresAt: now.Add(time.Minute), ExpiresAt: now.Add(time.Hour)},
+	} {
+		_, err := repo.CreateRefreshToken(token)
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, repo.RevokeUserSessions(1, now))
+
+	for hash, expected := range map[string]bool{"a": true, "b": true, "c": false} {
+		token, err := repo.GetRefreshToken(hash)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, token.RevokedAt != nil, "token %s", hash)
+	}
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/challenge/pkg/models"
+	"gorm.io/gorm"
+)
+
+var ErrTOTPAlreadyConfirmed = errors.New("two-factor authentication was already confirmed")
+
+func (r RepositoryImpl) GetTOTPCredential(userID uint64) (*models.TOTPCredential, error) {
+	var credential models.TOTPCredential
+	if err := r.DB.
+		Where("user_id = ?", userID).
+		First(&credential).Error; err != nil {
+		return nil, err
+	}
+
+	return &credential, nil
+}
+
+// SaveTOTPCredential creates or replaces the TOTP secret of a user
+func (r RepositoryImpl) SaveTOTPCredential(credential *models.TOTPCredential) (*models.TOTPCredential, error) {
+	if err := r.DB.Save(&credential).Error; err != nil {
+		return nil, err
+	}
+
+	return credential, nil
+}
+
+// ConfirmTOTPCredential enables two-factor authentication, marking the step of
+// the code used to confirm it as used and replacing the recovery codes. It fails
+// with ErrTOTPAlreadyConfirmed when it was confirmed in the meantime.
+func (r RepositoryImpl) ConfirmTOTPCredential(userID uint64, step int64, codes []models.RecoveryCode, at time.Time) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Model(&models.TOTPCredential{}).
+			Where("user_id = ? AND confirmed_at IS NULL", userID).
+			Updates(map[string]interface{}{"confirmed_at": at, "last_used_step": step})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTOTPAlreadyConfirmed
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&codes).Error
+	})
+}
+
+// UseTOTPStep marks a time step as used, returning false when a code of the
+// same or a later step was already used so codes cannot be replayed
+func (r RepositoryImpl) UseTOTPStep(userID uint64, step int64) (bool, error) {
+	result := r.DB.
+		Model(&models.TOTPCredential{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+
+	return result.RowsAffected > 0, result.Error
+}
+
+// UseRecoveryCode marks a recovery code of the user as used, returning false
+// when it does not exist or was already used
+func (r RepositoryImpl) UseRecoveryCode(userID uint64, codeHash string, at time.Time) (bool, error) {
+	result := r.DB.
+		Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", at)
+
+	return result.RowsAffected > 0, result.Error
+}
+
+// CreateLoginChallenge saves a challenge, dropping the ones that already expired
+func (r RepositoryImpl) CreateLoginChallenge(challenge *models.LoginChallenge) (*models.LoginChallenge, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at <= ?", time.Now()).Delete(&models.LoginChallenge{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&challenge).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+func (r RepositoryImpl) GetLoginChallenge(tokenHash string) (*models.LoginChallenge, error) {
+	var challenge models.LoginChallenge
+	if err := r.DB.
+		Where("token_hash = ?", tokenHash).
+		First(&challenge).Error; err != nil {
+		return nil, err
+	}
+
+	return &challenge, nil
+}
+
+// FailLoginChallenge counts a wrong code sent for the challenge
+func (r RepositoryImpl) FailLoginChallenge(id uint64) error {
+	return r.DB.
+		Model(&models.LoginChallenge{}).
+		Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+}
+
+// DeleteLoginChallenge consumes a challenge, returning false when it was
+// already consumed so it can only be exchanged once
+func (r RepositoryImpl) DeleteLoginChallenge(id uint64) (bool, error) {
+	result := r.DB.Delete(&models.LoginChallenge{}, id)
+
+	return result.RowsAffected > 0, result.Error
+}
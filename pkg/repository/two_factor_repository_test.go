@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/challenge/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryImpl_ConfirmTOTPCredential(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := &RepositoryImpl{DB: db}
+	now := time.Now()
+
+	_, err := repo.SaveTOTPCredential(&models.TOTPCredential{UserID: 1, Secret: "secret"})
+	assert.NoError(t, err)
+	assert.NoError(t, db.Create(&models.RecoveryCode{UserID: 1, CodeHash: "stale"}).Error)
+
+	err = repo.ConfirmTOTPCredential(1, 100, []models.RecoveryCode{{UserID: 1, CodeHash: "a"}, {UserID: 1, CodeHash: "b"}}, now)
+	assert.NoError(t, err)
+
+	credential, err := repo.GetTOTPCredential(1)
+	assert.NoError(t, err)
+	assert.NotNil(t, credential.ConfirmedAt)
+	assert.Equal(t, int64(100), credential.LastUsedStep)
+
+	var codes []models.RecoveryCode
+	assert.NoError(t, db.Where("user_id = ?", 1).Order("code_hash").Find(&codes).Error)
+	assert.Len(t, codes, 2)
+	assert.Equal(t, "a", codes[0].CodeHash)
+
+	err = repo.ConfirmTOTPCredential(1, 101, []models.RecoveryCode{{UserID: 1, CodeHash: "c"}}, now)
+	assert.Equal(t, ErrTOTPAlreadyConfirmed, err)
+}
+
+func TestRepositoryImpl_UseTOTPStep(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := &RepositoryImpl{DB: db}
+
+	_, err := repo.SaveTOTPCredential(&models.TOTPCredential{UserID: 1, Secret: "secret", LastUsedStep: 100})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		step     int64
+		expected bool
+	}{
+		{name: "later step", step: 101, expected: true},
+		{name: "same step is a replay", step: 101, expected: false},
+		{name: "earlier step", step: 100, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			used, err := repo.UseTOTPStep(1, tt.step)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, used)
+		})
+	}
+}
+
+func TestRepositoryImpl_UseRecoveryCode(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := &RepositoryImpl{DB: db}
+	assert.NoError(t, db.Create(&models.RecoveryCode{UserID: 1, CodeHash: "code"}).Error)
+
+	used, err := repo.UseRecoveryCode(2, "code", time.Now())
+	assert.NoError(t, err)
+	assert.False(t, used, "codes belong to a single user")
+
+	used, err = repo.UseRecoveryCode(1, "code", time.Now())
+	assert.NoError(t, err)
+	assert.True(t, used)
+
+	used, err = repo.UseRecoveryCode(1, "code", time.Now())
+	assert.NoError(t, err)
+	assert.False(t, used, "codes can only be used once")
+}
+
+func TestRepositoryImpl_LoginChallenge(t *testing.T) {
+	db := SetupTestDB(t)
+	repo := &RepositoryImpl{DB: db}
+	now := time.Now()
+
+	assert.NoError(t, db.Create(&models.LoginChallenge{UserID: 1, TokenHash: "expired", ExpiresAt: now.Add(-time.Minute)}).Error)
+
+	challenge, err := repo.CreateLoginChallenge(&models.LoginChallenge{UserID: 1, TokenHash: "hash", ExpiresAt: now.Add(time.Minute)})
+	assert.NoError(t, err)
+
+	_, err = repo.GetLoginChallenge("expired")
+	assert.Error(t, err, "expired challenges are dropped")
+
+	assert.NoError(t, repo.FailLoginChallenge(challenge.ID))
+	assert.NoError(t, repo.FailLoginChallenge(challenge.ID))
+
+	saved, err := repo.GetLoginChallenge("hash")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, saved.Attempts)
+
+	deleted, err := repo.DeleteLoginChallenge(challenge.ID)
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+
+	deleted, err = repo.DeleteLoginChallenge(challenge.ID)
+	assert.NoError(t, err)
+	assert.False(t, deleted, "challenges can only be consumed once")
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/challenge/pkg/models"
 )
 
@@ -22,13 +24,74 @@ func (r RepositoryImpl) GetUser(id uint64) (*models.User, error) {
 	return &user, nil
 }
 
+// GetUserByUsername returns the user with the username, deleted users are left out
 func (r RepositoryImpl) GetUserByUsername(username string) (*models.User, error) {
 	var user models.User
 	if err := r.DB.
-		Where("username = ?", username).
+		Where("username = ? AND deleted_at IS NULL", username).
 		First(&user).Error; err != nil {
 		return nil, err
 	}
 
 	return &user, nil
 }
+
+func (r RepositoryImpl) UpdateUserPassword(userID uint64, password string) error {
+	return r.DB.
+		Model(&models.User{}).
+		Where("id = ?", userID).
+		Update("password", password).Error
+}
+
+// UpdateUserProfile changes the profile fields set in the update and returns the updated user
+func (r RepositoryImpl) UpdateUserProfile(userID uint64, update models.ProfileUpdate) (*models.User, error) {
+	fields := map[string]interface{}{}
+	if update.DisplayName != nil {
+		fields["display_name"] = *update.DisplayName
+	}
+	if update.AvatarURL != nil {
+		fields["avatar_url"] = *update.AvatarURL
+	}
+	if update.StatusText != nil {
+		fields["status_text"] = *update.StatusText
+	}
+
+	if len(fields) > 0 {
+		if err := r.DB.
+			Model(&models.User{ID: userID}).
+			Updates(fields).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return r.GetUser(userID)
+}
+
+// ListUsers returns up to limit users with an id greater than after, ordered by id
+func (r RepositoryImpl) ListUsers(after, limit uint64) ([]models.User, error) {
+	var users []models.User
+	if err := r.DB.
+		Where("id > ?", after).
+		Order("id").
+		Limit(int(limit)).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (r RepositoryImpl) UpdateUserRole(userID uint64, role string) error {
+	return r.DB.
+		Model(&models.User{}).
+		Where("id = ?", userID).
+		Update("role", role).Error
+}
+
+// UpdateUserDisabled disables the user at the given time, or enables them when it is nil
+func (r RepositoryImpl) UpdateUserDisabled(userID uint64, disabledAt *time.Time) error {
+	return r.DB.
+		Model(&models.User{}).
+		Where("id = ?", userID).
+		Update("disabled_at", disabledAt).Error
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/challenge/pkg/models"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestRepositoryImpl_CreateUser(t *testing.T) {
@@ -176,3 +177,71 @@ func TestRepositoryImpl_GetUserByUsername(t *testing.T) {
 		})
 	}
 }
+
+func TestRepositoryImpl_UpdateUserPassword(t *testing.T) {
+	repo := &RepositoryImpl{DB: SetupTestDB(t)}
+	user, err := repo.CreateUser(&models.User{Username: "testuser", Password: "old-hash"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, repo.UpdateUserPassword(user.ID, "new-hash"))
+
+	saved, err := repo.GetUser(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "new-hash", saved.Password)
+}
+
+func TestRepositoryImpl_UpdateUserProfile(t *testing.T) {
+	repo := &RepositoryImpl{DB: SetupTestDB(t)}
+	user, err := repo.CreateUser(&models.User{Username: "testuser", Password: "hash", StatusText: "busy"})
+	assert.NoError(t, err)
+
+	displayName, empty := "Test User", ""
+	updated, err := repo.UpdateUserProfile(user.ID, models.ProfileUpdate{DisplayName: &displayName, StatusText: &empty})
+	assert.NoError(t, err)
+	assert.Equal(t, "Test User", updated.DisplayName)
+	assert.Equal(t, "", updated.StatusText)
+	assert.Equal(t, "hash", updated.Password)
+	assert.False(t, updated.UpdatedAt.Before(user.UpdatedAt))
+
+	_, err = repo.UpdateUserProfile(2, models.ProfileUpdate{DisplayName: &displayName})
+	assert.Error(t, err)
+}
+
+func TestRepositoryImpl_ListUsers(t *testing.T) {
+	repo := &RepositoryImpl{DB: SetupTestDB(t)}
+	for _, username := range []string{"a", "b", "c"} {
+		_, err := repo.CreateUser(&models.User{Username: username, Password: "hash"})
+		assert.NoError(t, err)
+	}
+
+	users, err := repo.ListUsers(1, 10)
+	assert.NoError(t, err)
+	assert.Len(t, users, 2)
+	assert.Equal(t, "b", users[0].Username)
+	assert.Equal(t, "user", users[0].Role)
+
+	users, err = repo.ListUsers(0, 1)
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+	assert.Equal(t, "a", users[0].Username)
+}
+
+func TestRepositoryImpl_UpdateUserRoleAndDisabled(t *testing.T) {
+	repo := &RepositoryImpl{DB: SetupTestDB(t)}
+	user, err := repo.CreateUser(&models.User{Username: "testuser", Password: "hash"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, repo.UpdateUserRole(user.ID, models.RoleAgent))
+	disabledAt := time.Now()
+	assert.NoError(t, repo.UpdateUserDisabled(user.ID, &disabledAt))
+
+	saved, err := repo.GetUser(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleAgent, saved.Role)
+	assert.NotNil(t, saved.DisabledAt)
+
+	assert.NoError(t, repo.UpdateUserDisabled(user.ID, nil))
+	saved, err = repo.GetUser(user.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, saved.DisabledAt)
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/repository"
+	"gorm.io/gorm"
+)
+
+// AccountDeletionBatchSize is the number of messages or attachments the
+// deletion job handles in each transaction
+const AccountDeletionBatchSize = 500
+
+// DeleteAccount schedules the deletion of the account of the user after
+// checking their password
+func (s ServiceImpl) DeleteAccount(userID uint64, password string) (*models.AccountDeletion, error) {
+	user, err := s.GetUserProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	match, _, err := s.Hasher.Verify(user.Password, password)
+	if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to delete account", err)
+	}
+	if !match {
+		return nil, httperrors.BadRequestError("invalid current password")
+	}
+
+	return s.scheduleAccountDeletion(userID, userID)
+}
+
+// DeleteUser schedules the deletion of the account of a user on behalf of an admin
+func (s ServiceImpl) DeleteUser(adminID, userID uint64) (*models.AccountDeletion, error) {
+	if adminID == userID {
+		return nil, httperrors.BadRequestError("you cannot delete your own account")
+	}
+
+	if _, err := s.GetUserProfile(userID); err != nil {
+		return nil, err
+	}
+
+	return s.scheduleAccountDeletion(userID, adminID)
+}
+
+// GetAccountDeletion returns the progress of the deletion of the account of a user
+func (s ServiceImpl) GetAccountDeletion(userID uint64) (*models.AccountDeletion, error) {
+	deletion, err := s.Repository.GetAccountDeletion(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, httperrors.NotFoundError("account deletion not found")
+	} else if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to get account deletion", err)
+	}
+
+	return deletion, nil
+}
+
+// ProcessAccountDeletions does one batch of the oldest pending deletion and
+// tells whether there is work left. Every batch is saved on its own, so an
+// interrupted deletion continues where it stopped.
+func (s ServiceImpl) ProcessAccountDeletions() (bool, error) {
+	deletion, err := s.Repository.NextAccountDeletion()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	count, err := s.Repository.DeleteUserMessages(deletion, AccountDeletionBatchSize)
+	if err != nil || count > 0 {
+		return err == nil, err
+	}
+
+	if deletion.MessagePolicy == models.DeletionPolicyDelete {
+		deleted, err := s.deleteUserAttachments(deletion.UserID)
+		if err != nil || deleted {
+			return err == nil, err
+		}
+	}
+
+	user, err := s.Repository.GetUser(deletion.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := s.Repository.CompleteAccountDeletion(deletion, time.Now()); err != nil {
+		return false, err
+	}
+
+	if err := s.Lockout.Reset(s.Lockout.UserKey(user.Username)); err != nil {
+		log.Printf("Cannot reset the failed logins of deleted user %d: %v", user.ID, err)
+	}
+
+	// Other deletions may be pending
+	return true, nil
+}
+
+func (s ServiceImpl) scheduleAccountDeletion(userID, requestedBy uint64) (*models.AccountDeletion, error) {
+	deletion, err := s.Repository.CreateAccountDeletion(&models.AccountDeletion{
+		UserID:        userID,
+		RequestedBy:   requestedBy,
+		MessagePolicy: s.DeletionPolicy,
+		Status:        models.AccountDeletionPending,
+		CreatedAt:     time.Now(),
+	})
+	if errors.Is(err, repository.ErrAccountDeleted) {
+		return nil, httperrors.BadRequestError("account was already deleted")
+	} else if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to delete account", err)
+	}
+
+	if s.Hub != nil {
+		s.Hub.Disconnect(userID)
+	}
+	if s.Deletions != nil {
+		s.Deletions.Wake()
+	}
+
+	return deletion, nil
+}
+
+// deleteUserAttachments deletes a batch of the attachments uploaded by the
+// user and tells whether it found any
+func (s ServiceImpl) deleteUserAttachments(userID uint64) (bool, error) {
+	attachments, err := s.Repository.GetUserAttachments(userID, AccountDeletionBatchSize)
+	if err != nil {
+		return false, err
+	}
+
+	for _, attachment := range attachments {
+		// The file goes first, a missing file is not an error if the job is retried
+		if s.Storage != nil {
+			if err := s.Storage.Delete(attachment.StorageKey); err != nil {
+				return false, err
+			}
+		}
+
+		if err := s.Repository.DeleteAttachment(attachment.ID); err != nil {
+			return false, err
+		}
+	}
+
+	return len(attachments) > 0, nil
+}
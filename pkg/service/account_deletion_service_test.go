@@ -0,0 +1,195 @@
+package service
+
+import (
+	"errors"
+	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestServiceImpl_DeleteAccount(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("Passphrase-1"), bcrypt.MinCost)
+	user := &models.User{ID: 1, Username: "testuser", Password: string(hashedPassword)}
+
+	tests := []struct {
+		name          string
+		password      string
+		mockBehavior  func(mockRepo *repository.MockRepository)
+		expectedError error
+	}{
+		{
+			name:     "success",
+			password: "Passphrase-1",
+			mockBehavior: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetUser", uint64(1)).Return(user, nil).Once()
+				mockRepo.On("CreateAccountDeletion", mock.MatchedBy(func(d *models.AccountDeletion) bool {
+					return d.UserID == 1 && d.RequestedBy == 1 && d.Status == models.AccountDeletionPending &&
+						d.MessagePolicy == models.DeletionPolicyDelete
+				})).Return(&models.AccountDeletion{ID: 1, UserID: 1}, nil).Once()
+			},
+		},
+		{
+			name:     "wrong password",
+			password: "Wrong-passphrase",
+			mockBehavior: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetUser", uint64(1)).Return(user, nil).Once()
+			},
+			expectedError: httperrors.BadRequestError("invalid current password"),
+		},
+		{
+			name:     "already deleted",
+			password: "Passphrase-1",
+			mockBehavior: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetUser", uint64(1)).Return(user, nil).Once()
+				mockRepo.On("CreateAccountDeletion", mock.Anything).Return(nil, repository.ErrAccountDeleted).Once()
+			},
+			expectedError: httperrors.BadRequestError("account was already deleted"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			tt.mockBehavior(mockRepo)
+			svc := NewService(mockRepo, WithAccountDeletion(models.DeletionPolicyDelete, nil))
+			sub := svc.Subscribe(1)
+
+			_, err := svc.DeleteAccount(1, tt.password)
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedError == nil, isDisconnected(sub))
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServiceImpl_DeleteUser(t *testing.T) {
+	mockRepo := new(repository.MockRepository)
+	svc := NewService(mockRepo)
+
+	_, err := svc.DeleteUser(1, 1)
+	assert.Equal(t, httperrors.BadRequestError("you cannot delete your own account"), err)
+
+	mockRepo.On("GetUser", uint64(9)).Return(nil, gorm.ErrRecordNotFound).Once()
+	_, err = svc.DeleteUser(1, 9)
+	assert.Equal(t, httperrors.NotFoundError("user not found"), err)
+
+	mockRepo.On("GetUser", uint64(2)).Return(&models.User{ID: 2}, nil).Once()
+	mockRepo.On("CreateAccountDeletion", mock.MatchedBy(func(d *models.AccountDeletion) bool {
+		return d.UserID == 2 && d.RequestedBy == 1 && d.MessagePolicy == models.DeletionPolicyAnonymize
+	})).Return(&models.AccountDeletion{ID: 1, UserID: 2}, nil).Once()
+	deletion, err := svc.DeleteUser(1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), deletion.UserID)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestServiceImpl_GetAccountDeletion(t *testing.T) {
+	mockRepo := new(repository.MockRepository)
+	svc := NewService(mockRepo)
+
+	mockRepo.On("GetAccountDeletion", uint64(2)).Return(&models.AccountDeletion{ID: 1, UserID: 2}, nil).Once()
+	deletion, err := svc.GetAccountDeletion(2)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), deletion.ID)
+
+	mockRepo.On("GetAccountDeletion", uint64(3)).Return(nil, gorm.ErrRecordNotFound).Once()
+	_, err = svc.GetAccountDeletion(3)
+	assert.Equal(t, httperrors.NotFoundError("account deletion not found"), err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestServiceImpl_ProcessAccountDeletions(t *testing.T) {
+	dbErr := errors.New("db error")
+
+	tests := []struct {
+		name          string
+		policy        string
+		mockBehavior  func(mockRepo *repository.MockRepository, deletion *models.AccountDeletion)
+		expectedMore  bool
+		expectedError error
+	}{
+		{
+			name: "nothing pending",
+			mockBehavior: func(mockRepo *repository.MockRepository, deletion *models.AccountDeletion) {
+				mockRepo.On("NextAccountDeletion").Return(nil, gorm.ErrRecordNotFound).Once()
+			},
+		},
+		{
+			name:   "batch of messages",
+			policy: models.DeletionPolicyAnonymize,
+			mockBehavior: func(mockRepo *repository.MockRepository, deletion *models.AccountDeletion) {
+				mockRepo.On("NextAccountDeletion").Return(deletion, nil).Once()
+				mockRepo.On("DeleteUserMessages", deletion, uint64(AccountDeletionBatchSize)).Return(int64(500), nil).Once()
+			},
+			expectedMore: true,
+		},
+		{
+			name:   "batch of attachments",
+			policy: models.DeletionPolicyDelete,
+			mockBehavior: func(mockRepo *repository.MockRepository, deletion *models.AccountDeletion) {
+				mockRepo.On("NextAccountDeletion").Return(deletion, nil).Once()
+				mockRepo.On("DeleteUserMessages", deletion, uint64(AccountDeletionBatchSize)).Return(int64(0), nil).Once()
+				mockRepo.On("GetUserAttachments", uint64(2), uint64(AccountDeletionBatchSize)).
+					Return([]models.Attachment{{ID: 5, OwnerID: 2, StorageKey: "missing"}}, nil).Once()
+				mockRepo.On("DeleteAttachment", uint64(5)).Return(nil).Once()
+			},
+			expectedMore: true,
+		},
+		{
+			name:   "complete",
+			policy: models.DeletionPolicyDelete,
+			mockBehavior: func(mockRepo *repository.MockRepository, deletion *models.AccountDeletion) {
+				mockRepo.On("NextAccountDeletion").Return(deletion, nil).Once()
+				mockRepo.On("DeleteUserMessages", deletion, uint64(AccountDeletionBatchSize)).Return(int64(0), nil).Once()
+				mockRepo.On("GetUserAttachments", uint64(2), uint64(AccountDeletionBatchSize)).Return([]models.Attachment{}, nil).Once()
+				mockRepo.On("GetUser", uint64(2)).Return(&models.User{ID: 2, Username: "peer"}, nil).Once()
+				mockRepo.On("CompleteAccountDeletion", deletion, mock.AnythingOfType("time.Time")).Return(nil).Once()
+			},
+			expectedMore: true,
+		},
+		{
+			name:   "batch fails",
+			policy: models.DeletionPolicyRetain,
+			mockBehavior: func(mockRepo *repository.MockRepository, deletion *models.AccountDeletion) {
+				mockRepo.On("NextAccountDeletion").Return(deletion, nil).Once()
+				mockRepo.On("DeleteUserMessages", deletion, uint64(AccountDeletionBatchSize)).Return(int64(0), dbErr).Once()
+			},
+			expectedError: dbErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deletion := &models.AccountDeletion{ID: 1, UserID: 2, MessagePolicy: tt.policy, Status: models.AccountDeletionPending}
+			mockRepo := new(repository.MockRepository)
+			tt.mockBehavior(mockRepo, deletion)
+			svc := NewService(mockRepo, WithStorage(newTestStorage(t)))
+
+			more, err := svc.ProcessAccountDeletions()
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedMore, more)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServiceImpl_AssignRole_DeletedUser(t *testing.T) {
+	mockRepo := new(repository.MockRepository)
+	svc := NewService(mockRepo)
+
+	deletedAt := time.Now()
+	mockRepo.On("GetUser", uint64(2)).Return(&models.User{ID: 2, DeletedAt: &deletedAt}, nil).Twice()
+
+	_, err := svc.AssignRole(1, 2, models.RoleAgent)
+	assert.Equal(t, httperrors.BadRequestError("user was deleted"), err)
+
+	_, err = svc.SetUserDisabled(1, 2, false)
+	assert.Equal(t, httperrors.BadRequestError("user was deleted"), err)
+	mockRepo.AssertExpectations(t)
+}
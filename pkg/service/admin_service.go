@@ -0,0 +1,85 @@
+package service
+
+import (
+	"time"
+
+	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/models"
+)
+
+// ListUsers returns up to limit users with an id greater than after
+func (s ServiceImpl) ListUsers(after, limit uint64) ([]models.User, error) {
+	users, err := s.Repository.ListUsers(after, limit)
+	if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to list users", err)
+	}
+
+	return users, nil
+}
+
+// SetUserDisabled disables or enables the account of a user. Disabling it
+// also revokes every session so the user is logged out right away.
+func (s ServiceImpl) SetUserDisabled(adminID, userID uint64, disabled bool) (*models.User, error) {
+	if adminID == userID {
+		return nil, httperrors.BadRequestError("you cannot disable your own account")
+	}
+
+	user, err := s.GetUserProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedAt != nil {
+		return nil, httperrors.BadRequestError("user was deleted")
+	}
+
+	var disabledAt *time.Time
+	if disabled {
+		now := time.Now()
+		disabledAt = &now
+	}
+
+	if err := s.Repository.UpdateUserDisabled(userID, disabledAt); err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to update user", err)
+	}
+
+	if disabled {
+		if err := s.Repository.RevokeUserSessions(userID, *disabledAt); err != nil {
+			return nil, httperrors.InternalServerError("an error occurred while trying to update user", err)
+		}
+		if s.Hub != nil {
+			s.Hub.Disconnect(userID)
+		}
+	}
+
+	user.DisabledAt = disabledAt
+
+	return user, nil
+}
+
+// AssignRole changes the role of a user. Their access tokens stop working
+// until they are refreshed with the new role.
+func (s ServiceImpl) AssignRole(adminID, userID uint64, role string) (*models.User, error) {
+	if role != models.RoleUser && role != models.RoleAgent && role != models.RoleAdmin {
+		return nil, httperrors.BadRequestError("invalid role")
+	}
+
+	if adminID == userID {
+		return nil, httperrors.BadRequestError("you cannot change your own role")
+	}
+
+	user, err := s.GetUserProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedAt != nil {
+		return nil, httperrors.BadRequestError("user was deleted")
+	}
+
+	if err := s.Repository.UpdateUserRole(userID, role); err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to update user", err)
+	}
+
+	user.Role = role
+
+	return user, nil
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"errors"
+	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/hub"
+	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestServiceImpl_ListUsers(t *testing.T) {
+	mockRepo := new(repository.MockRepository)
+	svc := NewService(mockRepo)
+
+	mockRepo.On("ListUsers", uint64(0), uint64(10)).Return([]models.User{{ID: 1}, {ID: 2}}, nil).Once()
+	users, err := svc.ListUsers(0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, users, 2)
+
+	dbErr := errors.New("db error")
+	mockRepo.On("ListUsers", uint64(2), uint64(10)).Return(nil, dbErr).Once()
+	_, err = svc.ListUsers(2, 10)
+	assert.Equal(t, httperrors.InternalServerError("an error occurred while trying to list users", dbErr), err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestServiceImpl_SetUserDisabled(t *testing.T) {
+	tests := []struct {
+		name          string
+		userID        uint64
+		disabled      bool
+		setupMocks    func(mockRepo *repository.MockRepository)
+		expectedError error
+	}{
+		{
+			name:     "disable revokes sessions",
+			userID:   2,
+			disabled: true,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetUser", uint64(2)).Return(&models.User{ID: 2}, nil).Once()
+				mockRepo.On("UpdateUserDisabled", uint64(2), mock.MatchedBy(func(at *time.Time) bool { return at != nil })).Return(nil).Once()
+				mockRepo.On("RevokeUserSessions", uint64(2), mock.Anything).Return(nil).Once()
+			},
+		},
+		{
+			name:     "enable",
+			userID:   2,
+			disabled: false,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetUser", uint64(2)).Return(&models.User{ID: 2}, nil).Once()
+				mockRepo.On("UpdateUserDisabled", uint64(2), (*time.Time)(nil)).Return(nil).Once()
+			},
+		},
+		{
+			name:          "own account",
+			userID:        1,
+			disabled:      true,
+			setupMocks:    func(mockRepo *repository.MockRepository) {},
+			expectedError: httperrors.BadRequestError("you cannot disable your own account"),
+		},
+		{
+			name:     "unknown user",
+			userID:   9,
+			disabled: true,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetUser", uint64(9)).Return(nil, gorm.ErrRecordNotFound).Once()
+			},
+			expectedError: httperrors.NotFoundError("user not found"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			tt.setupMocks(mockRepo)
+			svc := NewService(mockRepo)
+			sub := svc.Subscribe(tt.userID)
+
+			user, err := svc.SetUserDisabled(1, tt.userID, tt.disabled)
+			assert.Equal(t, tt.expectedError, err)
+			if tt.expectedError == nil {
+				assert.Equal(t, tt.disabled, user.DisabledAt != nil)
+			}
+			assert.Equal(t, tt.expectedError == nil && tt.disabled, isDisconnected(sub))
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServiceImpl_AssignRole(t *testing.T) {
+	tests := []struct {
+		name          string
+		userID        uint64
+		role          string
+		setupMocks    func(mockRepo *repository.MockRepository)
+		expectedError error
+	}{
+		{
+			name:   "success",
+			userID: 2,
+			role:   models.RoleAgent,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetUser", uint64(2)).Return(&models.User{ID: 2, Role: models.RoleUser}, nil).Once()
+				mockRepo.On("UpdateUserRole", uint64(2), models.RoleAgent).Return(nil).Once()
+			},
+		},
+		{
+			name:          "invalid role",
+			userID:        2,
+			role:          "root",
+			setupMocks:    func(mockRepo *repository.MockRepository) {},
+			expectedError: httperrors.BadRequestError("invalid role"),
+		},
+		{
+			name:          "own role",
+			userID:        1,
+			role:          models.RoleUser,
+			setupMocks:    func(mockRepo *repository.MockRepository) {},
+			expectedError: httperrors.BadRequestError("you cannot change your own role"),
+		},
+		{
+			name:   "unknown user",
+			userID: 9,
+			role:   models.RoleAdmin,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetUser", uint64(9)).Return(nil, gorm.ErrRecordNotFound).Once()
+			},
+			expectedError: httperrors.NotFoundError("user not found"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			tt.setupMocks(mockRepo)
+			svc := NewService(mockRepo)
+
+			user, err := svc.AssignRole(1, tt.userID, tt.role)
+			assert.Equal(t, tt.expectedError, err)
+			if tt.expectedError == nil {
+				assert.Equal(t, tt.role, user.Role)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+// isDisconnected tells whether the hub closed the subscription because the
+// user lost access
+func isDisconnected(sub *hub.Subscription) bool {
+	select {
+	case _, ok := <-sub.Messages:
+		return !ok && errors.Is(sub.Err(), hub.ErrDisconnected)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/helpers"
+	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/storage"
+	"gorm.io/gorm"
+)
+
+// UploadAttachment stores a file uploaded by the user. The mime type is
+// sniffed from the content, whatever the client claims it is.
+func (s ServiceImpl) UploadAttachment(ownerID uint64, fileName string, r io.Reader) (*models.Attachment, error) {
+	if s.Storage == nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to save attachment", errors.New("attachment storage is not configured"))
+	}
+
+	br := bufio.NewReaderSize(r, 512)
+	head, err := br.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, httperrors.BadRequestError("invalid attachment")
+	}
+	if len(head) == 0 {
+		return nil, httperrors.BadRequestError("empty attachment")
+	}
+
+	mimeType := strings.Split(http.DetectContentType(head), ";")[0]
+	if !helpers.ImageMimeTypes[mimeType] && !helpers.VideoMimeTypes[mimeType] {
+		return nil, httperrors.BadRequestError("unsupported attachment type")
+	}
+
+	key, err := helpers.RandomToken(16)
+	if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to save attachment", err)
+	}
+
+	hash := sha256.New()
+	size, err := s.Storage.Save(key, io.TeeReader(io.LimitReader(br, helpers.MaxAttachmentSize+1), hash))
+	if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to save attachment", err)
+	}
+	if size > helpers.MaxAttachmentSize {
+		_ = s.Storage.Delete(key)
+		return nil, httperrors.RequestEntityTooLargeError("attachment is too large")
+	}
+
+	attachment, err := s.Repository.SaveAttachment(&models.Attachment{
+		OwnerID:    ownerID,
+		FileName:   filepath.Base(fileName),
+		MimeType:   mimeType,
+		Size:       size,
+		SHA256:     hex.EncodeToString(hash.Sum(nil)),
+		StorageKey: key,
+	})
+	if err != nil {
+		_ = s.Storage.Delete(key)
+		return nil, httperrors.InternalServerError("an error occurred while trying to save attachment", err)
+	}
+
+	return attachment, nil
+}
+
+// GetAttachment opens an attachment for its owner or for the sender and
+// recipients of a message referencing it
+func (s ServiceImpl) GetAttachment(userID, attachmentID uint64) (*models.Attachment, io.ReadCloser, error) {
+	attachment, err := s.getAttachment(attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if attachment.OwnerID != userID {
+		allowed, err := s.Repository.IsAttachmentRecipient(attachmentID, userID)
+		if err != nil {
+			return nil, nil, httperrors.InternalServerError("an error occurred while trying to get attachment", err)
+		}
+		if !allowed {
+			return nil, nil, httperrors.ForbiddenError("you are not allowed to see this attachment")
+		}
+	}
+
+	if s.Storage == nil {
+		return nil, nil, httperrors.InternalServerError("an error occurred while trying to get attachment", errors.New("attachment storage is not configured"))
+	}
+
+	content, err := s.Storage.Open(attachment.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, httperrors.NotFoundError("attachment not found")
+	} else if err != nil {
+		return nil, nil, httperrors.InternalServerError("an error occurred while trying to get attachment", err)
+	}
+
+	return attachment, content, nil
+}
+
+func (s ServiceImpl) getAttachment(attachmentID uint64) (*models.Attachment, error) {
+	attachment, err := s.Repository.GetAttachment(attachmentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, httperrors.NotFoundError("attachment not found")
+	} else if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to get attachment", err)
+	}
+
+	return attachment, nil
+}
+
+// attachedContent validates an image or video referencing an attachment of the sender
+func (s ServiceImpl) attachedContent(sender uint64, content *models.Content) (models.Content, error) {
+	if content.URL != "" {
+		return models.Content{}, httperrors.BadRequestError("a message cannot have both an url and an attachment")
+	}
+
+	attachment, err := s.getAttachment(content.AttachmentID)
+	if err != nil {
+		return models.Content{}, err
+	}
+	if attachment.OwnerID != sender {
+		return models.Content{}, httperrors.ForbiddenError("you are not allowed to use this attachment")
+	}
+
+	if content.Type == models.ContentTypeImage {
+		if !helpers.ImageMimeTypes[attachment.MimeType] {
+			return models.Content{}, httperrors.BadRequestError("attachment is not an image")
+		}
+		if content.Width == 0 || content.Height == 0 {
+			return models.Content{}, httperrors.BadRequestError("invalid image dimensions")
+		}
+
+		return models.Content{
+			Type:         content.Type,
+			AttachmentID: attachment.ID,
+			Width:        content.Width,
+			Height:       content.Height,
+			MimeType:     attachment.MimeType,
+		}, nil
+	}
+
+	if !helpers.VideoMimeTypes[attachment.MimeType] {
+		return models.Content{}, httperrors.BadRequestError("attachment is not a video")
+	}
+	if content.Duration == 0 {
+		return models.Content{}, httperrors.BadRequestError("invalid video duration")
+	}
+
+	return models.Content{
+		Type:         content.Type,
+		AttachmentID: attachment.ID,
+		Duration:     content.Duration,
+		Source:       helpers.AttachmentVideoSource,
+	}, nil
+}
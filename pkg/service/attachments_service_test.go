@@ -0,0 +1,222 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/helpers"
+	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/repository"
+	"github.com/challenge/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+func newTestStorage(t *testing.T) *storage.LocalStorage {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	assert.NoError(t, err)
+	return store
+}
+
+func TestUploadAttachment(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       []byte
+		setupMocks    func(mockRepo *repository.MockRepository)
+		expectedError error
+	}{
+		{
+			name:    "success",
+			content: append(pngHeader, []byte("image data")...),
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("SaveAttachment", mock.MatchedBy(func(a *models.Attachment) bool {
+					return a.OwnerID == 1 && a.FileName == "cat.png" && a.MimeType == "image/png" &&
+						a.Size == 18 && len(a.SHA256) == 64 && a.StorageKey != ""
+				})).Return(&models.Attachment{ID: 1}, nil).Once()
+			},
+		},
+		{
+			name:          "unsupported type",
+			content:       []byte("<html><body>hello</body></html>"),
+			setupMocks:    func(mockRepo *repository.MockRepository) {},
+			expectedError: httperrors.BadRequestError("unsupported attachment type"),
+		},
+		{
+			name:          "empty",
+			content:       []byte{},
+			setupMocks:    func(mockRepo *repository.MockRepository) {},
+			expectedError: httperrors.BadRequestError("empty attachment"),
+		},
+		{
+			name:          "too large",
+			content:       append(pngHeader, bytes.Repeat([]byte{0}, helpers.MaxAttachmentSize)...),
+			setupMocks:    func(mockRepo *repository.MockRepository) {},
+			expectedError: httperrors.RequestEntityTooLargeError("attachment is too large"),
+		},
+		{
+			name:    "repository error",
+			content: append(pngHeader, []byte("image data")...),
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("SaveAttachment", mock.Anything).Return(nil, errors.New("repository error")).Once()
+			},
+			expectedError: httperrors.InternalServerError("an error occurred while trying to save attachment", errors.New("repository error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			tt.setupMocks(mockRepo)
+
+			service := NewService(mockRepo, WithStorage(newTestStorage(t)))
+			_, err := service.UploadAttachment(1, "../cat.png", bytes.NewReader(tt.content))
+
+			assert.Equal(t, tt.expectedError, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUploadAttachment_NoStorage(t *testing.T) {
+	service := NewService(new(repository.MockRepository))
+	_, err := service.UploadAttachment(1, "cat.png", bytes.NewReader(pngHeader))
+
+	assert.Equal(t, httperrors.InternalServerError("an error occurred while trying to save attachment", errors.New("attachment storage is not configured")), err)
+}
+
+func TestGetAttachment(t *testing.T) {
+	store := newTestStorage(t)
+	_, err := store.Save("key", strings.NewReader("image data"))
+	assert.NoError(t, err)
+	attachment := &models.Attachment{ID: 3, OwnerID: 1, StorageKey: "key"}
+
+	tests := []struct {
+		name          string
+		userID        uint64
+		setupMocks    func(mockRepo *repository.MockRepository)
+		expectedError error
+	}{
+		{
+			name:   "owner",
+			userID: 1,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetAttachment", uint64(3)).Return(attachment, nil).Once()
+			},
+		},
+		{
+			name:   "recipient",
+			userID: 2,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetAttachment", uint64(3)).Return(attachment, nil).Once()
+				mockRepo.On("IsAttachmentRecipient", uint64(3), uint64(2)).Return(true, nil).Once()
+			},
+		},
+		{
+			name:   "stranger",
+			userID: 4,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetAttachment", uint64(3)).Return(attachment, nil).Once()
+				mockRepo.On("IsAttachmentRecipient", uint64(3), uint64(4)).Return(false, nil).Once()
+			},
+			expectedError: httperrors.ForbiddenError("you are not allowed to see this attachment"),
+		},
+		{
+			name:   "not found",
+			userID: 1,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetAttachment", uint64(3)).Return(nil, gorm.ErrRecordNotFound).Once()
+			},
+			expectedError: httperrors.NotFoundError("attachment not found"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			tt.setupMocks(mockRepo)
+
+			service := NewService(mockRepo, WithStorage(store))
+			_, content, err := service.GetAttachment(tt.userID, 3)
+
+			assert.Equal(t, tt.expectedError, err)
+			if err == nil {
+				data, _ := io.ReadAll(content)
+				_ = content.Close()
+				assert.Equal(t, "image data", string(data))
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSendMessage_AttachmentContent(t *testing.T) {
+	image := &models.Attachment{ID: 3, OwnerID: 1, MimeType: "image/png"}
+	video := &models.Attachment{ID: 4, OwnerID: 1, MimeType: "video/mp4"}
+	foreign := &models.Attachment{ID: 5, OwnerID: 2, MimeType: "image/png"}
+
+	tests := []struct {
+		name            string
+		content         *models.Content
+		attachment      *models.Attachment
+		expectedContent models.Content
+		expectedError   error
+	}{
+		{
+			name:            "image",
+			content:         &models.Content{Type: "image", AttachmentID: 3, Width: 640, Height: 480, MimeType: "image/gif"},
+			attachment:      image,
+			expectedContent: models.Content{Type: "image", AttachmentID: 3, Width: 640, Height: 480, MimeType: "image/png"},
+		},
+		{
+			name:            "video",
+			content:         &models.Content{Type: "video", AttachmentID: 4, Duration: 30, Source: "youtube"},
+			attachment:      video,
+			expectedContent: models.Content{Type: "video", AttachmentID: 4, Duration: 30, Source: "attachment"},
+		},
+		{
+			name:          "image with a video attachment",
+			content:       &models.Content{Type: "image", AttachmentID: 4, Width: 640, Height: 480},
+			attachment:    video,
+			expectedError: httperrors.BadRequestError("attachment is not an image"),
+		},
+		{
+			name:          "attachment of another user",
+			content:       &models.Content{Type: "image", AttachmentID: 5, Width: 640, Height: 480},
+			attachment:    foreign,
+			expectedError: httperrors.ForbiddenError("you are not allowed to use this attachment"),
+		},
+		{
+			name:          "url and attachment",
+			content:       &models.Content{Type: "image", URL: "https://example.com/a.png", AttachmentID: 3},
+			expectedError: httperrors.BadRequestError("a message cannot have both an url and an attachment"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			expectRecipient(mockRepo, 1, 2)
+			if tt.attachment != nil {
+				mockRepo.On("GetAttachment", tt.attachment.ID).Return(tt.attachment, nil).Once()
+			}
+			if tt.expectedError == nil {
+				mockRepo.On("SaveMessage", mock.MatchedBy(func(m *models.Message) bool {
+					return m.Content == tt.expectedContent
+				})).Return(&models.Message{Id: 1, RecipientID: 2}, nil).Once()
+			}
+
+			service := NewService(mockRepo)
+			_, err := service.SendMessage(1, 2, tt.content)
+
+			assert.Equal(t, tt.expectedError, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
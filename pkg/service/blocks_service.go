@@ -0,0 +1,58 @@
+package service
+
+import (
+	"time"
+
+	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/models"
+)
+
+// BlockUser adds a user to the block list of another one. Blocked users can
+// no longer message the user and their messages are hidden from them.
+func (s ServiceImpl) BlockUser(userID, blockedID uint64) (*models.Block, error) {
+	if userID == blockedID {
+		return nil, httperrors.BadRequestError("you cannot block yourself")
+	}
+
+	blocked, err := s.GetUserProfile(blockedID)
+	if err != nil {
+		return nil, err
+	}
+	if blocked.DeletedAt != nil {
+		return nil, httperrors.NotFoundError("user not found")
+	}
+
+	block := &models.Block{UserID: userID, BlockedID: blockedID, CreatedAt: time.Now()}
+	if err := s.Repository.BlockUser(block.UserID, block.BlockedID, block.CreatedAt); err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to block user", err)
+	}
+
+	return block, nil
+}
+
+func (s ServiceImpl) UnblockUser(userID, blockedID uint64) error {
+	if err := s.Repository.UnblockUser(userID, blockedID); err != nil {
+		return httperrors.InternalServerError("an error occurred while trying to unblock user", err)
+	}
+
+	return nil
+}
+
+func (s ServiceImpl) GetBlockedUsers(userID uint64) ([]models.Block, error) {
+	blocks, err := s.Repository.GetBlockedUsers(userID)
+	if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to get blocked users", err)
+	}
+
+	return blocks, nil
+}
+
+// SetContactsOnly changes whether the user only accepts messages from users
+// they have messaged before
+func (s ServiceImpl) SetContactsOnly(userID uint64, contactsOnly bool) (*models.User, error) {
+	if err := s.Repository.UpdateUserContactsOnly(userID, contactsOnly); err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to update privacy settings", err)
+	}
+
+	return s.GetUserProfile(userID)
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"errors"
+	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestServiceImpl_BlockUser(t *testing.T) {
+	deletedAt := time.Now()
+	tests := []struct {
+		name          string
+		blockedID     uint64
+		setupMocks    func(mockRepo *repository.MockRepository)
+		expectedError error
+	}{
+		{
+			name:      "success",
+			blockedID: 2,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetUser", uint64(2)).Return(&models.User{ID: 2}, nil).Once()
+				mockRepo.On("BlockUser", uint64(1), uint64(2), mock.Anything).Return(nil).Once()
+			},
+		},
+		{
+			name:          "yourself",
+			blockedID:     1,
+			setupMocks:    func(mockRepo *repository.MockRepository) {},
+			expectedError: httperrors.BadRequestError("you cannot block yourself"),
+		},
+		{
+			name:      "unknown user",
+			blockedID: 9,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetUser", uint64(9)).Return(nil, gorm.ErrRecordNotFound).Once()
+			},
+			expectedError: httperrors.NotFoundError("user not found"),
+		},
+		{
+			name:      "deleted user",
+			blockedID: 3,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetUser", uint64(3)).Return(&models.User{ID: 3, DeletedAt: &deletedAt}, nil).Once()
+			},
+			expectedError: httperrors.NotFoundError("user not found"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			tt.setupMocks(mockRepo)
+			svc := NewService(mockRepo)
+
+			block, err := svc.BlockUser(1, tt.blockedID)
+			assert.Equal(t, tt.expectedError, err)
+			if tt.expectedError == nil {
+				assert.Equal(t, uint64(1), block.UserID)
+				assert.Equal(t, tt.blockedID, block.BlockedID)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServiceImpl_UnblockUser(t *testing.T) {
+	mockRepo := new(repository.MockRepository)
+	svc := NewService(mockRepo)
+
+	mockRepo.On("UnblockUser", uint64(1), uint64(2)).Return(nil).Once()
+	assert.NoError(t, svc.UnblockUser(1, 2))
+
+	dbErr := errors.New("db error")
+	mockRepo.On("UnblockUser", uint64(1), uint64(3)).Return(dbErr).Once()
+	assert.Equal(t, httperrors.InternalServerError("an error occurred while trying to unblock user", dbErr), svc.UnblockUser(1, 3))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestServiceImpl_GetBlockedUsers(t *testing.T) {
+	mockRepo := new(repository.MockRepository)
+	svc := NewService(mockRepo)
+
+	mockRepo.On("GetBlockedUsers", uint64(1)).Return([]models.Block{{UserID: 1, BlockedID: 2}}, nil).Once()
+	blocks, err := svc.GetBlockedUsers(1)
+	assert.NoError(t, err)
+	assert.Len(t, blocks, 1)
+
+	dbErr := errors.New("db error")
+	mockRepo.On("GetBlockedUsers", uint64(2)).Return(nil, dbErr).Once()
+	_, err = svc.GetBlockedUsers(2)
+	assert.Equal(t, httperrors.InternalServerError("an error occurred while trying to get blocked users", dbErr), err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestServiceImpl_SetContactsOnly(t *testing.T) {
+	mockRepo := new(repository.MockRepository)
+	svc := NewService(mockRepo)
+
+	mockRepo.On("UpdateUserContactsOnly", uint64(1), true).Return(nil).Once()
+	mockRepo.On("GetUser", uint64(1)).Return(&models.User{ID: 1, ContactsOnly: true}, nil).Once()
+	user, err := svc.SetContactsOnly(1, true)
+	assert.NoError(t, err)
+	assert.True(t, user.ContactsOnly)
+
+	dbErr := errors.New("db error")
+	mockRepo.On("UpdateUserContactsOnly", uint64(1), false).Return(dbErr).Once()
+	_, err = svc.SetContactsOnly(1, false)
+	assert.Equal(t, httperrors.InternalServerError("an error occurred while trying to update privacy settings", dbErr), err)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,338 @@
+package service
+
+import (
+	"errors"
+	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/repository"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+func (s ServiceImpl) CreateConversation(ownerID uint64, name string, memberIDs []uint64) (*models.Conversation, error) {
+	if name == "" {
+		return nil, httperrors.BadRequestError("invalid conversation name")
+	}
+
+	conversation := &models.Conversation{
+		Name:      name,
+		CreatedBy: ownerID,
+		Members: []models.ConversationMember{
+			{UserID: ownerID, Role: models.ConversationRoleOwner},
+		},
+	}
+
+	added := map[uint64]bool{ownerID: true}
+	for _, id := range memberIDs {
+		if added[id] {
+			continue
+		}
+
+		member, err := s.GetUser(id)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkConversationMember(ownerID, member); err != nil {
+			return nil, err
+		}
+
+		conversation.Members = append(conversation.Members, models.ConversationMember{
+			UserID: id,
+			Role:   models.ConversationRoleMember,
+		})
+		added[id] = true
+	}
+
+	conversation, err := s.Repository.CreateConversation(conversation)
+	if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to create conversation", err)
+	}
+
+	return conversation, nil
+}
+
+// GetConversation returns a conversation and its members if the user belongs to it
+func (s ServiceImpl) GetConversation(userID, conversationID uint64) (*models.Conversation, error) {
+	conversation, err := s.getConversation(conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if findMember(conversation, userID) == nil {
+		return nil, httperrors.ForbiddenError("you are not a member of this conversation")
+	}
+
+	return conversation, nil
+}
+
+// AddConversationMember adds a user to a conversation. Only owners and admins
+// can add members and only owners can add admins.
+func (s ServiceImpl) AddConversationMember(requester, conversationID, userID uint64, role string) (*models.ConversationMember, error) {
+	if role == "" {
+		role = models.ConversationRoleMember
+	}
+	if role != models.ConversationRoleMember && role != models.ConversationRoleAdmin {
+		return nil, httperrors.BadRequestError("invalid role")
+	}
+
+	conversation, err := s.getConversation(conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	manager, err := conversationManager(conversation, requester)
+	if err != nil {
+		return nil, err
+	}
+
+	if role == models.ConversationRoleAdmin && manager.Role != models.ConversationRoleOwner {
+		return nil, httperrors.ForbiddenError("only the owner can add admins")
+	}
+
+	if findMember(conversation, userID) != nil {
+		return nil, httperrors.BadRequestError("user is already a member of this conversation")
+	}
+
+	user, err := s.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkConversationMember(requester, user); err != nil {
+		return nil, err
+	}
+
+	member, err := s.Repository.SaveConversationMember(&models.ConversationMember{
+		ConversationID: conversationID,
+		UserID:         userID,
+		Role:           role,
+	})
+	if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to add member", err)
+	}
+
+	return member, nil
+}
+
+// RemoveConversationMember removes a user from a conversation. The owner can
+// remove anyone else, admins can only remove regular members.
+func (s ServiceImpl) RemoveConversationMember(requester, conversationID, userID uint64) error {
+	if requester == userID {
+		return s.LeaveConversation(userID, conversationID)
+	}
+
+	conversation, err := s.getConversation(conversationID)
+	if err != nil {
+		return err
+	}
+
+	manager, err := conversationManager(conversation, requester)
+	if err != nil {
+		return err
+	}
+
+	member := findMember(conversation, userID)
+	if member == nil {
+		return httperrors.NotFoundError("user is not a member of this conversation")
+	}
+
+	if member.Role == models.ConversationRoleOwner {
+		return httperrors.ForbiddenError("the owner cannot be removed")
+	}
+	if member.Role == models.ConversationRoleAdmin && manager.Role != models.ConversationRoleOwner {
+		return httperrors.ForbiddenError("only the owner can remove admins")
+	}
+
+	if err := s.Repository.DeleteConversationMember(conversationID, userID); err != nil {
+		return httperrors.InternalServerError("an error occurred while trying to remove member", err)
+	}
+
+	return nil
+}
+
+// LeaveConversation removes the user from a conversation. When the owner
+// leaves, ownership passes to the oldest admin or, if none, the oldest member.
+func (s ServiceImpl) LeaveConversation(userID, conversationID uint64) error {
+	conversation, err := s.getConversation(conversationID)
+	if err != nil {
+		return err
+	}
+
+	member := findMember(conversation, userID)
+	if member == nil {
+		return httperrors.ForbiddenError("you are not a member of this conversation")
+	}
+
+	if err := s.Repository.DeleteConversationMember(conversationID, userID); err != nil {
+		return httperrors.InternalServerError("an error occurred while trying to leave conversation", err)
+	}
+
+	if member.Role != models.ConversationRoleOwner {
+		return nil
+	}
+
+	var successor *models.ConversationMember
+	for i, m := range conversation.Members {
+		if m.UserID == userID {
+			continue
+		}
+		if m.Role == models.ConversationRoleAdmin {
+			successor = &conversation.Members[i]
+			break
+		}
+		if successor == nil {
+			successor = &conversation.Members[i]
+		}
+	}
+
+	if successor != nil {
+		successor.Role = models.ConversationRoleOwner
+		if _, err := s.Repository.SaveConversationMember(successor); err != nil {
+			return httperrors.InternalServerError("an error occurred while trying to leave conversation", err)
+		}
+	}
+
+	return nil
+}
+
+func (s ServiceImpl) SendConversationMessage(sender, conversationID uint64, content *models.Content) (*models.Message, error) {
+	validContent, err := s.validateContent(sender, content)
+	if err != nil {
+		return nil, err
+	}
+
+	conversation, err := s.getConversation(conversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &models.Message{
+		SenderID:       sender,
+		ConversationID: conversationID,
+		Content:        validContent,
+		Timestamp:      time.Now().String(),
+	}
+
+	message, err = s.Repository.SaveConversationMessage(message)
+	if errors.Is(err, repository.ErrNotConversationMember) {
+		return nil, httperrors.ForbiddenError("you are not a member of this conversation")
+	} else if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to save message", err)
+	}
+
+	if s.Hub != nil {
+		s.publishConversationMessage(conversation, message)
+	}
+
+	return message, nil
+}
+
+// publishConversationMessage pushes the message to the members of the
+// conversation, except its sender and the members who blocked them
+func (s ServiceImpl) publishConversationMessage(conversation *models.Conversation, message *models.Message) {
+	blockers, err := s.Repository.GetBlockerIDs(message.SenderID)
+	if err != nil {
+		// The message is saved, members get it with the next page they load
+		log.Printf("could not get the users who blocked %d: %v", message.SenderID, err)
+		return
+	}
+
+	blockedBy := make(map[uint64]bool, len(blockers))
+	for _, id := range blockers {
+		blockedBy[id] = true
+	}
+
+	for _, member := range conversation.Members {
+		if member.UserID != message.SenderID && !blockedBy[member.UserID] {
+			s.Hub.Publish(member.UserID, *message)
+		}
+	}
+}
+
+func (s ServiceImpl) GetConversationMessages(userID, conversationID, start, limit uint64) ([]models.Message, error) {
+	messages, err := s.Repository.GetConversationMessages(conversationID, userID, start, limit)
+	if errors.Is(err, repository.ErrNotConversationMember) {
+		return nil, httperrors.ForbiddenError("you are not a member of this conversation")
+	} else if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to get messages", err)
+	}
+
+	return messages, nil
+}
+
+// checkConversationMember applies the rules of direct messages to adding a
+// user to a conversation, as members can message each other there: nobody
+// can add a user who blocked them, and customers cannot add other customers
+// under the support policy nor users who only accept messages from contacts.
+func (s ServiceImpl) checkConversationMember(adderID uint64, member *models.User) error {
+	blocked, err := s.Repository.IsBlocked(member.ID, adderID)
+	if err != nil {
+		return httperrors.InternalServerError("an error occurred while trying to add member", err)
+	}
+	if blocked {
+		return httperrors.ForbiddenError("you cannot add this user")
+	}
+
+	if s.Messaging != MessagingPolicySupport && !member.ContactsOnly {
+		return nil
+	}
+
+	adder, err := s.GetUser(adderID)
+	if err != nil {
+		return err
+	}
+	// agents and admins reach customers regardless of their privacy settings
+	if adder.Role != models.RoleUser {
+		return nil
+	}
+
+	if s.Messaging == MessagingPolicySupport && member.Role == models.RoleUser {
+		return httperrors.ForbiddenError("customers can only message agents")
+	}
+
+	if member.ContactsOnly {
+		contact, err := s.Repository.HasSentMessage(member.ID, adderID)
+		if err != nil {
+			return httperrors.InternalServerError("an error occurred while trying to add member", err)
+		}
+		if !contact {
+			return httperrors.ForbiddenError("this user only accepts messages from contacts")
+		}
+	}
+
+	return nil
+}
+
+func (s ServiceImpl) getConversation(conversationID uint64) (*models.Conversation, error) {
+	conversation, err := s.Repository.GetConversation(conversationID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, httperrors.NotFoundError("conversation not found")
+	} else if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to get conversation", err)
+	}
+
+	return conversation, nil
+}
+
+func conversationManager(conversation *models.Conversation, userID uint64) (*models.ConversationMember, error) {
+	member := findMember(conversation, userID)
+	if member == nil {
+		return nil, httperrors.ForbiddenError("you are not a member of this conversation")
+	}
+
+	if member.Role != models.ConversationRoleOwner && member.Role != models.ConversationRoleAdmin {
+		return nil, httperrors.ForbiddenError("only owners and admins can manage members")
+	}
+
+	return member, nil
+}
+
+func findMember(conversation *models.Conversation, userID uint64) *models.ConversationMember {
+	for i := range conversation.Members {
+		if conversation.Members[i].UserID == userID {
+			return &conversation.Members[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,474 @@
+package service
+
+import (
+	"errors"
+	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func testConversation() *models.Conversation {
+	return &models.Conversation{
+		ID:        1,
+		Name:      "team",
+		CreatedBy: 1,
+		Members: []models.ConversationMember{
+			{ConversationID: 1, UserID: 1, Role: models.ConversationRoleOwner},
+			{ConversationID: 1, UserID: 2, Role: models.ConversationRoleAdmin},
+			{ConversationID: 1, UserID: 3, Role: models.ConversationRoleMember},
+		},
+	}
+}
+
+func TestCreateConversation(t *testing.T) {
+	tests := []struct {
+		name          string
+		convName      string
+		members       []uint64
+		setupMocks    func(mockRepo *repository.MockRepository)
+		expectedError error
+	}{
+		{
+			name:     "success",
+			convName: "team",
+			members:  []uint64{2, 2, 1},
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetUser", uint64(2)).Return(&models.User{ID: 2}, nil).Once()
+				mockRepo.On("IsBlocked", uint64(2), uint64(1)).Return(false, nil).Once()
+				mockRepo.On("CreateConversation", mock.MatchedBy(func(c *models.Conversation) bool {
+					return c.Name == "team" && len(c.Members) == 2 &&
+						c.Members[0].Role == models.ConversationRoleOwner &&
+						c.Members[1].UserID == 2
+				})).Return(testConversation(), nil).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name:          "invalid name",
+			convName:      "",
+			setupMocks:    func(mockRepo *repository.MockRepository) {},
+			expectedError: httperrors.BadRequestError("invalid conversation name"),
+		},
+		{
+			name:     "member not found",
+			convName: "team",
+			members:  []uint64{9},
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetUser", uint64(9)).Return(nil, gorm.ErrRecordNotFound).Once()
+			},
+			expectedError: httperrors.BadRequestError("user not found"),
+		},
+		{
+			name:     "member blocked the owner",
+			convName: "team",
+			members:  []uint64{2},
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetUser", uint64(2)).Return(&models.User{ID: 2}, nil).Once()
+				mockRepo.On("IsBlocked", uint64(2), uint64(1)).Return(true, nil).Once()
+			},
+			expectedError: httperrors.ForbiddenError("you cannot add this user"),
+		},
+		{
+			name:     "member only accepts contacts",
+			convName: "team",
+			members:  []uint64{2},
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetUser", uint64(2)).Return(&models.User{ID: 2, Role: models.RoleUser, ContactsOnly: true}, nil).Once()
+				mockRepo.On("IsBlocked", uint64(2), uint64(1)).Return(false, nil).Once()
+				mockRepo.On("GetUser", uint64(1)).Return(&models.User{ID: 1, Role: models.RoleUser}, nil).Once()
+				mockRepo.On("HasSentMessage", uint64(2), uint64(1)).Return(false, nil).Once()
+			},
+			expectedError: httperrors.ForbiddenError("this user only accepts messages from contacts"),
+		},
+		{
+			name:     "repository error",
+			convName: "team",
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("CreateConversation", mock.Anything).Return(nil, errors.New("repository error")).Once()
+			},
+			expectedError: httperrors.InternalServerError("an error occurred while trying to create conversation", errors.New("repository error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			tt.setupMocks(mockRepo)
+
+			service := NewService(mockRepo)
+			_, err := service.CreateConversation(1, tt.convName, tt.members)
+
+			assert.Equal(t, tt.expectedError, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCreateConversation_SupportPolicy(t *testing.T) {
+	users := map[uint64]*models.User{
+		1: {ID: 1, Role: models.RoleUser},
+		2: {ID: 2, Role: models.RoleUser},
+		3: {ID: 3, Role: models.RoleAgent},
+	}
+
+	tests := []struct {
+		name          string
+		owner         uint64
+		members       []uint64
+		expectedError error
+	}{
+		{name: "customer adds agent", owner: 1, members: []uint64{3}},
+		{name: "agent adds customers", owner: 3, members: []uint64{1, 2}},
+		{name: "customer adds customer", owner: 1, members: []uint64{3, 2}, expectedError: httperrors.ForbiddenError("customers can only message agents")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			for id, user := range users {
+				mockRepo.On("GetUser", id).Return(user, nil).Maybe()
+			}
+			mockRepo.On("IsBlocked", mock.Anything, mock.Anything).Return(false, nil).Maybe()
+			mockRepo.On("CreateConversation", mock.Anything).Return(testConversation(), nil).Maybe()
+
+			service := NewService(mockRepo, WithMessagingPolicy(MessagingPolicySupport))
+			_, err := service.CreateConversation(tt.owner, "support", tt.members)
+
+			assert.Equal(t, tt.expectedError, err)
+			if tt.expectedError != nil {
+				mockRepo.AssertNotCalled(t, "CreateConversation", mock.Anything)
+			}
+		})
+	}
+}
+
+func TestAddConversationMember_SupportPolicy(t *testing.T) {
+	conversation := &models.Conversation{
+		ID: 1,
+		Members: []models.ConversationMember{
+			{ConversationID: 1, UserID: 1, Role: models.ConversationRoleOwner},
+			{ConversationID: 1, UserID: 3, Role: models.ConversationRoleAdmin},
+		},
+	}
+
+	mockRepo := new(repository.MockRepository)
+	mockRepo.On("GetConversation", uint64(1)).Return(conversation, nil)
+	mockRepo.On("GetUser", uint64(1)).Return(&models.User{ID: 1, Role: models.RoleUser}, nil)
+	mockRepo.On("GetUser", uint64(2)).Return(&models.User{ID: 2, Role: models.RoleUser}, nil)
+	mockRepo.On("GetUser", uint64(3)).Return(&models.User{ID: 3, Role: models.RoleAgent}, nil)
+	mockRepo.On("IsBlocked", uint64(2), mock.Anything).Return(false, nil)
+	mockRepo.On("SaveConversationMember", mock.Anything).Return(&models.ConversationMember{}, nil).Once()
+
+	service := NewService(mockRepo, WithMessagingPolicy(MessagingPolicySupport))
+
+	_, err := service.AddConversationMember(1, 1, 2, "")
+	assert.Equal(t, httperrors.ForbiddenError("customers can only message agents"), err)
+
+	_, err = service.AddConversationMember(3, 1, 2, "")
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAddConversationMember(t *testing.T) {
+	tests := []struct {
+		name          string
+		requester     uint64
+		userID        uint64
+		role          string
+		setupMocks    func(mockRepo *repository.MockRepository)
+		expectedError error
+	}{
+		{
+			name:      "success",
+			requester: 2,
+			userID:    4,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetConversation", uint64(1)).Return(testConversation(), nil).Once()
+				mockRepo.On("GetUser", uint64(4)).Return(&models.User{ID: 4}, nil).Once()
+				mockRepo.On("IsBlocked", uint64(4), uint64(2)).Return(false, nil).Once()
+				mockRepo.On("SaveConversationMember", &models.ConversationMember{
+					ConversationID: 1,
+					UserID:         4,
+					Role:           models.ConversationRoleMember,
+				}).Return(&models.ConversationMember{}, nil).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name:          "invalid role",
+			requester:     1,
+			userID:        4,
+			role:          models.ConversationRoleOwner,
+			setupMocks:    func(mockRepo *repository.MockRepository) {},
+			expectedError: httperrors.BadRequestError("invalid role"),
+		},
+		{
+			name:      "conversation not found",
+			requester: 1,
+			userID:    4,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetConversation", uint64(1)).Return(nil, gorm.ErrRecordNotFound).Once()
+			},
+			expectedError: httperrors.NotFoundError("conversation not found"),
+		},
+		{
+			name:      "requester is a regular member",
+			requester: 3,
+			userID:    4,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetConversation", uint64(1)).Return(testConversation(), nil).Once()
+			},
+			expectedError: httperrors.ForbiddenError("only owners and admins can manage members"),
+		},
+		{
+			name:      "admin cannot add admins",
+			requester: 2,
+			userID:    4,
+			role:      models.ConversationRoleAdmin,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetConversation", uint64(1)).Return(testConversation(), nil).Once()
+			},
+			expectedError: httperrors.ForbiddenError("only the owner can add admins"),
+		},
+		{
+			name:      "already a member",
+			requester: 1,
+			userID:    3,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetConversation", uint64(1)).Return(testConversation(), nil).Once()
+			},
+			expectedError: httperrors.BadRequestError("user is already a member of this conversation"),
+		},
+		{
+			name:      "user blocked the requester",
+			requester: 2,
+			userID:    4,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetConversation", uint64(1)).Return(testConversation(), nil).Once()
+				mockRepo.On("GetUser", uint64(4)).Return(&models.User{ID: 4}, nil).Once()
+				mockRepo.On("IsBlocked", uint64(4), uint64(2)).Return(true, nil).Once()
+			},
+			expectedError: httperrors.ForbiddenError("you cannot add this user"),
+		},
+		{
+			name:      "contact of a user who only accepts contacts",
+			requester: 2,
+			userID:    4,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetConversation", uint64(1)).Return(testConversation(), nil).Once()
+				mockRepo.On("GetUser", uint64(4)).Return(&models.User{ID: 4, Role: models.RoleUser, ContactsOnly: true}, nil).Once()
+				mockRepo.On("IsBlocked", uint64(4), uint64(2)).Return(false, nil).Once()
+				mockRepo.On("GetUser", uint64(2)).Return(&models.User{ID: 2, Role: models.RoleUser}, nil).Once()
+				mockRepo.On("HasSentMessage", uint64(4), uint64(2)).Return(true, nil).Once()
+				mockRepo.On("SaveConversationMember", mock.Anything).Return(&models.ConversationMember{}, nil).Once()
+			},
+		},
+		{
+			name:      "agent adds a user who only accepts contacts",
+			requester: 2,
+			userID:    4,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetConversation", uint64(1)).Return(testConversation(), nil).Once()
+				mockRepo.On("GetUser", uint64(4)).Return(&models.User{ID: 4, Role: models.RoleUser, ContactsOnly: true}, nil).Once()
+				mockRepo.On("IsBlocked", uint64(4), uint64(2)).Return(false, nil).Once()
+				mockRepo.On("GetUser", uint64(2)).Return(&models.User{ID: 2, Role: models.RoleAgent}, nil).Once()
+				mockRepo.On("SaveConversationMember", mock.Anything).Return(&models.ConversationMember{}, nil).Once()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			tt.setupMocks(mockRepo)
+
+			service := NewService(mockRepo)
+			_, err := service.AddConversationMember(tt.requester, 1, tt.userID, tt.role)
+
+			assert.Equal(t, tt.expectedError, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRemoveConversationMember(t *testing.T) {
+	tests := []struct {
+		name          string
+		requester     uint64
+		userID        uint64
+		setupMocks    func(mockRepo *repository.MockRepository)
+		expectedError error
+	}{
+		{
+			name:      "success",
+			requester: 1,
+			userID:    2,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetConversation", uint64(1)).Return(testConversation(), nil).Once()
+				mockRepo.On("DeleteConversationMember", uint64(1), uint64(2)).Return(nil).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name:      "owner cannot be removed",
+			requester: 2,
+			userID:    1,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetConversation", uint64(1)).Return(testConversation(), nil).Once()
+			},
+			expectedError: httperrors.ForbiddenError("the owner cannot be removed"),
+		},
+		{
+			name:      "user is not a member",
+			requester: 1,
+			userID:    9,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetConversation", uint64(1)).Return(testConversation(), nil).Once()
+			},
+			expectedError: httperrors.NotFoundError("user is not a member of this conversation"),
+		},
+		{
+			name:      "repository error",
+			requester: 2,
+			userID:    3,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetConversation", uint64(1)).Return(testConversation(), nil).Once()
+				mockRepo.On("DeleteConversationMember", uint64(1), uint64(3)).Return(errors.New("repository error")).Once()
+			},
+			expectedError: httperrors.InternalServerError("an error occurred while trying to remove member", errors.New("repository error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			tt.setupMocks(mockRepo)
+
+			service := NewService(mockRepo)
+			err := service.RemoveConversationMember(tt.requester, 1, tt.userID)
+
+			assert.Equal(t, tt.expectedError, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLeaveConversation(t *testing.T) {
+	t.Run("owner leaving promotes an admin", func(t *testing.T) {
+		mockRepo := new(repository.MockRepository)
+		mockRepo.On("GetConversation", uint64(1)).Return(testConversation(), nil).Once()
+		mockRepo.On("DeleteConversationMember", uint64(1), uint64(1)).Return(nil).Once()
+		mockRepo.On("SaveConversationMember", &models.ConversationMember{
+			ConversationID: 1,
+			UserID:         2,
+			Role:           models.ConversationRoleOwner,
+		}).Return(&models.ConversationMember{}, nil).Once()
+
+		err := NewService(mockRepo).LeaveConversation(1, 1)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("member leaving", func(t *testing.T) {
+		mockRepo := new(repository.MockRepository)
+		mockRepo.On("GetConversation", uint64(1)).Return(testConversation(), nil).Once()
+		mockRepo.On("DeleteConversationMember", uint64(1), uint64(3)).Return(nil).Once()
+
+		err := NewService(mockRepo).LeaveConversation(3, 1)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("not a member", func(t *testing.T) {
+		mockRepo := new(repository.MockRepository)
+		mockRepo.On("GetConversation", uint64(1)).Return(testConversation(), nil).Once()
+
+		err := NewService(mockRepo).LeaveConversation(9, 1)
+
+		assert.Equal(t, httperrors.ForbiddenError("you are not a member of this conversation"), err)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestSendConversationMessage(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       *models.Content
+		setupMocks    func(mockRepo *repository.MockRepository)
+		expectedError error
+	}{
+		{
+			name:    "success",
+			content: &models.Content{Type: "text", Text: "hello team"},
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetConversation", uint64(1)).Return(testConversation(), nil).Once()
+				mockRepo.On("SaveConversationMessage", mock.Anything).Return(&models.Message{
+					Id:             1,
+					SenderID:       1,
+					ConversationID: 1,
+				}, nil).Once()
+				mockRepo.On("GetBlockerIDs", uint64(1)).Return([]uint64{3}, nil).Once()
+			},
+			expectedError: nil,
+		},
+		{
+			name:          "invalid message type",
+			content:       &models.Content{Type: "invalid"},
+			setupMocks:    func(mockRepo *repository.MockRepository) {},
+			expectedError: httperrors.BadRequestError("invalid message type"),
+		},
+		{
+			name:    "sender is not a member",
+			content: &models.Content{Type: "text", Text: "hello team"},
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetConversation", uint64(1)).Return(testConversation(), nil).Once()
+				mockRepo.On("SaveConversationMessage", mock.Anything).Return(nil, repository.ErrNotConversationMember).Once()
+			},
+			expectedError: httperrors.ForbiddenError("you are not a member of this conversation"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			tt.setupMocks(mockRepo)
+
+			service := NewService(mockRepo)
+			sub := service.Subscribe(2)
+			defer service.Unsubscribe(sub)
+			blocker := service.Subscribe(3)
+			defer service.Unsubscribe(blocker)
+
+			_, err := service.SendConversationMessage(1, 1, tt.content)
+
+			assert.Equal(t, tt.expectedError, err)
+			if tt.expectedError == nil {
+				assert.Len(t, sub.Messages, 1)
+			}
+			assert.Len(t, blocker.Messages, 0)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetConversationMessages(t *testing.T) {
+	mockRepo := new(repository.MockRepository)
+	mockRepo.On("GetConversationMessages", uint64(1), uint64(2), uint64(0), uint64(100)).Return([]models.Message{{Id: 1}}, nil).Once()
+	mockRepo.On("GetConversationMessages", uint64(1), uint64(9), uint64(0), uint64(100)).Return(nil, repository.ErrNotConversationMember).Once()
+
+	service := NewService(mockRepo)
+
+	messages, err := service.GetConversationMessages(2, 1, 0, 100)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+
+	_, err = service.GetConversationMessages(9, 1, 0, 100)
+	assert.Equal(t, httperrors.ForbiddenError("you are not a member of this conversation"), err)
+	mockRepo.AssertExpectations(t)
+}
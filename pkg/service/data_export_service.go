@@ -0,0 +1,374 @@
+package service
+
+import (
+	"archive/zip"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"time"
+
+	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/helpers"
+	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/repository"
+	"github.com/challenge/pkg/storage"
+	"gorm.io/gorm"
+)
+
+// DataExportTTL is how long a finished export can be downloaded
+const DataExportTTL = 24 * time.Hour
+
+// dataExportBatchSize is the number of expired exports removed in each run
+const dataExportBatchSize = 100
+
+// RequestDataExport schedules an export of the personal data of the user and
+// returns it with the token needed to download it, which is not stored
+func (s ServiceImpl) RequestDataExport(userID uint64) (*models.DataExport, string, error) {
+	if s.Storage == nil {
+		return nil, "", httperrors.InternalServerError("an error occurred while trying to export data", errors.New("export storage is not configured"))
+	}
+
+	token, err := helpers.RandomToken(32)
+	if err != nil {
+		return nil, "", httperrors.InternalServerError("an error occurred while trying to export data", err)
+	}
+
+	export, err := s.Repository.CreateDataExport(&models.DataExport{
+		UserID:    userID,
+		Status:    models.DataExportPending,
+		TokenHash: hashToken(token),
+		CreatedAt: time.Now(),
+	})
+	if errors.Is(err, repository.ErrDataExportInProgress) {
+		return nil, "", httperrors.BadRequestError("an export is already in progress")
+	} else if err != nil {
+		return nil, "", httperrors.InternalServerError("an error occurred while trying to export data", err)
+	}
+
+	if s.Exports != nil {
+		s.Exports.Wake()
+	}
+
+	return export, token, nil
+}
+
+// GetDataExport returns an export of the user
+func (s ServiceImpl) GetDataExport(userID, exportID uint64) (*models.DataExport, error) {
+	export, err := s.Repository.GetDataExport(exportID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && export.UserID != userID) {
+		return nil, httperrors.NotFoundError("export not found")
+	} else if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to get export", err)
+	}
+
+	return export, nil
+}
+
+// DownloadDataExport opens a ready export for the user holding its token. The
+// export can only be downloaded once and its file is deleted when the
+// returned reader is closed.
+func (s ServiceImpl) DownloadDataExport(userID, exportID uint64, token string) (*models.DataExport, io.ReadCloser, error) {
+	export, err := s.GetDataExport(userID, exportID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(token)), []byte(export.TokenHash)) != 1 {
+		return nil, nil, httperrors.NotFoundError("export not found")
+	}
+
+	switch export.Status {
+	case models.DataExportPending:
+		return nil, nil, httperrors.BadRequestError("export is not ready yet")
+	case models.DataExportReady:
+	default:
+		return nil, nil, httperrors.NotFoundError("export is no longer available")
+	}
+
+	if s.Storage == nil {
+		return nil, nil, httperrors.InternalServerError("an error occurred while trying to get export", errors.New("export storage is not configured"))
+	}
+
+	claimed, err := s.Repository.ClaimDataExport(export.ID, time.Now())
+	if err != nil {
+		return nil, nil, httperrors.InternalServerError("an error occurred while trying to get export", err)
+	}
+	if !claimed {
+		return nil, nil, httperrors.NotFoundError("export is no longer available")
+	}
+
+	content, err := s.Storage.Open(export.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, httperrors.NotFoundError("export is no longer available")
+	} else if err != nil {
+		return nil, nil, httperrors.InternalServerError("an error occurred while trying to get export", err)
+	}
+
+	return export, &exportReader{ReadCloser: content, storage: s.Storage, key: export.StorageKey}, nil
+}
+
+// ProcessDataExports removes expired exports and builds the oldest pending
+// one, and tells whether there is work left. An export interrupted by a
+// restart is built again from scratch.
+func (s ServiceImpl) ProcessDataExports() (bool, error) {
+	if s.Storage == nil {
+		return false, nil
+	}
+
+	expired, err := s.Repository.GetExpiredDataExports(time.Now(), dataExportBatchSize)
+	if err != nil {
+		return false, err
+	}
+	for i := range expired {
+		if err := s.Storage.Delete(expired[i].StorageKey); err != nil {
+			return false, err
+		}
+		expired[i].Status = models.DataExportExpired
+		if err := s.Repository.UpdateDataExport(&expired[i]); err != nil {
+			return false, err
+		}
+	}
+
+	export, err := s.Repository.NextDataExport()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if err := s.buildDataExport(export); err != nil {
+		log.Printf("Data export %d failed: %v", export.ID, err)
+		export.Status = models.DataExportFailed
+	} else {
+		export.Status = models.DataExportReady
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(DataExportTTL)
+	export.CompletedAt = &now
+	export.ExpiresAt = &expiresAt
+	if err := s.Repository.UpdateDataExport(export); err != nil {
+		return false, err
+	}
+
+	// Other exports may be pending
+	return true, nil
+}
+
+// buildDataExport writes the ZIP archive of an export to the storage while it
+// is generated, so it is never held in memory as a whole
+func (s ServiceImpl) buildDataExport(export *models.DataExport) error {
+	user, err := s.Repository.GetUser(export.UserID)
+	if err != nil {
+		return err
+	}
+
+	if export.StorageKey == "" {
+		if export.StorageKey, err = helpers.RandomToken(16); err != nil {
+			return err
+		}
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(s.writeDataExport(pw, user))
+	}()
+
+	export.Size, err = s.Storage.Save(export.StorageKey, pr)
+	// Unblocks the writer when saving failed half way
+	pr.CloseWithError(err)
+	if err != nil {
+		_ = s.Storage.Delete(export.StorageKey)
+		return err
+	}
+
+	return nil
+}
+
+// exportedProfile is the account data of the user written to profile.json
+type exportedProfile struct {
+	ID           uint64    `json:"id"`
+	Username     string    `json:"username"`
+	DisplayName  string    `json:"display_name"`
+	AvatarURL    string    `json:"avatar_url"`
+	StatusText   string    `json:"status_text"`
+	Role         string    `json:"role"`
+	ContactsOnly bool      `json:"contacts_only"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func newExportedProfile(user *models.User) exportedProfile {
+	return exportedProfile{
+		ID:           user.ID,
+		Username:     user.Username,
+		DisplayName:  user.DisplayName,
+		AvatarURL:    user.AvatarURL,
+		StatusText:   user.StatusText,
+		Role:         user.Role,
+		ContactsOnly: user.ContactsOnly,
+		CreatedAt:    user.CreatedAt,
+		UpdatedAt:    user.UpdatedAt,
+	}
+}
+
+// writeDataExport writes a ZIP archive with the profile of the user and the
+// messages they sent or received, as JSON and as an HTML transcript. The
+// messages are streamed twice from the repository, once for each file.
+func (s ServiceImpl) writeDataExport(w io.Writer, user *models.User) error {
+	archive := zip.NewWriter(w)
+	now := time.Now()
+	create := func(name string) (io.Writer, error) {
+		return archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: now})
+	}
+
+	file, err := create("profile.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(newExportedProfile(user)); err != nil {
+		return err
+	}
+
+	peers := map[uint64]string{user.ID: user.Username}
+	if file, err = create("messages.json"); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(file, "["); err != nil {
+		return err
+	}
+	first := true
+	err = s.Repository.StreamUserMessages(user.ID, func(message *models.Message) error {
+		peers[message.SenderID] = ""
+		if message.ConversationID == 0 {
+			peers[message.RecipientID] = ""
+		}
+
+		data, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+		if !first {
+			if _, err := io.WriteString(file, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		_, err = fmt.Fprintf(file, "\n  %s", data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(file, "\n]\n"); err != nil {
+		return err
+	}
+
+	// Usernames are looked up once the stream is closed
+	for id := range peers {
+		if id == user.ID || id == models.DeletedUserID {
+			continue
+		}
+		if peer, err := s.Repository.GetUser(id); err == nil {
+			peers[id] = peer.Username
+		}
+	}
+	peers[user.ID] = user.Username
+
+	if file, err = create("transcript.html"); err != nil {
+		return err
+	}
+	if err := transcriptTemplate.ExecuteTemplate(file, "header", user); err != nil {
+		return err
+	}
+	err = s.Repository.StreamUserMessages(user.ID, func(message *models.Message) error {
+		return transcriptTemplate.ExecuteTemplate(file, "message", transcriptMessage{
+			Message:   message,
+			Sender:    transcriptName(peers, message.SenderID),
+			Recipient: transcriptName(peers, message.RecipientID),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if err := transcriptTemplate.ExecuteTemplate(file, "footer", nil); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+type transcriptMessage struct {
+	*models.Message
+	Sender    string
+	Recipient string
+}
+
+func transcriptName(names map[uint64]string, id uint64) string {
+	if name := names[id]; name != "" {
+		return name
+	}
+	if id == models.DeletedUserID {
+		return "Deleted user"
+	}
+
+	return fmt.Sprintf("User %d", id)
+}
+
+var transcriptTemplate = template.Must(template.New("transcript").Parse(`
+{{- define "header" -}}
+<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Messages of {{.Username}}</title>
+<style>
+body { font-family: sans-serif; max-width: 48em; margin: 2em auto; }
+.message { border-bottom: 1px solid #ddd; padding: .5em 0; }
+.meta { color: #666; font-size: .85em; }
+</style>
+</head>
+<body>
+<h1>Messages of {{.Username}}</h1>
+{{end -}}
+
+{{- define "message" -}}
+<div class="message">
+<div class="meta">{{.Timestamp}} &middot; {{.Sender}} to {{if .ConversationID}}conversation {{.ConversationID}}{{else}}{{.Recipient}}{{end}}{{if .EditedAt}} &middot; edited{{end}}</div>
+{{- if .DeletedAt}}
+<p><em>Deleted message</em></p>
+{{- else if eq .Content.Type "text"}}
+<p>{{.Content.Text}}</p>
+{{- else}}
+<p><em>{{.Content.Type}}</em>{{with .Content.URL}} <a href="{{.}}">{{.}}</a>{{end}}{{with .Content.AttachmentID}} attachment {{.}}{{end}}</p>
+{{- end}}
+</div>
+{{end -}}
+
+{{- define "footer" -}}
+</body>
+</html>
+{{end -}}
+`))
+
+// exportReader deletes the file of a downloaded export once it is closed
+type exportReader struct {
+	io.ReadCloser
+	storage storage.Storage
+	key     string
+}
+
+func (r *exportReader) Close() error {
+	err := r.ReadCloser.Close()
+	if deleteErr := r.storage.Delete(r.key); deleteErr != nil {
+		log.Printf("Cannot delete downloaded export %s: %v", r.key, deleteErr)
+	}
+
+	return err
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/repository"
+	"github.com/challenge/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServiceImpl_RequestDataExport(t *testing.T) {
+	mockRepo := new(repository.MockRepository)
+	svc := NewService(mockRepo, WithStorage(newTestStorage(t)))
+
+	var tokenHash string
+	mockRepo.On("CreateDataExport", mock.MatchedBy(func(e *models.DataExport) bool {
+		tokenHash = e.TokenHash
+		return e.UserID == 1 && e.Status == models.DataExportPending
+	})).Return(&models.DataExport{ID: 1, UserID: 1}, nil).Once()
+
+	export, token, err := svc.RequestDataExport(1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), export.ID)
+	assert.Equal(t, hashToken(token), tokenHash)
+
+	mockRepo.On("CreateDataExport", mock.Anything).Return(nil, repository.ErrDataExportInProgress).Once()
+	_, _, err = svc.RequestDataExport(1)
+	assert.Equal(t, httperrors.BadRequestError("an export is already in progress"), err)
+	mockRepo.AssertExpectations(t)
+
+	_, _, err = NewService(mockRepo).RequestDataExport(1)
+	assert.Error(t, err)
+}
+
+func TestServiceImpl_DownloadDataExport(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	ready := &models.DataExport{ID: 1, UserID: 1, Status: models.DataExportReady, TokenHash: hashToken("token"), StorageKey: "export", ExpiresAt: &expiresAt}
+
+	tests := []struct {
+		name          string
+		userID        uint64
+		token         string
+		export        *models.DataExport
+		mockBehavior  func(mockRepo *repository.MockRepository)
+		expectedError error
+	}{
+		{
+			name:   "success",
+			userID: 1,
+			token:  "token",
+			export: ready,
+			mockBehavior: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("ClaimDataExport", uint64(1), mock.Anything).Return(true, nil).Once()
+			},
+		},
+		{
+			name:          "other user",
+			userID:        2,
+			token:         "token",
+			export:        ready,
+			mockBehavior:  func(mockRepo *repository.MockRepository) {},
+			expectedError: httperrors.NotFoundError("export not found"),
+		},
+		{
+			name:          "wrong token",
+			userID:        1,
+			token:         "wrong",
+			export:        ready,
+			mockBehavior:  func(mockRepo *repository.MockRepository) {},
+			expectedError: httperrors.NotFoundError("export not found"),
+		},
+		{
+			name:          "pending",
+			userID:        1,
+			token:         "token",
+			export:        &models.DataExport{ID: 1, UserID: 1, Status: models.DataExportPending, TokenHash: hashToken("token")},
+			mockBehavior:  func(mockRepo *repository.MockRepository) {},
+			expectedError: httperrors.BadRequestError("export is not ready yet"),
+		},
+		{
+			name:   "already downloaded",
+			userID: 1,
+			token:  "token",
+			export: ready,
+			mockBehavior: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("ClaimDataExport", uint64(1), mock.Anything).Return(false, nil).Once()
+			},
+			expectedError: httperrors.NotFoundError("export is no longer available"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStorage(t)
+			_, err := store.Save("export", strings.NewReader("zip"))
+			assert.NoError(t, err)
+
+			mockRepo := new(repository.MockRepository)
+			mockRepo.On("GetDataExport", uint64(1)).Return(tt.export, nil).Once()
+			tt.mockBehavior(mockRepo)
+			svc := NewService(mockRepo, WithStorage(store))
+
+			_, content, err := svc.DownloadDataExport(tt.userID, 1, tt.token)
+			assert.Equal(t, tt.expectedError, err)
+			if tt.expectedError == nil {
+				data, _ := io.ReadAll(content)
+				assert.Equal(t, "zip", string(data))
+				assert.NoError(t, content.Close())
+
+				_, err = store.Open("export")
+				assert.ErrorIs(t, err, storage.ErrNotFound, "downloaded exports are deleted")
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServiceImpl_ProcessDataExports(t *testing.T) {
+	store := newTestStorage(t)
+	mockRepo := new(repository.MockRepository)
+	svc := NewService(mockRepo, WithStorage(store))
+
+	export := &models.DataExport{ID: 1, UserID: 1, Status: models.DataExportPending}
+	mockRepo.On("GetExpiredDataExports", mock.Anything, uint64(dataExportBatchSize)).Return([]models.DataExport{}, nil).Once()
+	mockRepo.On("NextDataExport").Return(export, nil).Once()
+	mockRepo.On("GetUser", uint64(1)).Return(&models.User{ID: 1, Username: "alice", Password: "hash"}, nil).Once()
+	mockRepo.On("GetUser", uint64(2)).Return(&models.User{ID: 2, Username: "bob"}, nil).Once()
+	mockRepo.On("StreamUserMessages", uint64(1), mock.Anything).Return([]models.Message{
+		{Id: 1, SenderID: 1, RecipientID: 2, Timestamp: "2025-01-01 00:00:00", Content: models.Content{Type: "text", Text: "<b>hi</b>"}},
+		{Id: 2, SenderID: 2, RecipientID: 1, Timestamp: "2025-01-01 00:01:00", Content: models.Content{Type: "image", URL: "https://example.com/a.png"}},
+	}, nil).Twice()
+	mockRepo.On("UpdateDataExport", export).Return(nil).Once()
+
+	more, err := svc.ProcessDataExports()
+	assert.NoError(t, err)
+	assert.True(t, more)
+	assert.Equal(t, models.DataExportReady, export.Status)
+	assert.NotNil(t, export.ExpiresAt)
+	mockRepo.AssertExpectations(t)
+
+	file, err := store.Open(export.StorageKey)
+	assert.NoError(t, err)
+	data, err := io.ReadAll(file)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+	assert.Equal(t, int64(len(data)), export.Size)
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	assert.NoError(t, err)
+	files := map[string]string{}
+	for _, f := range archive.File {
+		r, err := f.Open()
+		assert.NoError(t, err)
+		content, _ := io.ReadAll(r)
+		files[f.Name] = string(content)
+	}
+
+	assert.Contains(t, files["profile.json"], `"username": "alice"`)
+	assert.NotContains(t, files["profile.json"], "hash")
+	assert.Contains(t, files["profile.json"], `"contacts_only": false`)
+
+	var messages []models.Message
+	assert.NoError(t, json.Unmarshal([]byte(files["messages.json"]), &messages))
+	assert.Len(t, messages, 2)
+
+	assert.Contains(t, files["transcript.html"], "&lt;b&gt;hi&lt;/b&gt;")
+	assert.Contains(t, files["transcript.html"], "bob to alice")
+	assert.Contains(t, files["transcript.html"], `href="https://example.com/a.png"`)
+}
+
+func TestServiceImpl_ProcessDataExports_Expired(t *testing.T) {
+	store := newTestStorage(t)
+	_, err := store.Save("old", strings.NewReader("zip"))
+	assert.NoError(t, err)
+
+	mockRepo := new(repository.MockRepository)
+	svc := NewService(mockRepo, WithStorage(store))
+
+	mockRepo.On("GetExpiredDataExports", mock.Anything, uint64(dataExportBatchSize)).
+		Return([]models.DataExport{{ID: 1, Status: models.DataExportReady, StorageKey: "old"}}, nil).Once()
+	mockRepo.On("UpdateDataExport", mock.MatchedBy(func(e *models.DataExport) bool {
+		return e.ID == 1 && e.Status == models.DataExportExpired
+	})).Return(nil).Once()
+	mockRepo.On("NextDataExport").Return(nil, gorm.ErrRecordNotFound).Once()
+
+	more, err := svc.ProcessDataExports()
+	assert.NoError(t, err)
+	assert.False(t, more)
+	mockRepo.AssertExpectations(t)
+
+	_, err = store.Open("old")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
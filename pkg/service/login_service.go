@@ -1,41 +1,238 @@
 package service
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"github.com/challenge/pkg/auth"
 	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/helpers"
+	"github.com/challenge/pkg/lockout"
 	"github.com/challenge/pkg/models"
-	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/challenge/pkg/repository"
 	"gorm.io/gorm"
+	"log"
 	"time"
 )
 
-func (s ServiceImpl) Login(username, password string) (uint64, string, error) {
+// Login checks the credentials of a user and starts a new session. Failed
+// attempts are counted per username and per client IP, which have to wait
+// longer after each failure until they get locked out. Users with two-factor
+// authentication get a challenge token to exchange with a code instead.
+func (s ServiceImpl) Login(username, password, ip string) (*models.AuthTokens, error) {
+	userKey, ipKey := s.Lockout.UserKey(username), s.Lockout.IPKey(ip)
+	wait, err := s.Lockout.Check(userKey, ipKey)
+	if err != nil {
+		return nil, httperrors.InternalServerError("An error occurred while trying to login", err)
+	}
+	if wait > 0 {
+		return nil, httperrors.TooManyRequestsError("Too many failed login attempts", wait)
+	}
+
 	user, err := s.GetUserByUsername(username)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return 0, "", httperrors.BadRequestError("Invalid username or password")
+		return nil, s.loginFailed(username, ip, "Invalid username or password", userKey, ipKey)
+	} else if err != nil {
+		return nil, httperrors.InternalServerError("An error occurred while trying to login", err)
+	}
+
+	match, needsRehash, err := s.Hasher.Verify(user.Password, password)
+	if err != nil {
+		return nil, httperrors.InternalServerError("An error occurred while trying to login", err)
+	}
+	if !match {
+		return nil, s.loginFailed(username, ip, "Invalid username or password", userKey, ipKey)
+	}
+
+	if user.DisabledAt != nil {
+		return nil, httperrors.ForbiddenError("account is disabled")
+	}
+
+	if needsRehash {
+		s.rehashPassword(user.ID, password)
+	}
+
+	enabled, err := s.twoFactorEnabled(user.ID)
+	if err != nil {
+		return nil, httperrors.InternalServerError("An error occurred while trying to login", err)
+	}
+	if enabled {
+		tokens, err := s.startLoginChallenge(user.ID)
+		if err != nil {
+			return nil, httperrors.InternalServerError("An error occurred while trying to login", err)
+		}
+
+		return tokens, nil
+	}
+
+	if err := s.Lockout.Reset(userKey); err != nil {
+		return nil, httperrors.InternalServerError("An error occurred while trying to login", err)
+	}
+
+	tokens, err := s.startSession(user)
+	if err != nil {
+		return nil, httperrors.InternalServerError("An error occurred while trying to login", err)
+	}
+
+	return tokens, nil
+}
+
+// loginFailed records a failed login, keeping an audit record of the keys it
+// locked out, and returns the error for the failure
+func (s ServiceImpl) loginFailed(username, ip, message string, keys ...lockout.Key) error {
+	locked, err := s.Lockout.Fail(keys...)
+	if err != nil {
+		return httperrors.InternalServerError("An error occurred while trying to login", err)
+	}
+
+	for _, attempt := range locked {
+		if _, err := s.Repository.SaveLoginLockout(&models.LoginLockout{
+			Key:         attempt.Key,
+			Username:    username,
+			IP:          ip,
+			Failures:    attempt.Failures,
+			LockedUntil: attempt.BlockedUntil,
+		}); err != nil {
+			return httperrors.InternalServerError("An error occurred while trying to login", err)
+		}
+	}
+
+	return httperrors.BadRequestError(message)
+}
+
+// startSession issues the tokens of a new session
+func (s ServiceImpl) startSession(user *models.User) (*models.AuthTokens, error) {
+	sessionID, err := helpers.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, refreshToken, err := issueTokens(user, sessionID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.Repository.CreateRefreshToken(refreshToken); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// RefreshSession exchanges a refresh token for a new access and refresh token.
+// Using a refresh token twice revokes the whole session, as it means it was stolen.
+func (s ServiceImpl) RefreshSession(refreshToken string) (*models.AuthTokens, error) {
+	current, err := s.Repository.GetRefreshToken(hashToken(refreshToken))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, httperrors.UnauthorizedError("invalid refresh token")
+	} else if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to refresh session", err)
+	}
+
+	now := time.Now()
+	if current.RevokedAt != nil {
+		if err := s.Repository.RevokeSession(current.UserID, current.SessionID, now); err != nil {
+			return nil, httperrors.InternalServerError("an error occurred while trying to refresh session", err)
+		}
+		return nil, httperrors.UnauthorizedError("invalid refresh token")
+	}
+	if !current.ExpiresAt.After(now) {
+		return nil, httperrors.UnauthorizedError("invalid refresh token")
+	}
+
+	// The user is read again so new tokens carry their current role
+	user, err := s.Repository.GetUser(current.UserID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, httperrors.InternalServerError("an error occurred while trying to refresh session", err)
+	}
+	if user == nil || user.DisabledAt != nil {
+		if err := s.Repository.RevokeSession(current.UserID, current.SessionID, now); err != nil {
+			return nil, httperrors.InternalServerError("an error occurred while trying to refresh session", err)
+		}
+		return nil, httperrors.UnauthorizedError("invalid refresh token")
+	}
+
+	tokens, next, err := issueTokens(user, current.SessionID, now)
+	if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to refresh session", err)
+	}
+
+	_, err = s.Repository.RotateRefreshToken(current, next, now)
+	if errors.Is(err, repository.ErrRefreshTokenRevoked) {
+		if err := s.Repository.RevokeSession(current.UserID, current.SessionID, now); err != nil {
+			return nil, httperrors.InternalServerError("an error occurred while trying to refresh session", err)
+		}
+		return nil, httperrors.UnauthorizedError("invalid refresh token")
 	} else if err != nil {
-		return 0, "", httperrors.InternalServerError("An error occurred while trying to login", err)
+		return nil, httperrors.InternalServerError("an error occurred while trying to refresh session", err)
+	}
+
+	return tokens, nil
+}
+
+// Logout revokes the session of the user and every token issued for it
+func (s ServiceImpl) Logout(userID uint64, sessionID string) error {
+	if err := s.Repository.RevokeSession(userID, sessionID, time.Now()); err != nil {
+		return httperrors.InternalServerError("an error occurred while trying to logout", err)
+	}
+
+	return nil
+}
+
+// issueTokens signs a new access token and creates the refresh token that goes with it
+func issueTokens(user *models.User, sessionID string, now time.Time) (*models.AuthTokens, *models.RefreshToken, error) {
+	userID := user.ID
+	tokenID, err := helpers.RandomToken(16)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refreshToken, err := helpers.RandomToken(32)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if err = checkPassword(user, password); err != nil {
-		return 0, "", httperrors.BadRequestError("Invalid username or password")
+	claims := auth.NewClaims(userID, user.Role, sessionID, tokenID, now)
+
+	signed, err := auth.SignToken(claims)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	expTime := time.Now().Add(time.Hour * 24)
+	tokens := &models.AuthTokens{
+		UserID:       userID,
+		AccessToken:  signed,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(auth.AccessTokenTTL.Seconds()),
+	}
 
-	claims := jwt.MapClaims{
-		"user_id": user.ID,
-		"exp":     expTime.Unix(),
+	stored := &models.RefreshToken{
+		UserID:          userID,
+		SessionID:       sessionID,
+		TokenHash:       hashToken(refreshToken),
+		AccessTokenID:   tokenID,
+		AccessExpiresAt: claims.ExpiresAt.Time,
+		ExpiresAt:       now.Add(auth.RefreshTokenTTL),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, _ := token.SignedString(auth.JwtSecret)
+	return tokens, stored, nil
+}
 
-	return user.ID, signed, nil
+// hashToken hashes a refresh token before it is stored or looked up. The
+// tokens are random so a fast hash is enough.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
-func checkPassword(user *models.User, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+// rehashPassword upgrades a stored hash to the current algorithm and parameters.
+// Failing to do so does not fail the login, it is retried on the next one.
+func (s ServiceImpl) rehashPassword(userID uint64, password string) {
+	hashedPassword, err := s.Hasher.Hash(password)
+	if err == nil {
+		err = s.Repository.UpdateUserPassword(userID, hashedPassword)
+	}
+	if err != nil {
+		log.Printf("could not rehash password of user %d: %v", userID, err)
+	}
 }
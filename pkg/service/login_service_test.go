@@ -4,19 +4,23 @@ import (
 	"errors"
 	"github.com/challenge/pkg/auth"
 	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/lockout"
 	"github.com/challenge/pkg/models"
 	"github.com/challenge/pkg/repository"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestServiceImpl_Login(t *testing.T) {
 	auth.JwtSecret = []byte("testsecret")
 	mockRepo := new(repository.MockRepository)
-	service := NewService(mockRepo)
 
 	tests := []struct {
 		name          string
@@ -32,8 +36,16 @@ func TestServiceImpl_Login(t *testing.T) {
 			password: "password123",
 			mockSetup: func(username string) {
 				hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
-				mockUser := &models.User{ID: 1, Username: username, Password: string(hashedPassword)}
+				mockUser := &models.User{ID: 1, Username: username, Password: string(hashedPassword), Role: models.RoleAgent}
 				mockRepo.On("GetUserByUsername", username).Return(mockUser, nil).Once()
+				// bcrypt hashes are upgraded to argon2id on login
+				mockRepo.On("UpdateUserPassword", uint64(1), mock.MatchedBy(func(hash string) bool {
+					return strings.HasPrefix(hash, "$argon2id$")
+				})).Return(nil).Once()
+				mockRepo.On("GetTOTPCredential", uint64(1)).Return(nil, gorm.ErrRecordNotFound).Once()
+				mockRepo.On("CreateRefreshToken", mock.MatchedBy(func(token *models.RefreshToken) bool {
+					return token.UserID == 1 && token.SessionID != "" && token.TokenHash != "" && token.AccessTokenID != ""
+				})).Return(&models.RefreshToken{}, nil).Once()
 			},
 			expectedID:    1,
 			expectedError: nil,
@@ -58,6 +70,19 @@ func TestServiceImpl_Login(t *testing.T) {
 			expectedID:    0,
 			expectedError: httperrors.InternalServerError("An error occurred while trying to login", errors.New("database error")),
 		},
+		{
+			name:     "disabled user",
+			username: "disabled",
+			password: "password123",
+			mockSetup: func(username string) {
+				disabledAt := time.Now()
+				hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+				mockUser := &models.User{ID: 2, Username: username, Password: string(hashedPassword), DisabledAt: &disabledAt}
+				mockRepo.On("GetUserByUsername", username).Return(mockUser, nil).Once()
+			},
+			expectedID:    0,
+			expectedError: httperrors.ForbiddenError("account is disabled"),
+		},
 		{
 			name:     "wrong password",
 			username: "testuser",
@@ -76,22 +101,161 @@ func TestServiceImpl_Login(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockSetup(tt.username)
 
-			userID, token, err := service.Login(tt.username, tt.password)
+			service := NewService(mockRepo)
+			tokens, err := service.Login(tt.username, tt.password, "127.0.0.1")
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
 				assert.Equal(t, tt.expectedError, err)
+				assert.Nil(t, tokens)
 			} else {
-				parsedToken, _ := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
-					return auth.JwtSecret, nil
-				})
+				claims, parseErr := auth.ParseToken(tokens.AccessToken)
+				assert.NoError(t, parseErr)
 
-				assert.Equal(t, tt.expectedID, uint64(parsedToken.Claims.(jwt.MapClaims)["user_id"].(float64)))
-				assert.NotEmpty(t, parsedToken.Claims.(jwt.MapClaims)["exp"])
+				userID, _ := claims.UserID()
+				assert.Equal(t, tt.expectedID, userID)
+				assert.Equal(t, auth.Issuer, claims.Issuer)
+				assert.Equal(t, jwt.ClaimStrings{auth.Audience}, claims.Audience)
+				assert.NotNil(t, claims.IssuedAt)
+				assert.NotNil(t, claims.NotBefore)
+				assert.NotNil(t, claims.ExpiresAt)
+				assert.NotEmpty(t, claims.ID)
+				assert.NotEmpty(t, claims.SessionID)
+				assert.Equal(t, models.RoleAgent, claims.Role)
 				assert.NoError(t, err)
-				assert.NotEmpty(t, token)
+				assert.Equal(t, tt.expectedID, tokens.UserID)
+				assert.NotEmpty(t, tokens.RefreshToken)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServiceImpl_Login_Lockout(t *testing.T) {
+	auth.JwtSecret = []byte("testsecret")
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	mockRepo := new(repository.MockRepository)
+	mockRepo.On("GetUserByUsername", "testuser").Return(&models.User{ID: 1, Username: "testuser", Password: string(hashedPassword)}, nil)
+	mockRepo.On("SaveLoginLockout", mock.MatchedBy(func(l *models.LoginLockout) bool {
+		return l.Key == "user:testuser" && l.Username == "testuser" && l.IP == "127.0.0.1" && l.Failures == 2
+	})).Return(&models.LoginLockout{}, nil).Once()
+
+	guard := lockout.NewGuard(lockout.Config{
+		UserMaxFailures: 2,
+		IPMaxFailures:   10,
+		LockoutDuration: time.Minute,
+		ResetAfter:      time.Hour,
+	}, lockout.NewMemoryStore())
+	service := NewService(mockRepo, WithLoginLockout(guard))
+
+	for i := 0; i < 2; i++ {
+		_, err := service.Login("testuser", "wrongpassword", "127.0.0.1")
+		assert.Equal(t, httperrors.BadRequestError("Invalid username or password"), err)
+	}
+
+	// Even the right password is rejected while locked out, from any IP
+	_, err := service.Login("TestUser", "password123", "10.0.0.1")
+	var er httperrors.ErrorResponse
+	assert.ErrorAs(t, err, &er)
+	assert.Equal(t, http.StatusTooManyRequests, er.Status)
+	assert.Greater(t, er.RetryAfter, 59*time.Second)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestServiceImpl_RefreshSession(t *testing.T) {
+	auth.JwtSecret = []byte("testsecret")
+	revokedAt := time.Now().Add(-time.Minute)
+	active := &models.RefreshToken{ID: 1, UserID: 1, SessionID: "session", ExpiresAt: time.Now().Add(time.Hour)}
+
+	tests := []struct {
+		name          string
+		setupMocks    func(mockRepo *repository.MockRepository)
+		expectedError error
+	}{
+		{
+			name: "success",
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetRefreshToken", hashToken("refresh")).Return(active, nil).Once()
+				mockRepo.On("GetUser", uint64(1)).Return(&models.User{ID: 1, Role: models.RoleUser}, nil).Once()
+				mockRepo.On("RotateRefreshToken", active, mock.MatchedBy(func(next *models.RefreshToken) bool {
+					return next.UserID == 1 && next.SessionID == "session" && next.TokenHash != hashToken("refresh")
+				}), mock.Anything).Return(&models.RefreshToken{}, nil).Once()
+			},
+		},
+		{
+			name: "disabled user revokes the session",
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				disabledAt := time.Now()
+				mockRepo.On("GetRefreshToken", hashToken("refresh")).Return(active, nil).Once()
+				mockRepo.On("GetUser", uint64(1)).Return(&models.User{ID: 1, Role: models.RoleUser, DisabledAt: &disabledAt}, nil).Once()
+				mockRepo.On("RevokeSession", uint64(1), "session", mock.Anything).Return(nil).Once()
+			},
+			expectedError: httperrors.UnauthorizedError("invalid refresh token"),
+		},
+		{
+			name: "unknown token",
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetRefreshToken", hashToken("refresh")).Return(nil, gorm.ErrRecordNotFound).Once()
+			},
+			expectedError: httperrors.UnauthorizedError("invalid refresh token"),
+		},
+		{
+			name: "expired token",
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetRefreshToken", hashToken("refresh")).Return(&models.RefreshToken{
+					ID: 1, UserID: 1, SessionID: "session", ExpiresAt: time.Now().Add(-time.Hour),
+				}, nil).Once()
+			},
+			expectedError: httperrors.UnauthorizedError("invalid refresh token"),
+		},
+		{
+			name: "reused token revokes the session",
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetRefreshToken", hashToken("refresh")).Return(&models.RefreshToken{
+					ID: 1, UserID: 1, SessionID: "session", ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt,
+				}, nil).Once()
+				mockRepo.On("RevokeSession", uint64(1), "session", mock.Anything).Return(nil).Once()
+			},
+			expectedError: httperrors.UnauthorizedError("invalid refresh token"),
+		},
+		{
+			name: "concurrent rotation revokes the session",
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetRefreshToken", hashToken("refresh")).Return(active, nil).Once()
+				mockRepo.On("GetUser", uint64(1)).Return(&models.User{ID: 1, Role: models.RoleUser}, nil).Once()
+				mockRepo.On("RotateRefreshToken", active, mock.Anything, mock.Anything).Return(nil, repository.ErrRefreshTokenRevoked).Once()
+				mockRepo.On("RevokeSession", uint64(1), "session", mock.Anything).Return(nil).Once()
+			},
+			expectedError: httperrors.UnauthorizedError("invalid refresh token"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			tt.setupMocks(mockRepo)
+
+			service := NewService(mockRepo)
+			tokens, err := service.RefreshSession("refresh")
+
+			assert.Equal(t, tt.expectedError, err)
+			if tt.expectedError == nil {
+				assert.Equal(t, uint64(1), tokens.UserID)
+				assert.NotEqual(t, "refresh", tokens.RefreshToken)
 			}
-			assert.Equal(t, tt.expectedID, userID)
+			mockRepo.AssertExpectations(t)
 		})
 	}
 }
+
+func TestServiceImpl_Logout(t *testing.T) {
+	mockRepo := new(repository.MockRepository)
+	mockRepo.On("RevokeSession", uint64(1), "session", mock.Anything).Return(nil).Once()
+	mockRepo.On("RevokeSession", uint64(2), "session", mock.Anything).Return(errors.New("database error")).Once()
+
+	service := NewService(mockRepo)
+
+	assert.NoError(t, service.Logout(1, "session"))
+	assert.Equal(t, httperrors.InternalServerError("an error occurred while trying to logout", errors.New("database error")), service.Logout(2, "session"))
+	mockRepo.AssertExpectations(t)
+}
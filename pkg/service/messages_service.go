@@ -1,30 +1,42 @@
 package service
 
 import (
+	"errors"
 	httperrors "github.com/challenge/pkg/errors"
 	"github.com/challenge/pkg/helpers"
+	"github.com/challenge/pkg/hub"
 	"github.com/challenge/pkg/models"
+	"gorm.io/gorm"
+	"net/url"
 	"time"
 )
 
 func (s ServiceImpl) SendMessage(sender, recipient uint64, content *models.Content) (*models.Message, error) {
-	valid := helpers.MessageTypes[content.Type]
-	if !valid {
-		return nil, httperrors.BadRequestError("invalid message type")
+	if err := s.checkRecipient(sender, recipient); err != nil {
+		return nil, err
+	}
+
+	validContent, err := s.validateContent(sender, content)
+	if err != nil {
+		return nil, err
 	}
 
 	message := &models.Message{
 		SenderID:    sender,
 		RecipientID: recipient,
-		Content:     *content,
+		Content:     validContent,
 		Timestamp:   time.Now().String(),
 	}
 
-	message, err := s.Repository.SaveMessage(message)
+	message, err = s.Repository.SaveMessage(message)
 	if err != nil {
 		return nil, httperrors.InternalServerError("an error occurred while trying to save message", err)
 	}
 
+	if s.Hub != nil {
+		s.Hub.Publish(message.RecipientID, *message)
+	}
+
 	return message, nil
 }
 
@@ -34,5 +46,408 @@ func (s ServiceImpl) GetMessages(id, start, limit uint64) ([]models.Message, err
 		return nil, httperrors.InternalServerError("an error occurred while trying to get messages", err)
 	}
 
+	if err := s.markDelivered(id, messages); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// GetMessagesPage returns a cursor paginated page of the messages received by the user
+func (s ServiceImpl) GetMessagesPage(id uint64, cursor, direction string, limit uint64) (*models.MessagePage, error) {
+	if direction == "" {
+		direction = models.PageBefore
+	}
+	if direction != models.PageBefore && direction != models.PageAfter {
+		return nil, httperrors.BadRequestError("invalid direction")
+	}
+
+	var cursorID uint64
+	if cursor != "" {
+		var err error
+		cursorID, err = helpers.DecodeCursor(cursor)
+		if err != nil {
+			return nil, httperrors.BadRequestError("invalid cursor")
+		}
+	}
+
+	messages, hasMore, err := s.Repository.GetMessagesPage(id, cursorID, direction, limit)
+	if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to get messages", err)
+	}
+
+	if err := s.markDelivered(id, messages); err != nil {
+		return nil, err
+	}
+
+	page := &models.MessagePage{
+		Messages: messages,
+		HasMore:  hasMore,
+	}
+	if page.Messages == nil {
+		page.Messages = []models.Message{}
+	}
+
+	if len(messages) > 0 {
+		if direction == models.PageAfter {
+			page.NextCursor = helpers.EncodeCursor(messages[len(messages)-1].Id)
+		} else {
+			page.NextCursor = helpers.EncodeCursor(messages[0].Id)
+		}
+	} else if cursor != "" {
+		// Keep the position so clients polling forward can retry later
+		page.NextCursor = cursor
+	}
+
+	return page, nil
+}
+
+// GetHistory returns the messages exchanged in both directions between the user and a peer
+func (s ServiceImpl) GetHistory(id, peer, start, limit uint64) ([]models.Message, error) {
+	messages, err := s.Repository.GetMessagesBetweenUsers(id, peer, start, limit)
+	if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to get messages", err)
+	}
+
+	if err := s.markDelivered(id, messages); err != nil {
+		return nil, err
+	}
+
 	return messages, nil
 }
+
+// GetMessagesAfter returns up to limit messages received by the user after the given message id
+func (s ServiceImpl) GetMessagesAfter(id, after, limit uint64) ([]models.Message, error) {
+	messages, err := s.Repository.GetMessagesAfter(id, after, limit)
+	if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to get messages", err)
+	}
+
+	return messages, nil
+}
+
+// MarkMessagesRead marks every message received by the user up to the given id
+// as read, optionally only the ones from a single sender
+func (s ServiceImpl) MarkMessagesRead(recipient, sender, upTo uint64) (int64, error) {
+	if upTo == 0 {
+		return 0, httperrors.BadRequestError("invalid message id")
+	}
+
+	updated, err := s.Repository.MarkMessagesRead(recipient, sender, upTo, time.Now())
+	if err != nil {
+		return 0, httperrors.InternalServerError("an error occurred while trying to mark messages as read", err)
+	}
+
+	return updated, nil
+}
+
+// GetUnreadCounts returns the number of unread messages received by the user per sender and in total
+func (s ServiceImpl) GetUnreadCounts(userID uint64) (*models.UnreadSummary, error) {
+	counts, err := s.Repository.CountUnreadMessages(userID)
+	if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to count unread messages", err)
+	}
+
+	summary := &models.UnreadSummary{Senders: []models.UnreadCount{}}
+	for _, count := range counts {
+		summary.Total += count.Count
+		summary.Senders = append(summary.Senders, count)
+	}
+
+	return summary, nil
+}
+
+// GetInbox returns the direct conversations of the user, the most recently active first
+func (s ServiceImpl) GetInbox(userID, before, limit uint64) ([]models.InboxEntry, error) {
+	entries, err := s.Repository.GetInbox(userID, before, limit)
+	if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to get inbox", err)
+	}
+
+	return entries, nil
+}
+
+// GetMessageStatus returns the delivery status of a message to its sender or recipient
+func (s ServiceImpl) GetMessageStatus(userID, messageID uint64) (*models.MessageStatus, error) {
+	message, err := s.getMessage(messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.SenderID != userID && message.RecipientID != userID {
+		return nil, httperrors.ForbiddenError("you are not allowed to see this message")
+	}
+
+	return &models.MessageStatus{
+		ID:          message.Id,
+		Status:      message.Status(),
+		DeliveredAt: message.DeliveredAt,
+		ReadAt:      message.ReadAt,
+	}, nil
+}
+
+// EditMessage replaces the text of a message sent by the user, keeping the previous version
+func (s ServiceImpl) EditMessage(userID, messageID uint64, text string) (*models.Message, error) {
+	if text == "" {
+		return nil, httperrors.BadRequestError("invalid message text")
+	}
+
+	message, err := s.getOwnMessage(userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.Content.Type != models.ContentTypeText {
+		return nil, httperrors.BadRequestError("only text messages can be edited")
+	}
+
+	now := time.Now()
+	revision := &models.MessageRevision{
+		MessageID: message.Id,
+		Action:    models.RevisionActionEdit,
+		EditedBy:  userID,
+		EditedAt:  now,
+		Content:   message.Content,
+	}
+	message.Content.Text = text
+	message.EditedAt = &now
+
+	message, err = s.Repository.UpdateMessage(message, revision)
+	if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to edit message", err)
+	}
+
+	return message, nil
+}
+
+// DeleteMessage turns a message sent by the user into a tombstone. The
+// original content is kept as a revision.
+func (s ServiceImpl) DeleteMessage(userID, messageID uint64) error {
+	message, err := s.getOwnMessage(userID, messageID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	revision := &models.MessageRevision{
+		MessageID: message.Id,
+		Action:    models.RevisionActionDelete,
+		EditedBy:  userID,
+		EditedAt:  now,
+		Content:   message.Content,
+	}
+	message.DeletedAt = &now
+
+	if _, err := s.Repository.UpdateMessage(message, revision); err != nil {
+		return httperrors.InternalServerError("an error occurred while trying to delete message", err)
+	}
+
+	return nil
+}
+
+// GetMessageRevisions returns the previous versions of a message sent by the user
+func (s ServiceImpl) GetMessageRevisions(userID, messageID uint64) ([]models.MessageRevision, error) {
+	message, err := s.getMessage(messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Admins can see the original versions of any message for compliance
+	if message.SenderID != userID {
+		user, err := s.GetUser(userID)
+		if err != nil {
+			return nil, err
+		}
+		if user.Role != models.RoleAdmin {
+			return nil, httperrors.ForbiddenError("you are not allowed to see this message")
+		}
+	}
+
+	revisions, err := s.Repository.GetMessageRevisions(messageID)
+	if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to get message revisions", err)
+	}
+
+	return revisions, nil
+}
+
+// checkRecipient rejects messages to users that blocked the sender or only
+// accept messages from their contacts, and only lets customers message agents
+// and admins under the support policy
+func (s ServiceImpl) checkRecipient(sender, recipient uint64) error {
+	recipientUser, err := s.Repository.GetUser(recipient)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return httperrors.BadRequestError("recipient not found")
+	} else if err != nil {
+		return httperrors.InternalServerError("an error occurred while trying to save message", err)
+	}
+	if recipientUser.DeletedAt != nil {
+		return httperrors.BadRequestError("recipient not found")
+	}
+
+	blocked, err := s.Repository.IsBlocked(recipient, sender)
+	if err != nil {
+		return httperrors.InternalServerError("an error occurred while trying to save message", err)
+	}
+	if blocked {
+		return httperrors.ForbiddenError("you cannot message this user")
+	}
+
+	if s.Messaging != MessagingPolicySupport && !recipientUser.ContactsOnly {
+		return nil
+	}
+
+	senderUser, err := s.GetUser(sender)
+	if err != nil {
+		return err
+	}
+	// agents and admins reach customers regardless of their privacy settings
+	if senderUser.Role != models.RoleUser {
+		return nil
+	}
+
+	if s.Messaging == MessagingPolicySupport && recipientUser.Role == models.RoleUser {
+		return httperrors.ForbiddenError("customers can only message agents")
+	}
+
+	if recipientUser.ContactsOnly {
+		contact, err := s.Repository.HasSentMessage(recipient, sender)
+		if err != nil {
+			return httperrors.InternalServerError("an error occurred while trying to save message", err)
+		}
+		if !contact {
+			return httperrors.ForbiddenError("this user only accepts messages from contacts")
+		}
+	}
+
+	return nil
+}
+
+func (s ServiceImpl) getMessage(messageID uint64) (*models.Message, error) {
+	message, err := s.Repository.GetMessage(messageID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, httperrors.NotFoundError("message not found")
+	} else if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to get message", err)
+	}
+
+	return message, nil
+}
+
+// getOwnMessage returns a message that can still be changed by the user
+func (s ServiceImpl) getOwnMessage(userID, messageID uint64) (*models.Message, error) {
+	message, err := s.getMessage(messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.SenderID != userID {
+		return nil, httperrors.ForbiddenError("only the sender can change this message")
+	}
+
+	if message.DeletedAt != nil {
+		return nil, httperrors.BadRequestError("message was deleted")
+	}
+
+	return message, nil
+}
+
+// markDelivered records that the messages received by the user were delivered
+func (s ServiceImpl) markDelivered(recipient uint64, messages []models.Message) error {
+	now := time.Now()
+	var ids []uint64
+	for i := range messages {
+		if messages[i].RecipientID == recipient && messages[i].DeliveredAt == nil {
+			ids = append(ids, messages[i].Id)
+			messages[i].DeliveredAt = &now
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if err := s.Repository.MarkMessagesDelivered(recipient, ids, now); err != nil {
+		return httperrors.InternalServerError("an error occurred while trying to mark messages as delivered", err)
+	}
+
+	return nil
+}
+
+// Subscribe opens a real-time feed of the messages sent to the user
+func (s ServiceImpl) Subscribe(userID uint64) *hub.Subscription {
+	return s.Hub.Subscribe(userID)
+}
+
+func (s ServiceImpl) Unsubscribe(sub *hub.Subscription) {
+	s.Hub.Unsubscribe(sub)
+}
+
+// validateContent checks the content has the fields required by its type and
+// returns it without the fields that belong to other types
+func (s ServiceImpl) validateContent(sender uint64, content *models.Content) (models.Content, error) {
+	valid := helpers.MessageTypes[content.Type]
+	if !valid {
+		return models.Content{}, httperrors.BadRequestError("invalid message type")
+	}
+
+	if content.AttachmentID != 0 && content.Type != models.ContentTypeText {
+		return s.attachedContent(sender, content)
+	}
+
+	switch content.Type {
+	case models.ContentTypeImage:
+		if !validURL(content.URL) {
+			return models.Content{}, httperrors.BadRequestError("invalid image url")
+		}
+		if content.Width == 0 || content.Height == 0 {
+			return models.Content{}, httperrors.BadRequestError("invalid image dimensions")
+		}
+		if !helpers.ImageMimeTypes[content.MimeType] {
+			return models.Content{}, httperrors.BadRequestError("invalid image mime type")
+		}
+
+		return models.Content{
+			Type:     content.Type,
+			URL:      content.URL,
+			Width:    content.Width,
+			Height:   content.Height,
+			MimeType: content.MimeType,
+		}, nil
+	case models.ContentTypeVideo:
+		if !validURL(content.URL) {
+			return models.Content{}, httperrors.BadRequestError("invalid video url")
+		}
+		if content.Duration == 0 {
+			return models.Content{}, httperrors.BadRequestError("invalid video duration")
+		}
+		if !helpers.VideoSources[content.Source] {
+			return models.Content{}, httperrors.BadRequestError("invalid video source")
+		}
+
+		return models.Content{
+			Type:     content.Type,
+			URL:      content.URL,
+			Duration: content.Duration,
+			Source:   content.Source,
+		}, nil
+	default:
+		if content.Text == "" {
+			return models.Content{}, httperrors.BadRequestError("invalid message text")
+		}
+
+		return models.Content{
+			Type: content.Type,
+			Text: content.Text,
+		}, nil
+	}
+}
+
+func validURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -3,10 +3,12 @@ package service
 import (
 	"errors"
 	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/helpers"
 	"github.com/challenge/pkg/models"
 	"github.com/challenge/pkg/repository"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 	"testing"
 	"time"
 )
@@ -73,6 +75,7 @@ func TestSendMessage(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			expectRecipient(mockRepo, tt.sender, tt.recipient)
 			tt.setupMocks()
 
 			service := NewService(mockRepo)
@@ -148,3 +151,662 @@ func TestGetMessages(t *testing.T) {
 		})
 	}
 }
+
+func TestSendMessage_PublishesToRecipient(t *testing.T) {
+	mockRepo := new(repository.MockRepository)
+	saved := &models.Message{
+		Id:          1,
+		SenderID:    1,
+		RecipientID: 2,
+		Content: models.Content{
+			Type: "text",
+			Text: "test message",
+		},
+		Timestamp: time.DateTime,
+	}
+	expectRecipient(mockRepo, 1, 2)
+	mockRepo.On("SaveMessage", mock.Anything).Return(saved, nil).Once()
+
+	service := NewService(mockRepo)
+	sub := service.Subscribe(2)
+	defer service.Unsubscribe(sub)
+
+	_, err := service.SendMessage(1, 2, &models.Content{Type: "text", Text: "test message"})
+	assert.NoError(t, err)
+
+	select {
+	case message := <-sub.Messages:
+		assert.Equal(t, *saved, message)
+	default:
+		t.Fatal("expected message to be published to recipient")
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetMessagesAfter(t *testing.T) {
+	mockRepo := new(repository.MockRepository)
+	mockRepo.On("GetMessagesAfter", uint64(2), uint64(5), uint64(10)).Return([]models.Message{{Id: 6}}, nil).Once()
+	mockRepo.On("GetMessagesAfter", uint64(2), uint64(6), uint64(10)).Return(nil, errors.New("repository error")).Once()
+
+	service := NewService(mockRepo)
+
+	messages, err := service.GetMessagesAfter(2, 5, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Message{{Id: 6}}, messages)
+
+	_, err = service.GetMessagesAfter(2, 6, 10)
+	assert.Equal(t, httperrors.InternalServerError("an error occurred while trying to get messages", errors.New("repository error")), err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetHistory(t *testing.T) {
+	mockRepo := new(repository.MockRepository)
+	mockRepo.On("GetMessagesBetweenUsers", uint64(1), uint64(2), uint64(0), uint64(100)).Return([]models.Message{{Id: 1}, {Id: 2}}, nil).Once()
+	mockRepo.On("GetMessagesBetweenUsers", uint64(1), uint64(3), uint64(0), uint64(100)).Return(nil, errors.New("repository error")).Once()
+
+	service := NewService(mockRepo)
+
+	messages, err := service.GetHistory(1, 2, 0, 100)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+
+	_, err = service.GetHistory(1, 3, 0, 100)
+	assert.Equal(t, httperrors.InternalServerError("an error occurred while trying to get messages", errors.New("repository error")), err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetMessagesPage(t *testing.T) {
+	tests := []struct {
+		name          string
+		cursor        string
+		direction     string
+		setupMocks    func(mockRepo *repository.MockRepository)
+		expectedPage  *models.MessagePage
+		expectedError error
+	}{
+		{
+			name: "first page",
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetMessagesPage", uint64(2), uint64(0), models.PageBefore, uint64(2)).
+					Return([]models.Message{{Id: 7}, {Id: 9}}, true, nil).Once()
+			},
+			expectedPage: &models.MessagePage{
+				Messages:   []models.Message{{Id: 7}, {Id: 9}},
+				NextCursor: helpers.EncodeCursor(7),
+				HasMore:    true,
+			},
+		},
+		{
+			name:      "after cursor",
+			cursor:    helpers.EncodeCursor(3),
+			direction: models.PageAfter,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetMessagesPage", uint64(2), uint64(3), models.PageAfter, uint64(2)).
+					Return([]models.Message{{Id: 5}}, false, nil).Once()
+			},
+			expectedPage: &models.MessagePage{
+				Messages:   []models.Message{{Id: 5}},
+				NextCursor: helpers.EncodeCursor(5),
+				HasMore:    false,
+			},
+		},
+		{
+			name:      "empty page keeps the cursor",
+			cursor:    helpers.EncodeCursor(9),
+			direction: models.PageAfter,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetMessagesPage", uint64(2), uint64(9), models.PageAfter, uint64(2)).
+					Return(nil, false, nil).Once()
+			},
+			expectedPage: &models.MessagePage{
+				Messages:   []models.Message{},
+				NextCursor: helpers.EncodeCursor(9),
+				HasMore:    false,
+			},
+		},
+		{
+			name:          "invalid cursor",
+			cursor:        "invalid",
+			setupMocks:    func(mockRepo *repository.MockRepository) {},
+			expectedError: httperrors.BadRequestError("invalid cursor"),
+		},
+		{
+			name:          "invalid direction",
+			direction:     "sideways",
+			setupMocks:    func(mockRepo *repository.MockRepository) {},
+			expectedError: httperrors.BadRequestError("invalid direction"),
+		},
+		{
+			name: "repository error",
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetMessagesPage", uint64(2), uint64(0), models.PageBefore, uint64(2)).
+					Return(nil, false, errors.New("repository error")).Once()
+			},
+			expectedError: httperrors.InternalServerError("an error occurred while trying to get messages", errors.New("repository error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			tt.setupMocks(mockRepo)
+
+			service := NewService(mockRepo)
+			page, err := service.GetMessagesPage(2, tt.cursor, tt.direction, 2)
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedPage, page)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetMessages_MarksDelivered(t *testing.T) {
+	delivered := time.Now()
+	mockRepo := new(repository.MockRepository)
+	mockRepo.On("GetMessagesFromUser", uint64(2), uint64(0), uint64(100)).Return([]models.Message{
+		{Id: 1, SenderID: 1, RecipientID: 2},
+		{Id: 2, SenderID: 1, RecipientID: 2, DeliveredAt: &delivered},
+		{Id: 3, SenderID: 1, RecipientID: 2},
+	}, nil).Once()
+	mockRepo.On("MarkMessagesDelivered", uint64(2), []uint64{1, 3}, mock.Anything).Return(nil).Once()
+
+	service := NewService(mockRepo)
+	messages, err := service.GetMessages(2, 0, 100)
+
+	assert.NoError(t, err)
+	for _, message := range messages {
+		assert.Equal(t, models.MessageStatusDelivered, message.Status())
+	}
+	assert.Equal(t, &delivered, messages[1].DeliveredAt)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetMessages_MarkDeliveredError(t *testing.T) {
+	mockRepo := new(repository.MockRepository)
+	mockRepo.On("GetMessagesFromUser", uint64(2), uint64(0), uint64(100)).Return([]models.Message{
+		{Id: 1, SenderID: 1, RecipientID: 2},
+	}, nil).Once()
+	mockRepo.On("MarkMessagesDelivered", uint64(2), []uint64{1}, mock.Anything).Return(errors.New("repository error")).Once()
+
+	service := NewService(mockRepo)
+	_, err := service.GetMessages(2, 0, 100)
+
+	assert.Equal(t, httperrors.InternalServerError("an error occurred while trying to mark messages as delivered", errors.New("repository error")), err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestMarkMessagesRead(t *testing.T) {
+	mockRepo := new(repository.MockRepository)
+	mockRepo.On("MarkMessagesRead", uint64(2), uint64(1), uint64(10), mock.Anything).Return(int64(3), nil).Once()
+	mockRepo.On("MarkMessagesRead", uint64(2), uint64(0), uint64(10), mock.Anything).Return(int64(0), errors.New("repository error")).Once()
+
+	service := NewService(mockRepo)
+
+	updated, err := service.MarkMessagesRead(2, 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), updated)
+
+	_, err = service.MarkMessagesRead(2, 0, 10)
+	assert.Equal(t, httperrors.InternalServerError("an error occurred while trying to mark messages as read", errors.New("repository error")), err)
+
+	_, err = service.MarkMessagesRead(2, 0, 0)
+	assert.Equal(t, httperrors.BadRequestError("invalid message id"), err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetMessageStatus(t *testing.T) {
+	read := time.Now()
+	tests := []struct {
+		name           string
+		userID         uint64
+		setupMocks     func(mockRepo *repository.MockRepository)
+		expectedStatus *models.MessageStatus
+		expectedError  error
+	}{
+		{
+			name:   "sender sees read status",
+			userID: 1,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetMessage", uint64(5)).Return(&models.Message{
+					Id: 5, SenderID: 1, RecipientID: 2, DeliveredAt: &read, ReadAt: &read,
+				}, nil).Once()
+			},
+			expectedStatus: &models.MessageStatus{ID: 5, Status: models.MessageStatusRead, DeliveredAt: &read, ReadAt: &read},
+		},
+		{
+			name:   "other users are rejected",
+			userID: 3,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetMessage", uint64(5)).Return(&models.Message{Id: 5, SenderID: 1, RecipientID: 2}, nil).Once()
+			},
+			expectedError: httperrors.ForbiddenError("you are not allowed to see this message"),
+		},
+		{
+			name:   "message not found",
+			userID: 1,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetMessage", uint64(5)).Return(nil, gorm.ErrRecordNotFound).Once()
+			},
+			expectedError: httperrors.NotFoundError("message not found"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			tt.setupMocks(mockRepo)
+
+			service := NewService(mockRepo)
+			status, err := service.GetMessageStatus(tt.userID, 5)
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.Equal(t, tt.expectedStatus, status)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetUnreadCounts(t *testing.T) {
+	mockRepo := new(repository.MockRepository)
+	mockRepo.On("CountUnreadMessages", uint64(2)).Return([]models.UnreadCount{
+		{SenderID: 1, Count: 2},
+		{SenderID: 3, Count: 1},
+	}, nil).Once()
+	mockRepo.On("CountUnreadMessages", uint64(4)).Return(nil, nil).Once()
+	mockRepo.On("CountUnreadMessages", uint64(5)).Return(nil, errors.New("repository error")).Once()
+
+	service := NewService(mockRepo)
+
+	summary, err := service.GetUnreadCounts(2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), summary.Total)
+	assert.Len(t, summary.Senders, 2)
+
+	summary, err = service.GetUnreadCounts(4)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.UnreadSummary{Senders: []models.UnreadCount{}}, summary)
+
+	_, err = service.GetUnreadCounts(5)
+	assert.Equal(t, httperrors.InternalServerError("an error occurred while trying to count unread messages", errors.New("repository error")), err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetInbox(t *testing.T) {
+	mockRepo := new(repository.MockRepository)
+	mockRepo.On("GetInbox", uint64(2), uint64(0), uint64(10)).Return([]models.InboxEntry{
+		{Peer: models.User{ID: 1}, LastMessage: models.Message{Id: 3}, UnreadCount: 1},
+	}, nil).Once()
+	mockRepo.On("GetInbox", uint64(5), uint64(0), uint64(10)).Return(nil, errors.New("repository error")).Once()
+
+	service := NewService(mockRepo)
+
+	entries, err := service.GetInbox(2, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	_, err = service.GetInbox(5, 0, 10)
+	assert.Equal(t, httperrors.InternalServerError("an error occurred while trying to get inbox", errors.New("repository error")), err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestEditMessage(t *testing.T) {
+	deleted := time.Now()
+	tests := []struct {
+		name          string
+		userID        uint64
+		text          string
+		setupMocks    func(mockRepo *repository.MockRepository)
+		expectedError error
+	}{
+		{
+			name:   "success",
+			userID: 1,
+			text:   "edited",
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetMessage", uint64(5)).Return(&models.Message{
+					Id: 5, SenderID: 1, RecipientID: 2, Content: models.Content{Type: "text", Text: "original"},
+				}, nil).Once()
+				mockRepo.On("UpdateMessage",
+					mock.MatchedBy(func(m *models.Message) bool {
+						return m.Content.Text == "edited" && m.EditedAt != nil
+					}),
+					mock.MatchedBy(func(r *models.MessageRevision) bool {
+						return r.MessageID == 5 && r.Action == models.RevisionActionEdit && r.Content.Text == "original"
+					}),
+				).Return(&models.Message{Id: 5}, nil).Once()
+			},
+		},
+		{
+			name:          "empty text",
+			userID:        1,
+			text:          "",
+			setupMocks:    func(mockRepo *repository.MockRepository) {},
+			expectedError: httperrors.BadRequestError("invalid message text"),
+		},
+		{
+			name:   "not the sender",
+			userID: 2,
+			text:   "edited",
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetMessage", uint64(5)).Return(&models.Message{Id: 5, SenderID: 1, RecipientID: 2}, nil).Once()
+			},
+			expectedError: httperrors.ForbiddenError("only the sender can change this message"),
+		},
+		{
+			name:   "deleted message",
+			userID: 1,
+			text:   "edited",
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetMessage", uint64(5)).Return(&models.Message{Id: 5, SenderID: 1, DeletedAt: &deleted}, nil).Once()
+			},
+			expectedError: httperrors.BadRequestError("message was deleted"),
+		},
+		{
+			name:   "message not found",
+			userID: 1,
+			text:   "edited",
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetMessage", uint64(5)).Return(nil, gorm.ErrRecordNotFound).Once()
+			},
+			expectedError: httperrors.NotFoundError("message not found"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			tt.setupMocks(mockRepo)
+
+			service := NewService(mockRepo)
+			_, err := service.EditMessage(tt.userID, 5, tt.text)
+
+			assert.Equal(t, tt.expectedError, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeleteMessage(t *testing.T) {
+	tests := []struct {
+		name          string
+		updateError   error
+		expectedError error
+	}{
+		{
+			name:          "success",
+			updateError:   nil,
+			expectedError: nil,
+		},
+		{
+			name:          "repository error",
+			updateError:   errors.New("repository error"),
+			expectedError: httperrors.InternalServerError("an error occurred while trying to delete message", errors.New("repository error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			mockRepo.On("GetMessage", uint64(5)).Return(&models.Message{
+				Id: 5, SenderID: 1, RecipientID: 2, Content: models.Content{Type: "text", Text: "original"},
+			}, nil).Once()
+			mockRepo.On("UpdateMessage",
+				mock.MatchedBy(func(m *models.Message) bool {
+					return m.DeletedAt != nil && m.Content.Text == "original"
+				}),
+				mock.MatchedBy(func(r *models.MessageRevision) bool {
+					return r.Action == models.RevisionActionDelete && r.Content.Text == "original"
+				}),
+			).Return(&models.Message{Id: 5}, tt.updateError).Once()
+
+			service := NewService(mockRepo)
+			err := service.DeleteMessage(1, 5)
+
+			assert.Equal(t, tt.expectedError, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetMessageRevisions(t *testing.T) {
+	mockRepo := new(repository.MockRepository)
+	mockRepo.On("GetMessage", uint64(5)).Return(&models.Message{Id: 5, SenderID: 1, RecipientID: 2}, nil)
+	mockRepo.On("GetMessageRevisions", uint64(5)).Return([]models.MessageRevision{{ID: 1, MessageID: 5}}, nil).Twice()
+	mockRepo.On("GetUser", uint64(2)).Return(&models.User{ID: 2, Role: models.RoleUser}, nil).Once()
+	mockRepo.On("GetUser", uint64(3)).Return(&models.User{ID: 3, Role: models.RoleAdmin}, nil).Once()
+
+	service := NewService(mockRepo)
+
+	revisions, err := service.GetMessageRevisions(1, 5)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 1)
+
+	_, err = service.GetMessageRevisions(2, 5)
+	assert.Equal(t, httperrors.ForbiddenError("you are not allowed to see this message"), err)
+
+	revisions, err = service.GetMessageRevisions(3, 5)
+	assert.NoError(t, err)
+	assert.Len(t, revisions, 1)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSendMessage_SupportPolicy(t *testing.T) {
+	users := map[uint64]*models.User{
+		1: {ID: 1, Role: models.RoleUser},
+		2: {ID: 2, Role: models.RoleUser},
+		3: {ID: 3, Role: models.RoleAgent},
+	}
+
+	tests := []struct {
+		name          string
+		sender        uint64
+		recipient     uint64
+		expectedError error
+	}{
+		{name: "customer to agent", sender: 1, recipient: 3},
+		{name: "agent to customer", sender: 3, recipient: 1},
+		{name: "customer to customer", sender: 1, recipient: 2, expectedError: httperrors.ForbiddenError("customers can only message agents")},
+		{name: "unknown recipient", sender: 1, recipient: 9, expectedError: httperrors.BadRequestError("recipient not found")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			for id, user := range users {
+				mockRepo.On("GetUser", id).Return(user, nil).Maybe()
+			}
+			mockRepo.On("GetUser", uint64(9)).Return(nil, gorm.ErrRecordNotFound).Maybe()
+			mockRepo.On("IsBlocked", mock.Anything, mock.Anything).Return(false, nil).Maybe()
+			mockRepo.On("SaveMessage", mock.Anything).Return(&models.Message{Id: 1, SenderID: tt.sender, RecipientID: tt.recipient}, nil).Maybe()
+
+			service := NewService(mockRepo, WithMessagingPolicy(MessagingPolicySupport))
+
+			_, err := service.SendMessage(tt.sender, tt.recipient, &models.Content{Type: "text", Text: "hello"})
+			assert.Equal(t, tt.expectedError, err)
+		})
+	}
+}
+
+func TestSendMessage_Privacy(t *testing.T) {
+	tests := []struct {
+		name          string
+		sender        *models.User
+		recipient     *models.User
+		setupMocks    func(mockRepo *repository.MockRepository)
+		expectedError error
+	}{
+		{
+			name:      "blocked by recipient",
+			sender:    &models.User{ID: 1, Role: models.RoleUser},
+			recipient: &models.User{ID: 2, Role: models.RoleUser},
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("IsBlocked", uint64(2), uint64(1)).Return(true, nil).Once()
+			},
+			expectedError: httperrors.ForbiddenError("you cannot message this user"),
+		},
+		{
+			name:      "blocked agent",
+			sender:    &models.User{ID: 1, Role: models.RoleAgent},
+			recipient: &models.User{ID: 2, Role: models.RoleUser},
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("IsBlocked", uint64(2), uint64(1)).Return(true, nil).Once()
+			},
+			expectedError: httperrors.ForbiddenError("you cannot message this user"),
+		},
+		{
+			name:      "contacts only from a contact",
+			sender:    &models.User{ID: 1, Role: models.RoleUser},
+			recipient: &models.User{ID: 2, Role: models.RoleUser, ContactsOnly: true},
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("IsBlocked", uint64(2), uint64(1)).Return(false, nil).Once()
+				mockRepo.On("HasSentMessage", uint64(2), uint64(1)).Return(true, nil).Once()
+			},
+		},
+		{
+			name:      "contacts only from a stranger",
+			sender:    &models.User{ID: 1, Role: models.RoleUser},
+			recipient: &models.User{ID: 2, Role: models.RoleUser, ContactsOnly: true},
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("IsBlocked", uint64(2), uint64(1)).Return(false, nil).Once()
+				mockRepo.On("HasSentMessage", uint64(2), uint64(1)).Return(false, nil).Once()
+			},
+			expectedError: httperrors.ForbiddenError("this user only accepts messages from contacts"),
+		},
+		{
+			name:      "contacts only from an agent",
+			sender:    &models.User{ID: 1, Role: models.RoleAgent},
+			recipient: &models.User{ID: 2, Role: models.RoleUser, ContactsOnly: true},
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("IsBlocked", uint64(2), uint64(1)).Return(false, nil).Once()
+			},
+		},
+		{
+			name:          "deleted recipient",
+			sender:        &models.User{ID: 1, Role: models.RoleUser},
+			recipient:     &models.User{ID: 2, DeletedAt: &time.Time{}},
+			setupMocks:    func(mockRepo *repository.MockRepository) {},
+			expectedError: httperrors.BadRequestError("recipient not found"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			mockRepo.On("GetUser", tt.recipient.ID).Return(tt.recipient, nil).Once()
+			mockRepo.On("GetUser", tt.sender.ID).Return(tt.sender, nil).Maybe()
+			tt.setupMocks(mockRepo)
+			if tt.expectedError == nil {
+				mockRepo.On("SaveMessage", mock.Anything).Return(&models.Message{Id: 1, SenderID: 1, RecipientID: 2}, nil).Once()
+			}
+
+			service := NewService(mockRepo)
+			_, err := service.SendMessage(tt.sender.ID, tt.recipient.ID, &models.Content{Type: "text", Text: "hello"})
+
+			assert.Equal(t, tt.expectedError, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSendMessage_ContentValidation(t *testing.T) {
+	tests := []struct {
+		name            string
+		content         *models.Content
+		expectedContent models.Content
+		expectedError   error
+	}{
+		{
+			name:            "text drops media fields",
+			content:         &models.Content{Type: "text", Text: "Hello", URL: "https://example.com/a.png"},
+			expectedContent: models.Content{Type: "text", Text: "Hello"},
+		},
+		{
+			name:          "text without text",
+			content:       &models.Content{Type: "text"},
+			expectedError: httperrors.BadRequestError("invalid message text"),
+		},
+		{
+			name: "image",
+			content: &models.Content{
+				Type: "image", Text: "ignored", URL: "https://example.com/a.png", Width: 640, Height: 480, MimeType: "image/png",
+			},
+			expectedContent: models.Content{
+				Type: "image", URL: "https://example.com/a.png", Width: 640, Height: 480, MimeType: "image/png",
+			},
+		},
+		{
+			name:          "image with invalid url",
+			content:       &models.Content{Type: "image", URL: "ftp://example.com/a.png", Width: 640, Height: 480, MimeType: "image/png"},
+			expectedError: httperrors.BadRequestError("invalid image url"),
+		},
+		{
+			name:          "image without dimensions",
+			content:       &models.Content{Type: "image", URL: "https://example.com/a.png", Width: 640, MimeType: "image/png"},
+			expectedError: httperrors.BadRequestError("invalid image dimensions"),
+		},
+		{
+			name:          "image with invalid mime type",
+			content:       &models.Content{Type: "image", URL: "https://example.com/a.png", Width: 640, Height: 480, MimeType: "text/html"},
+			expectedError: httperrors.BadRequestError("invalid image mime type"),
+		},
+		{
+			name:            "video",
+			content:         &models.Content{Type: "video", URL: "https://youtu.be/abc", Duration: 90, Source: "youtube"},
+			expectedContent: models.Content{Type: "video", URL: "https://youtu.be/abc", Duration: 90, Source: "youtube"},
+		},
+		{
+			name:          "video with invalid url",
+			content:       &models.Content{Type: "video", URL: "not a url", Duration: 90, Source: "youtube"},
+			expectedError: httperrors.BadRequestError("invalid video url"),
+		},
+		{
+			name:          "video without duration",
+			content:       &models.Content{Type: "video", URL: "https://youtu.be/abc", Source: "youtube"},
+			expectedError: httperrors.BadRequestError("invalid video duration"),
+		},
+		{
+			name:          "video with invalid source",
+			content:       &models.Content{Type: "video", URL: "https://youtu.be/abc", Duration: 90, Source: "dailymotion"},
+			expectedError: httperrors.BadRequestError("invalid video source"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			expectRecipient(mockRepo, 1, 2)
+			if tt.expectedError == nil {
+				mockRepo.On("SaveMessage", mock.MatchedBy(func(m *models.Message) bool {
+					return m.Content == tt.expectedContent
+				})).Return(&models.Message{Id: 1, RecipientID: 2}, nil).Once()
+			}
+
+			service := NewService(mockRepo)
+			_, err := service.SendMessage(1, 2, tt.content)
+
+			assert.Equal(t, tt.expectedError, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestEditMessage_OnlyText(t *testing.T) {
+	mockRepo := new(repository.MockRepository)
+	mockRepo.On("GetMessage", uint64(5)).Return(&models.Message{
+		Id: 5, SenderID: 1, Content: models.Content{Type: "image", URL: "https://example.com/a.png"},
+	}, nil).Once()
+
+	service := NewService(mockRepo)
+	_, err := service.EditMessage(1, 5, "caption")
+
+	assert.Equal(t, httperrors.BadRequestError("only text messages can be edited"), err)
+	mockRepo.AssertExpectations(t)
+}
+
+// expectRecipient sets up the lookups done before a direct message is sent to
+// a recipient that accepts messages from everyone
+func expectRecipient(mockRepo *repository.MockRepository, sender, recipient uint64) {
+	mockRepo.On("GetUser", recipient).Return(&models.User{ID: recipient, Role: models.RoleUser}, nil).Once()
+	mockRepo.On("IsBlocked", recipient, sender).Return(false, nil).Once()
+}
@@ -1,8 +1,15 @@
 package service
 
 import (
+	"io"
+
+	"github.com/challenge/pkg/hub"
+	"github.com/challenge/pkg/jobs"
+	"github.com/challenge/pkg/lockout"
 	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/password"
 	"github.com/challenge/pkg/repository"
+	"github.com/challenge/pkg/storage"
 )
 
 type Service interface {
@@ -10,17 +17,146 @@ type Service interface {
 	CreateUser(username, password string) (*models.User, error)
 	GetUser(id uint64) (*models.User, error)
 	GetUserByUsername(username string) (*models.User, error)
-	Login(username, password string) (uint64, string, error)
+	GetUserProfile(id uint64) (*models.User, error)
+	GetUserProfileByUsername(username string) (*models.User, error)
+	UpdateUserProfile(userID uint64, update models.ProfileUpdate) (*models.User, error)
+	ListUsers(after, limit uint64) ([]models.User, error)
+	SetUserDisabled(adminID, userID uint64, disabled bool) (*models.User, error)
+	AssignRole(adminID, userID uint64, role string) (*models.User, error)
+	DeleteAccount(userID uint64, password string) (*models.AccountDeletion, error)
+	DeleteUser(adminID, userID uint64) (*models.AccountDeletion, error)
+	GetAccountDeletion(userID uint64) (*models.AccountDeletion, error)
+	ProcessAccountDeletions() (bool, error)
+	RequestDataExport(userID uint64) (*models.DataExport, string, error)
+	GetDataExport(userID, exportID uint64) (*models.DataExport, error)
+	DownloadDataExport(userID, exportID uint64, token string) (*models.DataExport, io.ReadCloser, error)
+	ProcessDataExports() (bool, error)
+	BlockUser(userID, blockedID uint64) (*models.Block, error)
+	UnblockUser(userID, blockedID uint64) error
+	GetBlockedUsers(userID uint64) ([]models.Block, error)
+	SetContactsOnly(userID uint64, contactsOnly bool) (*models.User, error)
+	ChangePassword(userID uint64, oldPassword, newPassword string) error
+	Login(username, password, ip string) (*models.AuthTokens, error)
+	RefreshSession(refreshToken string) (*models.AuthTokens, error)
+	Logout(userID uint64, sessionID string) error
+	EnrollTOTP(userID uint64) (*models.TOTPEnrollment, error)
+	ConfirmTOTP(userID uint64, code string) ([]string, error)
+	VerifyLoginChallenge(challengeToken, code, ip string) (*models.AuthTokens, error)
 	SendMessage(sender, recipient uint64, content *models.Content) (*models.Message, error)
 	GetMessages(id, start, limit uint64) ([]models.Message, error)
+	GetMessagesPage(id uint64, cursor, direction string, limit uint64) (*models.MessagePage, error)
+	GetMessagesAfter(id, after, limit uint64) ([]models.Message, error)
+	MarkMessagesRead(recipient, sender, upTo uint64) (int64, error)
+	GetMessageStatus(userID, messageID uint64) (*models.MessageStatus, error)
+	GetUnreadCounts(userID uint64) (*models.UnreadSummary, error)
+	GetInbox(userID, before, limit uint64) ([]models.InboxEntry, error)
+	EditMessage(userID, messageID uint64, text string) (*models.Message, error)
+	DeleteMessage(userID, messageID uint64) error
+	GetMessageRevisions(userID, messageID uint64) ([]models.MessageRevision, error)
+	GetHistory(id, peer, start, limit uint64) ([]models.Message, error)
+	CreateConversation(ownerID uint64, name string, memberIDs []uint64) (*models.Conversation, error)
+	GetConversation(userID, conversationID uint64) (*models.Conversation, error)
+	AddConversationMember(requester, conversationID, userID uint64, role string) (*models.ConversationMember, error)
+	RemoveConversationMember(requester, conversationID, userID uint64) error
+	LeaveConversation(userID, conversationID uint64) error
+	SendConversationMessage(sender, conversationID uint64, content *models.Content) (*models.Message, error)
+	GetConversationMessages(userID, conversationID, start, limit uint64) ([]models.Message, error)
+	Subscribe(userID uint64) *hub.Subscription
+	Unsubscribe(sub *hub.Subscription)
+	UploadAttachment(ownerID uint64, fileName string, r io.Reader) (*models.Attachment, error)
+	GetAttachment(userID, attachmentID uint64) (*models.Attachment, io.ReadCloser, error)
 }
 
 type ServiceImpl struct {
-	Repository repository.Repository
+	Repository     repository.Repository
+	Hub            *hub.Hub
+	Storage        storage.Storage
+	Lockout        *lockout.Guard
+	Passwords      password.Policy
+	Hasher         *password.Hasher
+	Messaging      string
+	DeletionPolicy string
+	Deletions      *jobs.Worker
+	Exports        *jobs.Worker
+}
+
+// Messaging policies decide who users can send direct messages to
+const (
+	// MessagingPolicyOpen lets every user message any other user
+	MessagingPolicyOpen = "open"
+	// MessagingPolicySupport only lets customers, users with the user role,
+	// message agents and admins, who can message anyone
+	MessagingPolicySupport = "support"
+)
+
+// Option configures optional dependencies of the service
+type Option func(*ServiceImpl)
+
+// WithStorage sets the storage used to keep attachment blobs
+func WithStorage(store storage.Storage) Option {
+	return func(s *ServiceImpl) {
+		s.Storage = store
+	}
 }
 
-func NewService(repo repository.Repository) Service {
-	return &ServiceImpl{
-		Repository: repo,
+// WithLoginLockout sets the guard that slows down and locks out failed logins
+func WithLoginLockout(guard *lockout.Guard) Option {
+	return func(s *ServiceImpl) {
+		s.Lockout = guard
 	}
 }
+
+// WithPasswordPolicy sets the requirements of new passwords
+func WithPasswordPolicy(policy password.Policy) Option {
+	return func(s *ServiceImpl) {
+		s.Passwords = policy
+	}
+}
+
+// WithPasswordHasher sets the hasher of new passwords, which also decides
+// which stored hashes are outdated and get rehashed on login
+func WithPasswordHasher(hasher *password.Hasher) Option {
+	return func(s *ServiceImpl) {
+		s.Hasher = hasher
+	}
+}
+
+// WithMessagingPolicy sets who users can send direct messages to
+func WithMessagingPolicy(policy string) Option {
+	return func(s *ServiceImpl) {
+		s.Messaging = policy
+	}
+}
+
+// WithAccountDeletion sets the policy applied to the messages of deleted users
+// and the worker woken up to process new deletions
+func WithAccountDeletion(policy string, worker *jobs.Worker) Option {
+	return func(s *ServiceImpl) {
+		s.DeletionPolicy = policy
+		s.Deletions = worker
+	}
+}
+
+// WithDataExports sets the worker woken up to build new data exports
+func WithDataExports(worker *jobs.Worker) Option {
+	return func(s *ServiceImpl) {
+		s.Exports = worker
+	}
+}
+
+func NewService(repo repository.Repository, opts ...Option) Service {
+	s := &ServiceImpl{
+		Repository:     repo,
+		Hub:            hub.NewHub(),
+		Lockout:        lockout.NewGuard(lockout.DefaultConfig(), lockout.NewMemoryStore()),
+		Passwords:      password.DefaultPolicy(),
+		Hasher:         password.DefaultHasher(),
+		Messaging:      MessagingPolicyOpen,
+		DeletionPolicy: models.DeletionPolicyAnonymize,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
@@ -1,6 +1,9 @@
 package service
 
 import (
+	"io"
+
+	"github.com/challenge/pkg/hub"
 	"github.com/challenge/pkg/models"
 	"github.com/stretchr/testify/mock"
 )
@@ -38,9 +41,189 @@ func (m *MockService) GetUserByUsername(username string) (*models.User, error) {
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockService) Login(username, password string) (uint64, string, error) {
-	args := m.Called(username, password)
-	return args.Get(0).(uint64), args.String(1), args.Error(2)
+func (m *MockService) GetUserProfile(id uint64) (*models.User, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockService) GetUserProfileByUsername(username string) (*models.User, error) {
+	args := m.Called(username)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockService) UpdateUserProfile(userID uint64, update models.ProfileUpdate) (*models.User, error) {
+	args := m.Called(userID, update)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockService) ListUsers(after, limit uint64) ([]models.User, error) {
+	args := m.Called(after, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+func (m *MockService) SetUserDisabled(adminID, userID uint64, disabled bool) (*models.User, error) {
+	args := m.Called(adminID, userID, disabled)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockService) AssignRole(adminID, userID uint64, role string) (*models.User, error) {
+	args := m.Called(adminID, userID, role)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockService) DeleteAccount(userID uint64, password string) (*models.AccountDeletion, error) {
+	args := m.Called(userID, password)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AccountDeletion), args.Error(1)
+}
+
+func (m *MockService) DeleteUser(adminID, userID uint64) (*models.AccountDeletion, error) {
+	args := m.Called(adminID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AccountDeletion), args.Error(1)
+}
+
+func (m *MockService) GetAccountDeletion(userID uint64) (*models.AccountDeletion, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AccountDeletion), args.Error(1)
+}
+
+func (m *MockService) ProcessAccountDeletions() (bool, error) {
+	args := m.Called()
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockService) RequestDataExport(userID uint64) (*models.DataExport, string, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, "", args.Error(2)
+	}
+	return args.Get(0).(*models.DataExport), args.String(1), args.Error(2)
+}
+
+func (m *MockService) GetDataExport(userID, exportID uint64) (*models.DataExport, error) {
+	args := m.Called(userID, exportID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DataExport), args.Error(1)
+}
+
+func (m *MockService) DownloadDataExport(userID, exportID uint64, token string) (*models.DataExport, io.ReadCloser, error) {
+	args := m.Called(userID, exportID, token)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.DataExport), args.Get(1).(io.ReadCloser), args.Error(2)
+}
+
+func (m *MockService) ProcessDataExports() (bool, error) {
+	args := m.Called()
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockService) BlockUser(userID, blockedID uint64) (*models.Block, error) {
+	args := m.Called(userID, blockedID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Block), args.Error(1)
+}
+
+func (m *MockService) UnblockUser(userID, blockedID uint64) error {
+	args := m.Called(userID, blockedID)
+	return args.Error(0)
+}
+
+func (m *MockService) GetBlockedUsers(userID uint64) ([]models.Block, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Block), args.Error(1)
+}
+
+func (m *MockService) SetContactsOnly(userID uint64, contactsOnly bool) (*models.User, error) {
+	args := m.Called(userID, contactsOnly)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockService) ChangePassword(userID uint64, oldPassword, newPassword string) error {
+	args := m.Called(userID, oldPassword, newPassword)
+	return args.Error(0)
+}
+
+func (m *MockService) Login(username, password, ip string) (*models.AuthTokens, error) {
+	args := m.Called(username, password, ip)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AuthTokens), args.Error(1)
+}
+
+func (m *MockService) RefreshSession(refreshToken string) (*models.AuthTokens, error) {
+	args := m.Called(refreshToken)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AuthTokens), args.Error(1)
+}
+
+func (m *MockService) Logout(userID uint64, sessionID string) error {
+	args := m.Called(userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *MockService) EnrollTOTP(userID uint64) (*models.TOTPEnrollment, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TOTPEnrollment), args.Error(1)
+}
+
+func (m *MockService) ConfirmTOTP(userID uint64, code string) ([]string, error) {
+	args := m.Called(userID, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockService) VerifyLoginChallenge(challengeToken, code, ip string) (*models.AuthTokens, error) {
+	args := m.Called(challengeToken, code, ip)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AuthTokens), args.Error(1)
 }
 
 func (m *MockService) SendMessage(sender, recipient uint64, content *models.Content) (*models.Message, error) {
@@ -58,3 +241,155 @@ func (m *MockService) GetMessages(id, start, limit uint64) ([]models.Message, er
 	}
 	return args.Get(0).([]models.Message), args.Error(1)
 }
+
+func (m *MockService) GetMessagesPage(id uint64, cursor, direction string, limit uint64) (*models.MessagePage, error) {
+	args := m.Called(id, cursor, direction, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MessagePage), args.Error(1)
+}
+
+func (m *MockService) GetMessagesAfter(id, after, limit uint64) ([]models.Message, error) {
+	args := m.Called(id, after, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Message), args.Error(1)
+}
+
+func (m *MockService) MarkMessagesRead(recipient, sender, upTo uint64) (int64, error) {
+	args := m.Called(recipient, sender, upTo)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockService) GetMessageStatus(userID, messageID uint64) (*models.MessageStatus, error) {
+	args := m.Called(userID, messageID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MessageStatus), args.Error(1)
+}
+
+func (m *MockService) GetUnreadCounts(userID uint64) (*models.UnreadSummary, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UnreadSummary), args.Error(1)
+}
+
+func (m *MockService) GetInbox(userID, before, limit uint64) ([]models.InboxEntry, error) {
+	args := m.Called(userID, before, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.InboxEntry), args.Error(1)
+}
+
+func (m *MockService) EditMessage(userID, messageID uint64, text string) (*models.Message, error) {
+	args := m.Called(userID, messageID, text)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Message), args.Error(1)
+}
+
+func (m *MockService) DeleteMessage(userID, messageID uint64) error {
+	args := m.Called(userID, messageID)
+	return args.Error(0)
+}
+
+func (m *MockService) GetMessageRevisions(userID, messageID uint64) ([]models.MessageRevision, error) {
+	args := m.Called(userID, messageID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.MessageRevision), args.Error(1)
+}
+
+func (m *MockService) CreateConversation(ownerID uint64, name string, memberIDs []uint64) (*models.Conversation, error) {
+	args := m.Called(ownerID, name, memberIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Conversation), args.Error(1)
+}
+
+func (m *MockService) GetConversation(userID, conversationID uint64) (*models.Conversation, error) {
+	args := m.Called(userID, conversationID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Conversation), args.Error(1)
+}
+
+func (m *MockService) AddConversationMember(requester, conversationID, userID uint64, role string) (*models.ConversationMember, error) {
+	args := m.Called(requester, conversationID, userID, role)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ConversationMember), args.Error(1)
+}
+
+func (m *MockService) RemoveConversationMember(requester, conversationID, userID uint64) error {
+	args := m.Called(requester, conversationID, userID)
+	return args.Error(0)
+}
+
+func (m *MockService) LeaveConversation(userID, conversationID uint64) error {
+	args := m.Called(userID, conversationID)
+	return args.Error(0)
+}
+
+func (m *MockService) SendConversationMessage(sender, conversationID uint64, content *models.Content) (*models.Message, error) {
+	args := m.Called(sender, conversationID, content)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Message), args.Error(1)
+}
+
+func (m *MockService) GetConversationMessages(userID, conversationID, start, limit uint64) ([]models.Message, error) {
+	args := m.Called(userID, conversationID, start, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Message), args.Error(1)
+}
+
+func (m *MockService) GetHistory(id, peer, start, limit uint64) ([]models.Message, error) {
+	args := m.Called(id, peer, start, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Message), args.Error(1)
+}
+
+func (m *MockService) Subscribe(userID uint64) *hub.Subscription {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(*hub.Subscription)
+}
+
+func (m *MockService) Unsubscribe(sub *hub.Subscription) {
+	m.Called(sub)
+}
+
+func (m *MockService) UploadAttachment(ownerID uint64, fileName string, r io.Reader) (*models.Attachment, error) {
+	args := m.Called(ownerID, fileName, r)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Attachment), args.Error(1)
+}
+
+func (m *MockService) GetAttachment(userID, attachmentID uint64) (*models.Attachment, io.ReadCloser, error) {
+	args := m.Called(userID, attachmentID)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*models.Attachment), args.Get(1).(io.ReadCloser), args.Error(2)
+}
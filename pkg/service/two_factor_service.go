@@ -0,0 +1,229 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/challenge/pkg/auth"
+	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/helpers"
+	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/repository"
+	"github.com/challenge/pkg/totp"
+	"gorm.io/gorm"
+)
+
+const (
+	// LoginChallengeTTL is how long a challenge token can be exchanged for a session
+	LoginChallengeTTL = 5 * time.Minute
+	// MaxLoginChallengeAttempts is the number of wrong codes a challenge accepts
+	MaxLoginChallengeAttempts = 5
+	// RecoveryCodeCount is the number of recovery codes handed out when enabling 2FA
+	RecoveryCodeCount = 10
+	// totpSkew is the number of time steps of clock drift accepted either way
+	totpSkew = 1
+)
+
+// EnrollTOTP generates a new TOTP secret for the user. Two-factor authentication
+// is not enabled until the secret is confirmed with ConfirmTOTP.
+func (s ServiceImpl) EnrollTOTP(userID uint64) (*models.TOTPEnrollment, error) {
+	user, err := s.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := s.Repository.GetTOTPCredential(userID)
+	if err == nil && credential.ConfirmedAt != nil {
+		return nil, httperrors.BadRequestError("two-factor authentication is already enabled")
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, httperrors.InternalServerError("an error occurred while trying to enroll two-factor authentication", err)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to enroll two-factor authentication", err)
+	}
+
+	if _, err := s.Repository.SaveTOTPCredential(&models.TOTPCredential{UserID: userID, Secret: secret}); err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to enroll two-factor authentication", err)
+	}
+
+	return &models.TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(auth.Issuer, user.Username, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication once the user proves their
+// authenticator app works, and returns the recovery codes. They are only
+// shown this time.
+func (s ServiceImpl) ConfirmTOTP(userID uint64, code string) ([]string, error) {
+	credential, err := s.Repository.GetTOTPCredential(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, httperrors.BadRequestError("two-factor authentication enrollment not started")
+	} else if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to confirm two-factor authentication", err)
+	}
+	if credential.ConfirmedAt != nil {
+		return nil, httperrors.BadRequestError("two-factor authentication is already enabled")
+	}
+
+	now := time.Now()
+	step, ok := totp.Validate(credential.Secret, code, now, totpSkew)
+	if !ok {
+		return nil, httperrors.BadRequestError("invalid two-factor code")
+	}
+
+	codes, recoveryCodes, err := generateRecoveryCodes(userID)
+	if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to confirm two-factor authentication", err)
+	}
+
+	err = s.Repository.ConfirmTOTPCredential(userID, step, recoveryCodes, now)
+	if errors.Is(err, repository.ErrTOTPAlreadyConfirmed) {
+		return nil, httperrors.BadRequestError("two-factor authentication is already enabled")
+	} else if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to confirm two-factor authentication", err)
+	}
+
+	return codes, nil
+}
+
+// VerifyLoginChallenge exchanges the challenge token handed out by Login and a
+// TOTP or recovery code for the tokens of a new session. Wrong codes count as
+// failed logins of the user.
+func (s ServiceImpl) VerifyLoginChallenge(challengeToken, code, ip string) (*models.AuthTokens, error) {
+	now := time.Now()
+	challenge, err := s.Repository.GetLoginChallenge(hashToken(challengeToken))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, httperrors.UnauthorizedError("invalid challenge token")
+	} else if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to login", err)
+	}
+	if !challenge.ExpiresAt.After(now) || challenge.Attempts >= MaxLoginChallengeAttempts {
+		return nil, httperrors.UnauthorizedError("invalid challenge token")
+	}
+
+	user, err := s.GetUser(challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.DisabledAt != nil {
+		return nil, httperrors.ForbiddenError("account is disabled")
+	}
+
+	userKey, ipKey := s.Lockout.UserKey(user.Username), s.Lockout.IPKey(ip)
+	wait, err := s.Lockout.Check(userKey, ipKey)
+	if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to login", err)
+	}
+	if wait > 0 {
+		return nil, httperrors.TooManyRequestsError("Too many failed login attempts", wait)
+	}
+
+	valid, err := s.verifySecondFactor(user.ID, code, now)
+	if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to login", err)
+	}
+	if !valid {
+		if err := s.Repository.FailLoginChallenge(challenge.ID); err != nil {
+			return nil, httperrors.InternalServerError("an error occurred while trying to login", err)
+		}
+
+		return nil, s.loginFailed(user.Username, ip, "invalid two-factor code", userKey, ipKey)
+	}
+
+	consumed, err := s.Repository.DeleteLoginChallenge(challenge.ID)
+	if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to login", err)
+	}
+	if !consumed {
+		return nil, httperrors.UnauthorizedError("invalid challenge token")
+	}
+
+	if err := s.Lockout.Reset(userKey); err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to login", err)
+	}
+
+	tokens, err := s.startSession(user)
+	if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to login", err)
+	}
+
+	return tokens, nil
+}
+
+func (s ServiceImpl) twoFactorEnabled(userID uint64) (bool, error) {
+	credential, err := s.Repository.GetTOTPCredential(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return credential.ConfirmedAt != nil, nil
+}
+
+// startLoginChallenge hands out the challenge token of the second login step
+func (s ServiceImpl) startLoginChallenge(userID uint64) (*models.AuthTokens, error) {
+	token, err := helpers.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.Repository.CreateLoginChallenge(&models.LoginChallenge{
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(LoginChallengeTTL),
+	}); err != nil {
+		return nil, err
+	}
+
+	return &models.AuthTokens{
+		UserID:         userID,
+		ChallengeToken: token,
+		ExpiresIn:      int64(LoginChallengeTTL.Seconds()),
+	}, nil
+}
+
+// verifySecondFactor checks a TOTP code, which cannot be used twice, or else
+// uses up a recovery code
+func (s ServiceImpl) verifySecondFactor(userID uint64, code string, now time.Time) (bool, error) {
+	credential, err := s.Repository.GetTOTPCredential(userID)
+	if err != nil {
+		return false, err
+	}
+
+	if step, ok := totp.Validate(credential.Secret, code, now, totpSkew); ok {
+		return s.Repository.UseTOTPStep(userID, step)
+	}
+
+	return s.Repository.UseRecoveryCode(userID, hashRecoveryCode(code), now)
+}
+
+// generateRecoveryCodes returns the recovery codes to show to the user and the
+// hashed ones to store
+func generateRecoveryCodes(userID uint64) ([]string, []models.RecoveryCode, error) {
+	codes := make([]string, 0, RecoveryCodeCount)
+	recoveryCodes := make([]models.RecoveryCode, 0, RecoveryCodeCount)
+	for i := 0; i < RecoveryCodeCount; i++ {
+		token, err := helpers.RandomToken(8)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		code := token[0:4] + "-" + token[4:8] + "-" + token[8:12] + "-" + token[12:16]
+		codes = append(codes, code)
+		recoveryCodes = append(recoveryCodes, models.RecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(code)})
+	}
+
+	return codes, recoveryCodes, nil
+}
+
+// hashRecoveryCode hashes a recovery code ignoring case and separators. They
+// have 64 random bits so a fast hash is enough.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return hashToken(code)
+}
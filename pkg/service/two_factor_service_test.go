@@ -0,0 +1,298 @@
+package service
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/challenge/pkg/auth"
+	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/password"
+	"github.com/challenge/pkg/repository"
+	"github.com/challenge/pkg/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const testTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func currentTOTPCode(t *testing.T) string {
+	code, err := totp.Code(testTOTPSecret, totp.Step(time.Now()))
+	assert.NoError(t, err)
+	return code
+}
+
+func TestServiceImpl_Login_TwoFactor(t *testing.T) {
+	confirmedAt := time.Now()
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+
+	mockRepo := new(repository.MockRepository)
+	mockRepo.On("GetUserByUsername", "testuser").Return(&models.User{ID: 1, Username: "testuser", Password: string(hashedPassword)}, nil).Once()
+	mockRepo.On("GetTOTPCredential", uint64(1)).Return(&models.TOTPCredential{UserID: 1, Secret: testTOTPSecret, ConfirmedAt: &confirmedAt}, nil).Once()
+	mockRepo.On("CreateLoginChallenge", mock.MatchedBy(func(challenge *models.LoginChallenge) bool {
+		return challenge.UserID == 1 && challenge.TokenHash != "" && challenge.ExpiresAt.After(time.Now())
+	})).Return(&models.LoginChallenge{}, nil).Once()
+
+	service := NewService(mockRepo, WithPasswordHasher(password.NewHasher(password.Bcrypt{Cost: bcrypt.MinCost})))
+	tokens, err := service.Login("testuser", "password123", "127.0.0.1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), tokens.UserID)
+	assert.NotEmpty(t, tokens.ChallengeToken)
+	assert.Empty(t, tokens.AccessToken)
+	assert.Empty(t, tokens.RefreshToken)
+	assert.Equal(t, int64(LoginChallengeTTL.Seconds()), tokens.ExpiresIn)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestServiceImpl_EnrollTOTP(t *testing.T) {
+	confirmedAt := time.Now()
+
+	tests := []struct {
+		name          string
+		setupMocks    func(mockRepo *repository.MockRepository)
+		expectedError error
+	}{
+		{
+			name: "success",
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetTOTPCredential", uint64(1)).Return(nil, gorm.ErrRecordNotFound).Once()
+				mockRepo.On("SaveTOTPCredential", mock.MatchedBy(func(credential *models.TOTPCredential) bool {
+					return credential.UserID == 1 && credential.Secret != "" && credential.ConfirmedAt == nil
+				})).Return(&models.TOTPCredential{}, nil).Once()
+			},
+		},
+		{
+			name: "replaces a pending enrollment",
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetTOTPCredential", uint64(1)).Return(&models.TOTPCredential{UserID: 1, Secret: testTOTPSecret}, nil).Once()
+				mockRepo.On("SaveTOTPCredential", mock.MatchedBy(func(credential *models.TOTPCredential) bool {
+					return credential.Secret != testTOTPSecret
+				})).Return(&models.TOTPCredential{}, nil).Once()
+			},
+		},
+		{
+			name: "already enabled",
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetTOTPCredential", uint64(1)).Return(&models.TOTPCredential{UserID: 1, ConfirmedAt: &confirmedAt}, nil).Once()
+			},
+			expectedError: httperrors.BadRequestError("two-factor authentication is already enabled"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			mockRepo.On("GetUser", uint64(1)).Return(&models.User{ID: 1, Username: "testuser"}, nil).Once()
+			tt.setupMocks(mockRepo)
+
+			service := NewService(mockRepo)
+			enrollment, err := service.EnrollTOTP(1)
+
+			assert.Equal(t, tt.expectedError, err)
+			if tt.expectedError == nil {
+				uri, _ := url.Parse(enrollment.URI)
+				assert.Equal(t, "/"+auth.Issuer+":testuser", uri.Path)
+				assert.Equal(t, enrollment.Secret, uri.Query().Get("secret"))
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServiceImpl_ConfirmTOTP(t *testing.T) {
+	confirmedAt := time.Now()
+
+	tests := []struct {
+		name          string
+		code          func(t *testing.T) string
+		setupMocks    func(mockRepo *repository.MockRepository)
+		expectedError error
+	}{
+		{
+			name: "success",
+			code: currentTOTPCode,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetTOTPCredential", uint64(1)).Return(&models.TOTPCredential{UserID: 1, Secret: testTOTPSecret}, nil).Once()
+				mockRepo.On("ConfirmTOTPCredential", uint64(1), mock.Anything, mock.MatchedBy(func(codes []models.RecoveryCode) bool {
+					return len(codes) == RecoveryCodeCount && codes[0].UserID == 1 && codes[0].CodeHash != ""
+				}), mock.Anything).Return(nil).Once()
+			},
+		},
+		{
+			name: "invalid code",
+			code: func(t *testing.T) string { return "000000" },
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetTOTPCredential", uint64(1)).Return(&models.TOTPCredential{UserID: 1, Secret: testTOTPSecret}, nil).Once()
+			},
+			expectedError: httperrors.BadRequestError("invalid two-factor code"),
+		},
+		{
+			name: "enrollment not started",
+			code: currentTOTPCode,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetTOTPCredential", uint64(1)).Return(nil, gorm.ErrRecordNotFound).Once()
+			},
+			expectedError: httperrors.BadRequestError("two-factor authentication enrollment not started"),
+		},
+		{
+			name: "already enabled",
+			code: currentTOTPCode,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetTOTPCredential", uint64(1)).Return(&models.TOTPCredential{UserID: 1, Secret: testTOTPSecret, ConfirmedAt: &confirmedAt}, nil).Once()
+			},
+			expectedError: httperrors.BadRequestError("two-factor authentication is already enabled"),
+		},
+		{
+			name: "confirmed concurrently",
+			code: currentTOTPCode,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetTOTPCredential", uint64(1)).Return(&models.TOTPCredential{UserID: 1, Secret: testTOTPSecret}, nil).Once()
+				mockRepo.On("ConfirmTOTPCredential", uint64(1), mock.Anything, mock.Anything, mock.Anything).Return(repository.ErrTOTPAlreadyConfirmed).Once()
+			},
+			expectedError: httperrors.BadRequestError("two-factor authentication is already enabled"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			tt.setupMocks(mockRepo)
+
+			service := NewService(mockRepo)
+			codes, err := service.ConfirmTOTP(1, tt.code(t))
+
+			assert.Equal(t, tt.expectedError, err)
+			if tt.expectedError == nil {
+				assert.Len(t, codes, RecoveryCodeCount)
+				assert.Regexp(t, `^[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}$`, codes[0])
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServiceImpl_VerifyLoginChallenge(t *testing.T) {
+	auth.JwtSecret = []byte("testsecret")
+	confirmedAt := time.Now()
+	credential := &models.TOTPCredential{UserID: 1, Secret: testTOTPSecret, ConfirmedAt: &confirmedAt}
+	challenge := &models.LoginChallenge{ID: 7, UserID: 1, ExpiresAt: time.Now().Add(time.Minute)}
+
+	tests := []struct {
+		name          string
+		code          func(t *testing.T) string
+		setupMocks    func(mockRepo *repository.MockRepository)
+		expectedError error
+	}{
+		{
+			name: "totp code",
+			code: currentTOTPCode,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetLoginChallenge", hashToken("challenge")).Return(challenge, nil).Once()
+				mockRepo.On("GetUser", uint64(1)).Return(&models.User{ID: 1, Username: "testuser"}, nil).Once()
+				mockRepo.On("GetTOTPCredential", uint64(1)).Return(credential, nil).Once()
+				mockRepo.On("UseTOTPStep", uint64(1), mock.Anything).Return(true, nil).Once()
+				mockRepo.On("DeleteLoginChallenge", uint64(7)).Return(true, nil).Once()
+				mockRepo.On("CreateRefreshToken", mock.Anything).Return(&models.RefreshToken{}, nil).Once()
+			},
+		},
+		{
+			name: "recovery code",
+			code: func(t *testing.T) string { return "ABCD-0123-4567-89EF" },
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetLoginChallenge", hashToken("challenge")).Return(challenge, nil).Once()
+				mockRepo.On("GetUser", uint64(1)).Return(&models.User{ID: 1, Username: "testuser"}, nil).Once()
+				mockRepo.On("GetTOTPCredential", uint64(1)).Return(credential, nil).Once()
+				mockRepo.On("UseRecoveryCode", uint64(1), hashRecoveryCode("abcd0123456789ef"), mock.Anything).Return(true, nil).Once()
+				mockRepo.On("DeleteLoginChallenge", uint64(7)).Return(true, nil).Once()
+				mockRepo.On("CreateRefreshToken", mock.Anything).Return(&models.RefreshToken{}, nil).Once()
+			},
+		},
+		{
+			name: "replayed totp code",
+			code: currentTOTPCode,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetLoginChallenge", hashToken("challenge")).Return(challenge, nil).Once()
+				mockRepo.On("GetUser", uint64(1)).Return(&models.User{ID: 1, Username: "testuser"}, nil).Once()
+				mockRepo.On("GetTOTPCredential", uint64(1)).Return(credential, nil).Once()
+				mockRepo.On("UseTOTPStep", uint64(1), mock.Anything).Return(false, nil).Once()
+				mockRepo.On("FailLoginChallenge", uint64(7)).Return(nil).Once()
+			},
+			expectedError: httperrors.BadRequestError("invalid two-factor code"),
+		},
+		{
+			name: "wrong code",
+			code: func(t *testing.T) string { return "not-a-code" },
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetLoginChallenge", hashToken("challenge")).Return(challenge, nil).Once()
+				mockRepo.On("GetUser", uint64(1)).Return(&models.User{ID: 1, Username: "testuser"}, nil).Once()
+				mockRepo.On("GetTOTPCredential", uint64(1)).Return(credential, nil).Once()
+				mockRepo.On("UseRecoveryCode", uint64(1), mock.Anything, mock.Anything).Return(false, nil).Once()
+				mockRepo.On("FailLoginChallenge", uint64(7)).Return(nil).Once()
+			},
+			expectedError: httperrors.BadRequestError("invalid two-factor code"),
+		},
+		{
+			name: "challenge used concurrently",
+			code: currentTOTPCode,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetLoginChallenge", hashToken("challenge")).Return(challenge, nil).Once()
+				mockRepo.On("GetUser", uint64(1)).Return(&models.User{ID: 1, Username: "testuser"}, nil).Once()
+				mockRepo.On("GetTOTPCredential", uint64(1)).Return(credential, nil).Once()
+				mockRepo.On("UseTOTPStep", uint64(1), mock.Anything).Return(true, nil).Once()
+				mockRepo.On("DeleteLoginChallenge", uint64(7)).Return(false, nil).Once()
+			},
+			expectedError: httperrors.UnauthorizedError("invalid challenge token"),
+		},
+		{
+			name: "unknown challenge",
+			code: currentTOTPCode,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetLoginChallenge", hashToken("challenge")).Return(nil, gorm.ErrRecordNotFound).Once()
+			},
+			expectedError: httperrors.UnauthorizedError("invalid challenge token"),
+		},
+		{
+			name: "expired challenge",
+			code: currentTOTPCode,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetLoginChallenge", hashToken("challenge")).Return(&models.LoginChallenge{
+					ID: 7, UserID: 1, ExpiresAt: time.Now().Add(-time.Minute),
+				}, nil).Once()
+			},
+			expectedError: httperrors.UnauthorizedError("invalid challenge token"),
+		},
+		{
+			name: "too many attempts",
+			code: currentTOTPCode,
+			setupMocks: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetLoginChallenge", hashToken("challenge")).Return(&models.LoginChallenge{
+					ID: 7, UserID: 1, Attempts: MaxLoginChallengeAttempts, ExpiresAt: time.Now().Add(time.Minute),
+				}, nil).Once()
+			},
+			expectedError: httperrors.UnauthorizedError("invalid challenge token"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			tt.setupMocks(mockRepo)
+
+			service := NewService(mockRepo)
+			tokens, err := service.VerifyLoginChallenge("challenge", tt.code(t), "127.0.0.1")
+
+			assert.Equal(t, tt.expectedError, err)
+			if tt.expectedError == nil {
+				assert.Equal(t, uint64(1), tokens.UserID)
+				assert.NotEmpty(t, tokens.AccessToken)
+				assert.NotEmpty(t, tokens.RefreshToken)
+				assert.Empty(t, tokens.ChallengeToken)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
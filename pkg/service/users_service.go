@@ -3,12 +3,19 @@ package service
 import (
 	"errors"
 	httperrors "github.com/challenge/pkg/errors"
+	"github.com/challenge/pkg/helpers"
 	"github.com/challenge/pkg/models"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 func (s ServiceImpl) CreateUser(username, password string) (*models.User, error) {
+	if err := s.Passwords.Validate(username, password); err != nil {
+		return nil, httperrors.BadRequestError(err.Error())
+	}
+
 	user, err := s.Repository.GetUserByUsername(username)
 	if err == nil {
 		return nil, httperrors.BadRequestError("user already exists")
@@ -17,11 +24,15 @@ func (s ServiceImpl) CreateUser(username, password string) (*models.User, error)
 		return nil, httperrors.InternalServerError("an error occurred while trying to create user", err)
 	}
 
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := s.Hasher.Hash(password)
+	if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to create user", err)
+	}
 
 	user, err = s.Repository.CreateUser(&models.User{
 		Username: username,
-		Password: string(hashedPassword),
+		Password: hashedPassword,
+		Role:     models.RoleUser,
 	})
 	if err != nil {
 		return nil, httperrors.InternalServerError("an error occurred while trying to create user", err)
@@ -49,3 +60,104 @@ func (s ServiceImpl) GetUserByUsername(username string) (*models.User, error) {
 
 	return user, nil
 }
+
+// ChangePassword replaces the password of the user after checking the current
+// one, and revokes every session so they have to login again
+func (s ServiceImpl) ChangePassword(userID uint64, oldPassword, newPassword string) error {
+	user, err := s.GetUser(userID)
+	if err != nil {
+		return err
+	}
+
+	match, _, err := s.Hasher.Verify(user.Password, oldPassword)
+	if err != nil {
+		return httperrors.InternalServerError("an error occurred while trying to change password", err)
+	}
+	if !match {
+		return httperrors.BadRequestError("invalid current password")
+	}
+
+	if oldPassword == newPassword {
+		return httperrors.BadRequestError("new password must be different from the current one")
+	}
+
+	if err := s.Passwords.Validate(user.Username, newPassword); err != nil {
+		return httperrors.BadRequestError(err.Error())
+	}
+
+	hashedPassword, err := s.Hasher.Hash(newPassword)
+	if err != nil {
+		return httperrors.InternalServerError("an error occurred while trying to change password", err)
+	}
+
+	if err := s.Repository.UpdateUserPassword(userID, hashedPassword); err != nil {
+		return httperrors.InternalServerError("an error occurred while trying to change password", err)
+	}
+
+	if err := s.Repository.RevokeUserSessions(userID, time.Now()); err != nil {
+		return httperrors.InternalServerError("an error occurred while trying to change password", err)
+	}
+
+	return nil
+}
+
+// GetUserProfile returns the user to show to other users
+func (s ServiceImpl) GetUserProfile(id uint64) (*models.User, error) {
+	user, err := s.Repository.GetUser(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, httperrors.NotFoundError("user not found")
+	} else if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to get user", err)
+	}
+
+	return user, nil
+}
+
+// GetUserProfileByUsername looks up a user by their exact username
+func (s ServiceImpl) GetUserProfileByUsername(username string) (*models.User, error) {
+	user, err := s.Repository.GetUserByUsername(username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, httperrors.NotFoundError("user not found")
+	} else if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to get user", err)
+	}
+
+	return user, nil
+}
+
+// UpdateUserProfile changes the profile fields of the user set in the update.
+// Empty values clear the field.
+func (s ServiceImpl) UpdateUserProfile(userID uint64, update models.ProfileUpdate) (*models.User, error) {
+	if update.DisplayName != nil {
+		displayName := strings.TrimSpace(*update.DisplayName)
+		if utf8.RuneCountInString(displayName) > helpers.MaxDisplayNameLength {
+			return nil, httperrors.BadRequestError("display name is too long")
+		}
+		update.DisplayName = &displayName
+	}
+
+	if update.AvatarURL != nil {
+		avatarURL := strings.TrimSpace(*update.AvatarURL)
+		if avatarURL != "" && !validURL(avatarURL) {
+			return nil, httperrors.BadRequestError("invalid avatar url")
+		}
+		update.AvatarURL = &avatarURL
+	}
+
+	if update.StatusText != nil {
+		statusText := strings.TrimSpace(*update.StatusText)
+		if utf8.RuneCountInString(statusText) > helpers.MaxStatusTextLength {
+			return nil, httperrors.BadRequestError("status text is too long")
+		}
+		update.StatusText = &statusText
+	}
+
+	user, err := s.Repository.UpdateUserProfile(userID, update)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, httperrors.NotFoundError("user not found")
+	} else if err != nil {
+		return nil, httperrors.InternalServerError("an error occurred while trying to update profile", err)
+	}
+
+	return user, nil
+}
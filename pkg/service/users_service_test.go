@@ -4,11 +4,13 @@ import (
 	"errors"
 	httperrors "github.com/challenge/pkg/errors"
 	"github.com/challenge/pkg/models"
+	"github.com/challenge/pkg/password"
 	"github.com/challenge/pkg/repository"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"strings"
 	"testing"
 )
 
@@ -16,7 +18,7 @@ func TestServiceImpl_CreateUser(t *testing.T) {
 	mockRepo := new(repository.MockRepository)
 	svc := NewService(mockRepo)
 
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("Secure-passphrase"), bcrypt.DefaultCost)
 
 	tests := []struct {
 		name          string
@@ -29,7 +31,7 @@ func TestServiceImpl_CreateUser(t *testing.T) {
 		{
 			name:     "success",
 			username: "testuser",
-			password: "password123",
+			password: "Secure-passphrase",
 			mockBehavior: func() {
 				expectedUser := models.User{
 					Username: "testuser",
@@ -47,7 +49,7 @@ func TestServiceImpl_CreateUser(t *testing.T) {
 		{
 			name:     "user already exists",
 			username: "testuser",
-			password: "password123",
+			password: "Secure-passphrase",
 			mockBehavior: func() {
 				expectedUser := models.User{
 					Username: "testuser",
@@ -61,17 +63,25 @@ func TestServiceImpl_CreateUser(t *testing.T) {
 		{
 			name:     "user already exists",
 			username: "testuser",
-			password: "password123",
+			password: "Secure-passphrase",
 			mockBehavior: func() {
 				mockRepo.On("GetUserByUsername", "testuser").Return(nil, errors.New("unexpected error")).Once()
 			},
 			expectedUser:  nil,
 			expectedError: httperrors.InternalServerError("an error occurred while trying to create user", errors.New("unexpected error")),
 		},
+		{
+			name:          "password rejected by policy",
+			username:      "testuser",
+			password:      "password123",
+			mockBehavior:  func() {},
+			expectedUser:  nil,
+			expectedError: httperrors.BadRequestError("password is too common"),
+		},
 		{
 			name:     "failed to create user",
 			username: "testuser",
-			password: "password123",
+			password: "Secure-passphrase",
 			mockBehavior: func() {
 				mockRepo.On("GetUserByUsername", "testuser").Return(nil, gorm.ErrRecordNotFound).Once()
 				mockRepo.On("CreateUser", mock.Anything).Return(nil, errors.New("database error")).Once()
@@ -209,3 +219,209 @@ func TestServiceImpl_GetUserByUsername(t *testing.T) {
 		})
 	}
 }
+
+func TestServiceImpl_ChangePassword(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("Old-passphrase"), bcrypt.MinCost)
+	user := &models.User{ID: 1, Username: "testuser", Password: string(hashedPassword)}
+
+	tests := []struct {
+		name          string
+		oldPassword   string
+		newPassword   string
+		mockBehavior  func(mockRepo *repository.MockRepository)
+		expectedError error
+	}{
+		{
+			name:        "success",
+			oldPassword: "Old-passphrase",
+			newPassword: "New-passphrase",
+			mockBehavior: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetUser", uint64(1)).Return(user, nil).Once()
+				mockRepo.On("UpdateUserPassword", uint64(1), mock.MatchedBy(func(hash string) bool {
+					match, needsRehash, err := password.DefaultHasher().Verify(hash, "New-passphrase")
+					return match && !needsRehash && err == nil
+				})).Return(nil).Once()
+				mockRepo.On("RevokeUserSessions", uint64(1), mock.Anything).Return(nil).Once()
+			},
+		},
+		{
+			name:        "wrong current password",
+			oldPassword: "Wrong-passphrase",
+			newPassword: "New-passphrase",
+			mockBehavior: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetUser", uint64(1)).Return(user, nil).Once()
+			},
+			expectedError: httperrors.BadRequestError("invalid current password"),
+		},
+		{
+			name:        "same password",
+			oldPassword: "Old-passphrase",
+			newPassword: "Old-passphrase",
+			mockBehavior: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetUser", uint64(1)).Return(user, nil).Once()
+			},
+			expectedError: httperrors.BadRequestError("new password must be different from the current one"),
+		},
+		{
+			name:        "new password contains username",
+			oldPassword: "Old-passphrase",
+			newPassword: "testuser-2025!",
+			mockBehavior: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetUser", uint64(1)).Return(user, nil).Once()
+			},
+			expectedError: httperrors.BadRequestError("password must not contain the username"),
+		},
+		{
+			name:        "revoke sessions error",
+			oldPassword: "Old-passphrase",
+			newPassword: "New-passphrase",
+			mockBehavior: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetUser", uint64(1)).Return(user, nil).Once()
+				mockRepo.On("UpdateUserPassword", uint64(1), mock.Anything).Return(nil).Once()
+				mockRepo.On("RevokeUserSessions", uint64(1), mock.Anything).Return(errors.New("database error")).Once()
+			},
+			expectedError: httperrors.InternalServerError("an error occurred while trying to change password", errors.New("database error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			tt.mockBehavior(mockRepo)
+
+			svc := NewService(mockRepo)
+			err := svc.ChangePassword(1, tt.oldPassword, tt.newPassword)
+
+			assert.Equal(t, tt.expectedError, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServiceImpl_GetUserProfile(t *testing.T) {
+	tests := []struct {
+		name          string
+		mockBehavior  func(mockRepo *repository.MockRepository)
+		expectedError error
+	}{
+		{
+			name: "success",
+			mockBehavior: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetUser", uint64(1)).Return(&models.User{ID: 1, Username: "testuser"}, nil).Once()
+			},
+		},
+		{
+			name: "not found",
+			mockBehavior: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetUser", uint64(1)).Return(nil, gorm.ErrRecordNotFound).Once()
+			},
+			expectedError: httperrors.NotFoundError("user not found"),
+		},
+		{
+			name: "database error",
+			mockBehavior: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("GetUser", uint64(1)).Return(nil, errors.New("database error")).Once()
+			},
+			expectedError: httperrors.InternalServerError("an error occurred while trying to get user", errors.New("database error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			tt.mockBehavior(mockRepo)
+
+			svc := NewService(mockRepo)
+			user, err := svc.GetUserProfile(1)
+
+			assert.Equal(t, tt.expectedError, err)
+			if tt.expectedError == nil {
+				assert.Equal(t, "testuser", user.Username)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServiceImpl_GetUserProfileByUsername(t *testing.T) {
+	mockRepo := new(repository.MockRepository)
+	mockRepo.On("GetUserByUsername", "testuser").Return(&models.User{ID: 1, Username: "testuser"}, nil).Once()
+	mockRepo.On("GetUserByUsername", "nobody").Return(nil, gorm.ErrRecordNotFound).Once()
+
+	svc := NewService(mockRepo)
+
+	user, err := svc.GetUserProfileByUsername("testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), user.ID)
+
+	_, err = svc.GetUserProfileByUsername("nobody")
+	assert.Equal(t, httperrors.NotFoundError("user not found"), err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestServiceImpl_UpdateUserProfile(t *testing.T) {
+	text := func(value string) *string { return &value }
+
+	tests := []struct {
+		name          string
+		update        models.ProfileUpdate
+		mockBehavior  func(mockRepo *repository.MockRepository)
+		expectedError error
+	}{
+		{
+			name:   "success trims values",
+			update: models.ProfileUpdate{DisplayName: text("  Test User "), AvatarURL: text("https://example.com/avatar.png")},
+			mockBehavior: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("UpdateUserProfile", uint64(1), models.ProfileUpdate{
+					DisplayName: text("Test User"), AvatarURL: text("https://example.com/avatar.png"),
+				}).Return(&models.User{ID: 1, DisplayName: "Test User"}, nil).Once()
+			},
+		},
+		{
+			name:   "clear avatar",
+			update: models.ProfileUpdate{AvatarURL: text("")},
+			mockBehavior: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("UpdateUserProfile", uint64(1), models.ProfileUpdate{AvatarURL: text("")}).Return(&models.User{ID: 1}, nil).Once()
+			},
+		},
+		{
+			name:          "display name too long",
+			update:        models.ProfileUpdate{DisplayName: text(strings.Repeat("é", 65))},
+			mockBehavior:  func(mockRepo *repository.MockRepository) {},
+			expectedError: httperrors.BadRequestError("display name is too long"),
+		},
+		{
+			name:          "status text too long",
+			update:        models.ProfileUpdate{StatusText: text(strings.Repeat("a", 141))},
+			mockBehavior:  func(mockRepo *repository.MockRepository) {},
+			expectedError: httperrors.BadRequestError("status text is too long"),
+		},
+		{
+			name:          "invalid avatar url",
+			update:        models.ProfileUpdate{AvatarURL: text("javascript:alert(1)")},
+			mockBehavior:  func(mockRepo *repository.MockRepository) {},
+			expectedError: httperrors.BadRequestError("invalid avatar url"),
+		},
+		{
+			name:   "database error",
+			update: models.ProfileUpdate{StatusText: text("away")},
+			mockBehavior: func(mockRepo *repository.MockRepository) {
+				mockRepo.On("UpdateUserProfile", uint64(1), mock.Anything).Return(nil, errors.New("database error")).Once()
+			},
+			expectedError: httperrors.InternalServerError("an error occurred while trying to update profile", errors.New("database error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repository.MockRepository)
+			tt.mockBehavior(mockRepo)
+
+			svc := NewService(mockRepo)
+			_, err := svc.UpdateUserProfile(1, tt.update)
+
+			assert.Equal(t, tt.expectedError, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStorage(t *testing.T) {
+	store, err := NewLocalStorage(t.TempDir())
+	assert.NoError(t, err)
+
+	written, err := store.Save("blob", strings.NewReader("content"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), written)
+
+	file, err := store.Open("blob")
+	assert.NoError(t, err)
+	content, err := io.ReadAll(file)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+	assert.Equal(t, "content", string(content))
+
+	assert.NoError(t, store.Delete("blob"))
+	assert.NoError(t, store.Delete("blob"))
+
+	_, err = store.Open("blob")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocalStorage_InvalidKey(t *testing.T) {
+	store, err := NewLocalStorage(t.TempDir())
+	assert.NoError(t, err)
+
+	for _, key := range []string{"", ".", "..", "../escape", `dir\file`} {
+		_, err := store.Save(key, strings.NewReader("content"))
+		assert.Error(t, err, key)
+
+		_, err = store.Open(key)
+		assert.Error(t, err, key)
+	}
+}